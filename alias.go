@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// splitCommandLine splits an alias into the commands it chains with &&,
+// each split into words like a shell would: quotes group words and a
+// backslash escapes the next character. Nothing else is special.
+func splitCommandLine(s string) ([][]string, error) {
+	var steps [][]string
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			inWord, escaped = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			inWord, quote = true, r
+		case r == ' ' || r == '\t' || r == '\n':
+			endWord()
+		case r == '&' && i+1 < len(runes) && runes[i+1] == '&':
+			endWord()
+			steps = append(steps, words)
+			words = nil
+			i++
+		default:
+			inWord = true
+			word.WriteRune(r)
+		}
+	}
+	if escaped || quote != 0 {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	endWord()
+	steps = append(steps, words)
+	for _, step := range steps {
+		if len(step) == 0 {
+			return nil, errors.New("empty command")
+		}
+	}
+	return steps, nil
+}
+
+// checkAlias reports why name can't be an alias for command, if it can't
+func checkAlias(name, command string) error {
+	if _, ok := findCommand(name); ok {
+		return fmt.Errorf("alias %s would hide the command of the same name", name)
+	}
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("invalid alias name %q", name)
+	}
+	if _, err := splitCommandLine(command); err != nil {
+		return fmt.Errorf("alias %s: %v", name, err)
+	}
+	return nil
+}
+
+// aliasSteps expands the alias called name into the bookmarksync command
+// lines it runs, expanding the aliases it uses in turn. args are added to
+// the last one, as they would be to a command.
+func aliasSteps(name string, args []string) ([][]string, error) {
+	var expand func(name string, args []string, seen []string) ([][]string, error)
+	expand = func(name string, args []string, seen []string) ([][]string, error) {
+		if slices.Contains(seen, name) {
+			return nil, fmt.Errorf("alias %s uses itself", seen[0])
+		}
+		seen = append(seen, name)
+		steps, err := splitCommandLine(config.Aliases[name])
+		if err != nil {
+			return nil, fmt.Errorf("alias %s: %v", name, err)
+		}
+		steps[len(steps)-1] = append(steps[len(steps)-1], args...)
+
+		var expanded [][]string
+		for _, step := range steps {
+			// Options without a command are sync's, as on the command line
+			if _, ok := findCommand(step[0]); ok || strings.HasPrefix(step[0], "-") {
+				expanded = append(expanded, step)
+				continue
+			}
+			if _, ok := config.Aliases[step[0]]; !ok {
+				return nil, fmt.Errorf("alias %s: unknown command: %s", name, step[0])
+			}
+			inner, err := expand(step[0], step[1:], seen)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, inner...)
+		}
+		return expanded, nil
+	}
+	return expand(name, args, nil)
+}
+
+// runAlias runs the commands of the alias called name one after the other,
+// each as its own bookmarksync process, and stops at the first that fails
+// with the status it failed with
+func runAlias(name string, args []string) error {
+	steps, err := aliasSteps(name, args)
+	if err != nil {
+		return err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	for _, step := range steps {
+		if jsonOutput {
+			step = append([]string{"--json"}, step...)
+		}
+		cmd := exec.Command(executable, step...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				// The command has said what went wrong
+				os.Exit(exitErr.ExitCode())
+			}
+			return err
+		}
+	}
+	return nil
+}
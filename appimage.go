@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultAppImageDirs are scanned for AppImages when no --appimage-dir is
+// given, relative to the home directory
+var defaultAppImageDirs = []string{"Applications", "AppImages", filepath.Join(".local", "bin")}
+
+// AppImageBackends finds AppImages that run with a portable home
+// (Foo.AppImage.home) or portable config (Foo.AppImage.config) directory
+// and returns GTK and Qt backends for the bookmark files inside them, so
+// those applications' dialogs get the synced places too.
+func AppImageBackends(dirs []string) ([]BookmarkSyncBackend, error) {
+	if len(dirs) == 0 {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range defaultAppImageDirs {
+			dirs = append(dirs, filepath.Join(homeDir, dir))
+		}
+	}
+
+	var backends []BookmarkSyncBackend
+	for _, dir := range dirs {
+		dir, err := expandHome(dir)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			// The portable home replaces $HOME, the portable config
+			// replaces $XDG_CONFIG_HOME
+			var configDir string
+			name := entry.Name()
+			switch {
+			case strings.HasSuffix(name, ".AppImage.home"):
+				configDir = filepath.Join(dir, name, ".config")
+			case strings.HasSuffix(name, ".AppImage.config"):
+				configDir = filepath.Join(dir, name)
+			default:
+				continue
+			}
+
+			app := strings.ToLower(name[:strings.Index(name, ".AppImage.")])
+			backends = append(backends,
+				&GTKBackend{
+					BackendName: "appimage:" + app + ":gtk",
+					Path:        filepath.Join(configDir, "gtk-3.0", "bookmarks"),
+				},
+				&QtBackend{
+					BackendName: "appimage:" + app + ":qt",
+					Path:        filepath.Join(configDir, "QtProject.conf"),
+				},
+			)
+		}
+	}
+
+	return backends, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SyncAuto syncs from whichever backend was edited most recently since the
+// last sync. When several were edited, the others lose their changes, so
+// this is reported, and refused in safe mode.
+func (bs *BookmarkSync) SyncAuto() error {
+	state, err := LoadState()
+	if err != nil {
+		return fmt.Errorf("failed to load state: %v", err)
+	}
+
+	changed, err := bs.ChangedBackends(state)
+	if err != nil {
+		return err
+	}
+	// Edits to a backend that feeds nothing stay where they are
+	changed = slices.DeleteFunc(changed, func(name string) bool { return !bs.feeds(name) })
+	if len(changed) == 0 {
+		bs.say(tr("No backend changed since the last sync\n"))
+		return nil
+	}
+	if len(changed) > 1 {
+		if bs.Safe && !bs.Merge {
+			return fmt.Errorf("refusing to sync: %s were all modified since the last sync (use --two-way or --merge)", strings.Join(changed, ", "))
+		}
+		log.Print(tr("Warning: %s were all modified since the last sync; changes outside %s may be lost", strings.Join(changed, ", "), changed[0]))
+	}
+
+	source := changed[0]
+	if bs.Report != nil {
+		bs.Report.Source = source
+	}
+	bs.say(tr("Running sync from %s backend (most recently modified)\n", source))
+	return bs.SyncFrom(source)
+}
+
+// recordOutcome remembers whether the sync that returned err failed, for
+// status --prompt
+func recordOutcome(err error) {
+	state, loadErr := LoadState()
+	if loadErr != nil {
+		return
+	}
+	failed := ""
+	if err != nil {
+		failed = err.Error()
+	}
+	if state.Failed == failed {
+		return
+	}
+	state.Failed = failed
+	if saveErr := state.Save(); saveErr != nil {
+		log.Print(tr("Warning: failed to save state: %v", saveErr))
+	}
+}
+
+// recordSync remembers when a sync finished writing and what it left in
+// every backend, so the next run can tell our writes from the user's edits
+func (bs *BookmarkSync) recordSync() error {
+	state, err := LoadState()
+	if err != nil {
+		return err
+	}
+	state.Synced = time.Now()
+	bs.recordBackends(state)
+	if err := state.Save(); err != nil {
+		return err
+	}
+	bs.commitCanonical()
+	return nil
+}
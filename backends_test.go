@@ -0,0 +1,33 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"bookmarksync-go/backendtest"
+	"bookmarksync-go/bookmark"
+)
+
+func TestConformance(t *testing.T) {
+	factories := map[string]backendtest.Factory{
+		"gtk": func(t *testing.T, dir string) bookmark.Backend {
+			return &GTKBackend{Path: filepath.Join(dir, "bookmarks")}
+		},
+		"gtk2": func(t *testing.T, dir string) bookmark.Backend {
+			return &GTK2Backend{Path: filepath.Join(dir, ".gtk-bookmarks")}
+		},
+		"kde": func(t *testing.T, dir string) bookmark.Backend {
+			return &KDEBackend{Path: filepath.Join(dir, "user-places.xbel")}
+		},
+		"canonical": func(t *testing.T, dir string) bookmark.Backend {
+			// Not the real home its targets are made portable against
+			testHome(t)
+			return &CanonicalBackend{Path: filepath.Join(dir, "places.toml")}
+		},
+	}
+	for name, factory := range factories {
+		t.Run(name, func(t *testing.T) {
+			backendtest.RunConformance(t, factory)
+		})
+	}
+}
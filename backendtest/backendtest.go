@@ -0,0 +1,227 @@
+// Package backendtest checks that a bookmarksync backend behaves like the
+// built-in ones: what Replace writes, GetPlaces reads back as far as the
+// backend's declared capabilities allow, Merge keeps what is there, and a
+// broken file is an error rather than an empty list. A backend's tests run
+// the suite over a fresh folder per check:
+//
+//	func TestConformance(t *testing.T) {
+//		backendtest.RunConformance(t, func(t *testing.T, dir string) bookmark.Backend {
+//			return &MyBackend{Path: filepath.Join(dir, "places")}
+//		})
+//	}
+package backendtest
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bookmarksync-go/bookmark"
+)
+
+// Factory returns the backend under test, keeping its files below dir,
+// which is empty. Backends that only write into an existing folder, like
+// an application profile, should create it.
+type Factory func(t *testing.T, dir string) bookmark.Backend
+
+// corpus are the places the round trips are made with
+var corpus = []bookmark.Place{
+	{Label: "Documents", Target: "file:///home/user/Documents"},
+	{Label: "With spaces", Target: "file:///home/user/With%20spaces"},
+	{Label: "Übungen", Target: "file:///home/user/%C3%9Cbungen"},
+	{Label: "写真", Target: "file:///home/user/%E5%86%99%E7%9C%9F"},
+	{Label: "Tom & Jerry <1>", Target: "file:///home/user/Tom%20&%20Jerry"},
+	{Label: "C++", Target: "file:///home/user/C++"},
+	{Label: "Server", Target: "sftp://user@example.com/srv/www"},
+	{Label: "Share", Target: "smb://fileserver/share"},
+}
+
+// RunConformance runs the conformance checks against the backends factory
+// returns, each as a subtest
+func RunConformance(t *testing.T, factory Factory) {
+	t.Helper()
+	setup := func(t *testing.T) bookmark.Backend {
+		t.Helper()
+		backend := factory(t, t.TempDir())
+		if backend == nil {
+			t.Fatal("factory returned no backend")
+		}
+		return backend
+	}
+
+	t.Run("Name", func(t *testing.T) {
+		backend := setup(t)
+		name := backend.Name()
+		if name == "" || strings.ContainsAny(name, " \t\n=,") {
+			t.Errorf("Name() = %q, want a non-empty name without spaces, = or ,", name)
+		}
+		if again := backend.Name(); again != name {
+			t.Errorf("Name() changed from %q to %q", name, again)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		backend := setup(t)
+		places, err := backend.GetPlaces()
+		if err != nil {
+			t.Fatalf("GetPlaces() before any write: %v", err)
+		}
+		if len(places) != 0 {
+			t.Errorf("GetPlaces() before any write = %v, want none", places)
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		backend := setup(t)
+		replace(t, backend, corpus)
+		checkPlaces(t, backend, storable(backend, corpus))
+	})
+
+	t.Run("ReplaceDropsOthers", func(t *testing.T) {
+		backend := setup(t)
+		replace(t, backend, corpus)
+		replace(t, backend, corpus[:2])
+		checkPlaces(t, backend, storable(backend, corpus[:2]))
+	})
+
+	t.Run("ReplaceWithNothing", func(t *testing.T) {
+		backend := setup(t)
+		replace(t, backend, corpus)
+		replace(t, backend, nil)
+		checkPlaces(t, backend, nil)
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		backend := setup(t)
+		replace(t, backend, corpus[:3])
+		// The first place again, spelled differently, and a new one
+		incoming := []bookmark.Place{
+			{Label: "Documents", Target: "file:///home/user/Documents/"},
+			{Label: "Music", Target: "file:///home/user/Music"},
+		}
+		if err := backend.Merge(incoming); err != nil {
+			t.Fatalf("Merge(): %v", err)
+		}
+		checkPlaces(t, backend, storable(backend, append(corpus[:3:3], incoming[1])))
+	})
+
+	t.Run("Files", func(t *testing.T) {
+		backend := setup(t)
+		files, err := backend.Files()
+		if err != nil {
+			t.Fatalf("Files(): %v", err)
+		}
+		replace(t, backend, corpus)
+		written := false
+		for _, file := range files {
+			if !filepath.IsAbs(file) {
+				t.Errorf("Files() returned %q, want absolute paths", file)
+			}
+			if _, err := os.Stat(file); err == nil {
+				written = true
+			}
+		}
+		if !written {
+			t.Errorf("none of Files() = %v exists after Replace()", files)
+		}
+	})
+
+	t.Run("UnreadableFile", func(t *testing.T) {
+		backend := setup(t)
+		replace(t, backend, corpus)
+		files, err := backend.Files()
+		if err != nil {
+			t.Fatalf("Files(): %v", err)
+		}
+		for _, file := range files {
+			if _, err := os.Stat(file); err != nil {
+				continue
+			}
+			// A folder where the file should be can't be read by anyone
+			if err := os.Remove(file); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Mkdir(file, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if places, err := backend.GetPlaces(); err == nil {
+				t.Errorf("GetPlaces() with %s unreadable = %v, want an error", file, places)
+			}
+			return
+		}
+		t.Skip("Replace() wrote no file")
+	})
+}
+
+// capabilities returns what backend declares it can store, everything when
+// it declares nothing
+func capabilities(backend bookmark.Backend) bookmark.Capabilities {
+	if reporter, ok := backend.(interface{ Capabilities() bookmark.Capabilities }); ok {
+		return reporter.Capabilities()
+	}
+	return bookmark.Capabilities{Labels: true, Remote: true}
+}
+
+// storable returns the places backend can store, by its capabilities
+func storable(backend bookmark.Backend, places []bookmark.Place) []bookmark.Place {
+	if capabilities(backend).Remote {
+		return places
+	}
+	var local []bookmark.Place
+	for _, place := range places {
+		if strings.HasPrefix(place.Target, "file://") {
+			local = append(local, place)
+		}
+	}
+	return local
+}
+
+// replace replaces the backend's places, failing the test on errors
+func replace(t *testing.T, backend bookmark.Backend, places []bookmark.Place) {
+	t.Helper()
+	if err := backend.Replace(places); err != nil {
+		t.Fatalf("Replace(): %v", err)
+	}
+}
+
+// checkPlaces fails the test unless the backend reads back want, in order,
+// with the same labels if it declares it stores them
+func checkPlaces(t *testing.T, backend bookmark.Backend, want []bookmark.Place) {
+	t.Helper()
+	got, err := backend.GetPlaces()
+	if err != nil {
+		t.Fatalf("GetPlaces(): %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("GetPlaces() returned %d places, want %d:\ngot  %v\nwant %v", len(got), len(want), got, want)
+	}
+	labels := capabilities(backend).Labels
+	for i := range want {
+		if targetKey(got[i].Target) != targetKey(want[i].Target) {
+			t.Errorf("place %d has target %q, want %q", i, got[i].Target, want[i].Target)
+		}
+		if labels && got[i].Label != want[i].Label {
+			t.Errorf("place %d has label %q, want %q", i, got[i].Label, want[i].Label)
+		}
+	}
+}
+
+// targetKey returns target in a form that is the same however a backend
+// spells it: percent-encoded or not, with a trailing slash or localhost
+func targetKey(target string) string {
+	scheme, rest, ok := strings.Cut(target, "://")
+	if !ok {
+		return target
+	}
+	host, path, _ := strings.Cut(rest, "/")
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		path = unescaped
+	}
+	scheme, host = strings.ToLower(scheme), strings.ToLower(host)
+	if scheme == "file" && host == "localhost" {
+		host = ""
+	}
+	return scheme + "://" + host + "/" + strings.TrimSuffix(path, "/")
+}
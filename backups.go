@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultBackups is how many copies of each backend's files are kept when
+// the configuration doesn't say
+const defaultBackups = 10
+
+// backupTimeFormat names backups so that they sort by age
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// runBackups are the backups made for the backends written since the last
+// recorded sync, by backend: the name of the backup holding the files as
+// they were before, or "" when the backend had no files yet
+var runBackups = map[string]string{}
+
+// runBackupsMu guards runBackups, as backends are written in parallel
+var runBackupsMu sync.Mutex
+
+// noteRunBackup remembers backup as the one undo restores the backend
+// called name from, unless it already has one since the last recorded sync
+func noteRunBackup(name, backup string) {
+	runBackupsMu.Lock()
+	defer runBackupsMu.Unlock()
+	if _, ok := runBackups[name]; !ok {
+		runBackups[name] = backup
+	}
+}
+
+// backupsDir returns where the copies of a backend's files are kept
+func backupsDir(name string) (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "backups", strings.ReplaceAll(name, ":", "-")), nil
+}
+
+// keptBackups returns how many backups of each backend to keep
+func keptBackups() int {
+	if config.Backups == nil {
+		return defaultBackups
+	}
+	return *config.Backups
+}
+
+// backupBackend copies a backend's files aside before every write and
+// keeps the newest copies, so a bad sync can be undone by hand
+type backupBackend struct {
+	BookmarkSyncBackend
+}
+
+func (b *backupBackend) Capabilities() Capabilities {
+	return capabilitiesOf(b.BookmarkSyncBackend)
+}
+
+func (b *backupBackend) Unwrap() BookmarkSyncBackend {
+	return b.BookmarkSyncBackend
+}
+
+func (b *backupBackend) Replace(places []Place) error {
+	if err := b.backup(); err != nil {
+		return err
+	}
+	return b.BookmarkSyncBackend.Replace(places)
+}
+
+func (b *backupBackend) Merge(places []Place) error {
+	if err := b.backup(); err != nil {
+		return err
+	}
+	return b.BookmarkSyncBackend.Merge(places)
+}
+
+// backup copies the backend's files to backups/BACKEND/TIME, a file for
+// backends with one file and a folder of them otherwise, unless they are
+// the same as in the newest backup. Then it removes all but the newest.
+func (b *backupBackend) backup() error {
+	keep := keptBackups()
+	if keep <= 0 {
+		return nil
+	}
+	files, err := b.Files()
+	if err != nil {
+		return err
+	}
+	dir, err := backupsDir(b.Name())
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var existing []string
+	for _, file := range files {
+		if _, err := os.Stat(file); err == nil {
+			existing = append(existing, file)
+		}
+	}
+	if len(existing) == 0 {
+		noteRunBackup(b.Name(), "")
+		return nil
+	}
+
+	backup := filepath.Join(dir, time.Now().Format(backupTimeFormat))
+	copies := make(map[string]string, len(existing))
+	for _, file := range existing {
+		copies[file] = backup
+		if len(files) > 1 {
+			copies[file] = filepath.Join(backup, filepath.Base(file))
+		}
+	}
+	if len(entries) > 0 && sameBackup(copies, filepath.Join(dir, entries[len(entries)-1].Name()), backup) {
+		noteRunBackup(b.Name(), entries[len(entries)-1].Name())
+		return nil
+	}
+	for file, dst := range copies {
+		if err := copyFileSynced(file, dst); err != nil {
+			return err
+		}
+	}
+	noteRunBackup(b.Name(), filepath.Base(backup))
+
+	entries, err = os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries[:max(len(entries)-keep, 0)] {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameBackup reports whether the files of copies, which are to be copied
+// to backup, are the same as in the older backup newest
+func sameBackup(copies map[string]string, newest, backup string) bool {
+	for file, dst := range copies {
+		current, err := os.ReadFile(file)
+		if err != nil {
+			return false
+		}
+		saved, err := os.ReadFile(newest + strings.TrimPrefix(dst, backup))
+		if err != nil || !bytes.Equal(current, saved) {
+			return false
+		}
+	}
+	return true
+}
+
+// recordUndo remembers the backups made since the last recorded sync as the
+// ones undo restores, unless nothing was backed up
+func recordUndo(state *State) {
+	runBackupsMu.Lock()
+	defer runBackupsMu.Unlock()
+	if len(runBackups) == 0 {
+		return
+	}
+	state.Undo = runBackups
+	runBackups = map[string]string{}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BlenderBackend implements BookmarkSyncBackend for the bookmarks of
+// Blender's file browser, stored per Blender version in
+// ~/.config/blender/<version>/config/bookmarks.txt
+type BlenderBackend struct{}
+
+func (b *BlenderBackend) Name() string {
+	return "blender"
+}
+
+func (b *BlenderBackend) Capabilities() Capabilities {
+	return Capabilities{Labels: true, Remote: false}
+}
+
+// versionDirs returns the per-version config directories, newest first
+func (b *BlenderBackend) versionDirs() ([]string, error) {
+	configHome, err := xdgConfigHome()
+	if err != nil {
+		return nil, err
+	}
+
+	blenderDir := filepath.Join(configHome, "blender")
+	entries, err := os.ReadDir(blenderDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() && parseBlenderVersion(entry.Name()) != nil {
+			versions = append(versions, entry.Name())
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		a, b := parseBlenderVersion(versions[i]), parseBlenderVersion(versions[j])
+		if a[0] != b[0] {
+			return a[0] > b[0]
+		}
+		return a[1] > b[1]
+	})
+
+	var dirs []string
+	for _, version := range versions {
+		dirs = append(dirs, filepath.Join(blenderDir, version, "config"))
+	}
+	return dirs, nil
+}
+
+func (b *BlenderBackend) Files() ([]string, error) {
+	dirs, err := b.versionDirs()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, dir := range dirs {
+		files = append(files, filepath.Join(dir, "bookmarks.txt"))
+	}
+	return files, nil
+}
+
+// parseBlenderVersion parses a "major.minor" directory name
+func parseBlenderVersion(name string) []int {
+	major, minor, ok := strings.Cut(name, ".")
+	if !ok {
+		return nil
+	}
+	maj, err := strconv.Atoi(major)
+	if err != nil {
+		return nil
+	}
+	mnr, err := strconv.Atoi(minor)
+	if err != nil {
+		return nil
+	}
+	return []int{maj, mnr}
+}
+
+func (b *BlenderBackend) GetPlaces() ([]Place, error) {
+	dirs, err := b.versionDirs()
+	if err != nil || len(dirs) == 0 {
+		return []Place{}, err
+	}
+
+	bookmarks, _, err := readBlenderBookmarks(filepath.Join(dirs[0], "bookmarks.txt"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Place{}, nil
+		}
+		return nil, err
+	}
+	return bookmarks, nil
+}
+
+// readBlenderBookmarks parses bookmarks.txt, returning the [Bookmarks]
+// entries as places and the raw [Recent] lines
+func readBlenderBookmarks(path string) ([]Place, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkText(path, data); err != nil {
+		return nil, nil, err
+	}
+
+	var places []Place
+	var recent []string
+	section := ""
+	name := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "["):
+			section = line
+		case section == "[Recent]":
+			recent = append(recent, line)
+		case section != "[Bookmarks]":
+			continue
+		case strings.HasPrefix(line, "!"):
+			// A "!name" line labels the path that follows it
+			name = line[1:]
+		default:
+			dir := strings.TrimSuffix(line, "/")
+			if dir == "" {
+				dir = "/"
+			}
+			label := name
+			if label == "" {
+				label = filepath.Base(dir)
+			}
+			places = append(places, Place{Label: label, Target: fileURI(dir)})
+			name = ""
+		}
+	}
+
+	return places, recent, scanner.Err()
+}
+
+func (b *BlenderBackend) Merge(places []Place) error {
+	return mergeInto(b, places)
+}
+
+// Installed reports whether any Blender version has a configuration
+// folder, which Replace only writes into
+func (b *BlenderBackend) Installed() bool {
+	dirs, err := b.versionDirs()
+	return err == nil && len(dirs) > 0
+}
+
+func (b *BlenderBackend) Replace(places []Place) error {
+	dirs, err := b.versionDirs()
+	if err != nil {
+		return err
+	}
+
+	// Every installed Blender version keeps its own list
+	for _, dir := range dirs {
+		if err := writeBlenderBookmarks(filepath.Join(dir, "bookmarks.txt"), places); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeBlenderBookmarks(path string, places []Place) error {
+	_, recent, err := readBlenderBookmarks(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "[Bookmarks]")
+	for _, place := range places {
+		// Blender's file browser only handles local directories
+		if !strings.HasPrefix(place.Target, "file://") {
+			continue
+		}
+		u, err := url.Parse(place.Target)
+		if err != nil {
+			continue
+		}
+		dir := strings.TrimSuffix(u.Path, "/") + "/"
+		if place.Label != "" && place.Label != filepath.Base(u.Path) {
+			fmt.Fprintf(&buf, "!%s\n", place.Label)
+		}
+		fmt.Fprintln(&buf, dir)
+	}
+	fmt.Fprintln(&buf, "[Recent]")
+	for _, line := range recent {
+		fmt.Fprintln(&buf, line)
+	}
+	return replaceFile(path, buf.Bytes())
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Regression test: every sync rewrote Blender's bookmarks, for users who
+// never asked for it
+func TestBlenderOptIn(t *testing.T) {
+	home := testHome(t)
+	if err := os.MkdirAll(filepath.Join(home, ".config/blender/4.1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := NewBookmarkSync().backends["blender"]; ok {
+		t.Error("registered without apps")
+	}
+	config.Apps = []string{"blender"}
+	if _, ok := NewBookmarkSync().backends["blender"]; !ok {
+		t.Error("not registered with apps and a version installed")
+	}
+}
+
+func TestBlenderRoundTrip(t *testing.T) {
+	home := testHome(t)
+	old := writeFile(t, home, ".config/blender/3.6/config/bookmarks.txt", "[Bookmarks]\n/old\n[Recent]\n/home/me/recent/\n")
+	for _, dir := range []string{"4.1", "4.10", "scripts"} {
+		if err := os.MkdirAll(filepath.Join(home, ".config/blender", dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backend := &BlenderBackend{}
+
+	if err := backend.Replace(places("Projects", "file:///home/me/Projects", "Server", "sftp://host/srv", "", "file:///")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := backend.GetPlaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := places("Projects", "file:///home/me/Projects", "/", "file:///"); !reflect.DeepEqual(got, want) {
+		t.Errorf("read back %v, want %v", got, want)
+	}
+	// Every version gets the places, keeping its recent folders
+	data, err := os.ReadFile(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "/home/me/Projects") || !strings.Contains(string(data), "[Recent]\n/home/me/recent/") {
+		t.Errorf("%s is\n%s", old, data)
+	}
+}
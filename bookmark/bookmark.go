@@ -0,0 +1,29 @@
+// Package bookmark holds the types bookmarksync's backends are written
+// against, so that packages outside the command, like backendtest, can
+// use them too
+package bookmark
+
+// Place represents a bookmark entry
+type Place struct {
+	Label  string `json:"label"`
+	Target string `json:"target"`
+}
+
+// Backend defines the interface for bookmark backends
+type Backend interface {
+	GetPlaces() ([]Place, error)
+	Replace(places []Place) error
+	// Merge unions places into the backend, keeping entries only it has
+	Merge(places []Place) error
+	Name() string
+	// Files returns the files the backend reads and writes
+	Files() ([]string, error)
+}
+
+// Capabilities describe what a backend's file format can store
+type Capabilities struct {
+	// Labels is false when labels are derived from the path on read
+	Labels bool
+	// Remote is false when only file:// places can be stored
+	Remote bool
+}
@@ -0,0 +1,618 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// canonicalHeader starts the canonical places file
+const canonicalHeader = `# Places synced by bookmarksync to every other backend. Edit freely: the
+# order is kept, and a place without a label gets its folder's name.
+`
+
+// CanonicalBackend implements BookmarkSyncBackend for a plain places file
+// meant to be the source of truth, and to be kept with the dotfiles: one
+// [[place]] table per bookmark, in order, which diffs line by line.
+type CanonicalBackend struct {
+	// Path overrides the default $XDG_CONFIG_HOME/bookmarksync/places.toml
+	Path string
+}
+
+// canonicalFile is the canonical places file's layout
+type canonicalFile struct {
+	Places []canonicalPlace `toml:"place"`
+}
+
+// canonicalPlace is a place of the canonical places file. Created and
+// Modified, when it was added and last relabelled, scoped or locked, are
+// kept up to date by every write, and travel with the file between
+// machines.
+type canonicalPlace struct {
+	Label  string `toml:"label,omitempty"`
+	Target string `toml:"target"`
+	// Hosts are the hostnames or globs of the machines the place is for,
+	// every one when empty. The others keep it in the file but don't see
+	// it.
+	Hosts []string `toml:"hosts,omitempty"`
+	// Locked keeps syncs and commands from removing or relabelling the
+	// place without --force
+	Locked   bool      `toml:"locked,omitempty"`
+	Created  time.Time `toml:"created,omitempty"`
+	Modified time.Time `toml:"modified,omitempty"`
+}
+
+// seenEntries are the newest entries read from any copy of the canonical
+// places file, like the git, remote and webdav ones, by normalized target.
+// Writing another copy takes their times and hosts from here, so that they
+// travel from copy to copy like the places do.
+var seenEntries = struct {
+	sync.Mutex
+	entries map[string]canonicalPlace
+}{entries: make(map[string]canonicalPlace)}
+
+// noteEntries adds entries to seenEntries where they are at least as new
+// as those noted before
+func noteEntries(entries []canonicalPlace) {
+	seenEntries.Lock()
+	defer seenEntries.Unlock()
+	for _, entry := range entries {
+		key := normalizeTarget(entry.Target)
+		if seen, ok := seenEntries.entries[key]; !ok || !entry.Modified.Before(seen.Modified) {
+			seenEntries.entries[key] = entry
+		}
+	}
+}
+
+// seenEntry returns the newest entry of target read so far
+func seenEntry(key string) (canonicalPlace, bool) {
+	seenEntries.Lock()
+	defer seenEntries.Unlock()
+	entry, ok := seenEntries.entries[key]
+	return entry, ok
+}
+
+// hostname is the name of this machine that hosts of canonical places
+// are matched against
+var hostname = sync.OnceValue(func() string {
+	name, _ := os.Hostname()
+	return strings.ToLower(name)
+})
+
+// onThisHost reports whether a place for hosts is for this machine. A
+// host matches by its full name or the part before the first dot.
+func onThisHost(hosts []string) bool {
+	if len(hosts) == 0 {
+		return true
+	}
+	full := hostname()
+	short, _, _ := strings.Cut(full, ".")
+	for _, host := range hosts {
+		host = strings.ToLower(host)
+		for _, name := range []string{full, short} {
+			if ok, _ := path.Match(host, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *CanonicalBackend) Name() string {
+	return "canonical"
+}
+
+func (c *CanonicalBackend) path() (string, error) {
+	if c.Path != "" {
+		return c.Path, nil
+	}
+	configHome, err := xdgConfigHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configHome, "bookmarksync", "places.toml"), nil
+}
+
+func (c *CanonicalBackend) Files() ([]string, error) {
+	path, err := c.path()
+	if err != nil {
+		return nil, err
+	}
+	return []string{path}, nil
+}
+
+func (c *CanonicalBackend) GetPlaces() ([]Place, error) {
+	path, err := c.path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []Place{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data, err = openPlaces(path, data); err != nil {
+		return nil, err
+	}
+	places, err := parseCanonical(data)
+	if err != nil {
+		return nil, &corruptError{File: path, Err: err}
+	}
+	if places, err = c.mergeSyncConflicts(path, places); err != nil {
+		return nil, err
+	}
+	return placesHere(places), nil
+}
+
+// syncConflicts returns the copies of path Syncthing keeps when two
+// machines changed it before it could sync, like
+// places.sync-conflict-20240101-120000-ABCDEFG.toml, oldest first
+func syncConflicts(path string) []string {
+	ext := filepath.Ext(path)
+	matches, _ := filepath.Glob(strings.TrimSuffix(path, ext) + ".sync-conflict-*" + ext)
+	sort.Strings(matches)
+	return matches
+}
+
+// mergeSyncConflicts merges the places of the conflict copies next to path
+// with places, those of path itself, taking the places the file had after
+// the last sync for their common ancestor. The merge is written to path
+// and the copies are moved out of the synced folder, to
+// ~/.local/state/bookmarksync/sync-conflicts, so that the machines agree
+// again.
+func (c *CanonicalBackend) mergeSyncConflicts(path string, places []Place) ([]Place, error) {
+	copies := syncConflicts(path)
+	if len(copies) == 0 {
+		return places, nil
+	}
+	var baseline []Place
+	if state, err := LoadState(); err == nil {
+		baseline = state.Seen[c.Name()]
+	}
+
+	// Every copy has the times of its edits, so the newest wins
+	caps := Capabilities{Labels: true, Remote: true}
+	var own map[string]canonicalPlace
+	if data, err := os.ReadFile(path); err == nil {
+		own = canonicalTimes(path, data)
+	}
+	edits := []placeEdits{diffPlaces(filepath.Base(path), caps, baseline, places)}
+	edits[0].modified = modifiedTimes(own)
+	var merged []string
+	for _, copy := range copies {
+		data, err := os.ReadFile(copy)
+		if err != nil {
+			return nil, err
+		}
+		plain, err := openPlaces(copy, data)
+		if err != nil {
+			log.Print(tr("Warning: skipping %s: %v", copy, err))
+			continue
+		}
+		theirs, err := parseCanonical(plain)
+		if err != nil {
+			log.Print(tr("Warning: skipping %s: %v", copy, err))
+			continue
+		}
+		entries := canonicalTimes(copy, data)
+		edit := diffPlaces(filepath.Base(copy), caps, baseline, theirs)
+		edit.modified = modifiedTimes(entries)
+		edits = append(edits, edit)
+		merged = append(merged, copy)
+	}
+	places, conflicts := threeWayMerge(baseline, edits)
+	for _, conflict := range conflicts {
+		log.Print(tr("Conflict: %s", conflict))
+	}
+	if !repairFiles || len(merged) == 0 {
+		return places, nil
+	}
+
+	if err := c.Replace(places); err != nil {
+		return nil, err
+	}
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	for _, copy := range merged {
+		if err := copyFileSynced(copy, filepath.Join(dir, "sync-conflicts", filepath.Base(copy))); err != nil {
+			return nil, err
+		}
+		if err := os.Remove(copy); err != nil {
+			return nil, err
+		}
+		log.Print(tr("Merged the sync conflict %s into %s", filepath.Base(copy), path))
+	}
+	return places, nil
+}
+
+// decodeCanonical reads the entries of a canonical places file, with the
+// labels of those without one made up from their target
+func decodeCanonical(data []byte) ([]canonicalPlace, error) {
+	var file canonicalFile
+	meta, err := toml.Decode(string(data), &file)
+	if err != nil {
+		return nil, err
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("unknown setting %s", undecoded[0])
+	}
+	for i, place := range file.Places {
+		if place.Target == "" {
+			return nil, fmt.Errorf("place %d has no target", i+1)
+		}
+		file.Places[i].Target = expandPlaceholders(place.Target)
+		if place.Label == "" {
+			file.Places[i].Label = defaultLabel(file.Places[i].Target)
+		}
+		for _, host := range place.Hosts {
+			if _, err := path.Match(host, ""); err != nil || host == "" {
+				return nil, fmt.Errorf("place %d has an invalid host %q", i+1, host)
+			}
+		}
+	}
+	noteEntries(file.Places)
+	return file.Places, nil
+}
+
+// parseCanonical reads the places of a canonical places file, those for
+// other hosts too
+func parseCanonical(data []byte) ([]Place, error) {
+	entries, err := decodeCanonical(data)
+	if err != nil {
+		return nil, err
+	}
+	places := []Place{}
+	for _, entry := range entries {
+		places = append(places, Place{Label: entry.Label, Target: entry.Target})
+	}
+	return places, nil
+}
+
+// hostPlaces reads the places of a canonical places file that are for this
+// host, which are all its backends get to see
+func hostPlaces(data []byte) ([]Place, error) {
+	places, err := parseCanonical(data)
+	if err != nil {
+		return nil, err
+	}
+	return placesHere(places), nil
+}
+
+// placesHere drops the places of a canonical places file that are for
+// other hosts
+func placesHere(places []Place) []Place {
+	here := []Place{}
+	for _, place := range places {
+		if entry, ok := seenEntry(normalizeTarget(place.Target)); !ok || onThisHost(entry.Hosts) {
+			here = append(here, place)
+		}
+	}
+	return here
+}
+
+// canonicalTimes returns the entries of a canonical places file, which
+// may be encrypted, by normalized target, none if it doesn't parse
+func canonicalTimes(name string, data []byte) map[string]canonicalPlace {
+	entries := make(map[string]canonicalPlace)
+	data, err := openPlaces(name, data)
+	if err != nil {
+		return entries
+	}
+	decoded, _ := decodeCanonical(data)
+	for _, entry := range decoded {
+		entries[normalizeTarget(entry.Target)] = entry
+	}
+	return entries
+}
+
+// canonicalEntries returns the entries of the canonical places file by
+// normalized target, with the times kept in it, none without the canonical
+// backend
+func (bs *BookmarkSync) canonicalEntries() map[string]canonicalPlace {
+	backend, ok := bs.backends["canonical"]
+	if !ok {
+		return nil
+	}
+	files, err := backend.Files()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		return nil
+	}
+	return canonicalTimes(files[0], data)
+}
+
+// modifiedTimes returns when each entry was modified, by normalized target,
+// for the three-way merge to let the newest edit win
+func modifiedTimes(entries map[string]canonicalPlace) map[string]time.Time {
+	times := make(map[string]time.Time, len(entries))
+	for key, entry := range entries {
+		times[key] = entry.Modified
+	}
+	return times
+}
+
+func (c *CanonicalBackend) Merge(places []Place) error {
+	return mergeInto(c, places)
+}
+
+func (c *CanonicalBackend) Replace(places []Place) error {
+	path, err := c.path()
+	if err != nil {
+		return err
+	}
+	data, err := c.Render(places)
+	if err != nil {
+		return err
+	}
+	if data, err = sealPlaces(data, path); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return replaceFile(path, data)
+}
+
+// keep writes data, a copy of the canonical places file made elsewhere, as
+// the file, times and all
+func (c *CanonicalBackend) keep(data []byte) error {
+	path, err := c.path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return replaceFile(path, data)
+}
+
+func (c *CanonicalBackend) Render(places []Place) ([]byte, error) {
+	var current []canonicalPlace
+	if path, err := c.path(); err == nil {
+		if data, err := os.ReadFile(path); err == nil {
+			if data, err := openPlaces(path, data); err == nil {
+				current, _ = decodeCanonical(data)
+			}
+		}
+	}
+	var file canonicalFile
+	file.Places = withOtherHosts(stampPlaces(places, current), current)
+	for i := range file.Places {
+		file.Places[i].Target = portableTarget(file.Places[i].Target)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(canonicalHeader)
+	if len(file.Places) > 0 {
+		buf.WriteString("\n")
+		encoder := toml.NewEncoder(&buf)
+		encoder.Indent = ""
+		if err := encoder.Encode(file); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// newestEntry returns the newest of the entry current has for key and the
+// one read last from any copy of the file
+func newestEntry(current map[string]canonicalPlace, key string) (canonicalPlace, bool) {
+	entry, ok := current[key]
+	if seen, seenOK := seenEntry(key); seenOK && (!ok || seen.Modified.After(entry.Modified)) {
+		return seen, true
+	}
+	return entry, ok
+}
+
+// stampPlaces makes the entries of the canonical places file for places,
+// keeping the times of the entries current and the other copies of the file
+// have for them when the label is theirs, and the hosts and lock of the
+// newest. A place whose label is new was modified now, and one that is new
+// altogether created when bookmarksync first saw it, or now.
+func stampPlaces(places []Place, current []canonicalPlace) []canonicalPlace {
+	byKey := make(map[string]canonicalPlace, len(current))
+	for _, entry := range current {
+		byKey[normalizeTarget(entry.Target)] = entry
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	var state *State
+	entries := make([]canonicalPlace, 0, len(places))
+	for _, place := range places {
+		key := normalizeTarget(place.Target)
+		entry := canonicalPlace{Label: place.Label, Target: place.Target}
+		prev, had := byKey[key]
+		newest, known := newestEntry(byKey, key)
+		switch {
+		case known && newest.Label == place.Label:
+			entry.Created, entry.Modified = newest.Created, newest.Modified
+		case had && prev.Label == place.Label:
+			entry.Created, entry.Modified = prev.Created, prev.Modified
+		case known:
+			entry.Created, entry.Modified = newest.Created, now
+		}
+		entry.Hosts, entry.Locked = newest.Hosts, newest.Locked
+
+		if entry.Created.IsZero() {
+			// New, or in a file written before it kept times
+			if state == nil {
+				if state, _ = LoadState(); state == nil {
+					state = &State{}
+				}
+			}
+			p := state.Provenance[key]
+			entry.Created = now
+			if !p.Added.IsZero() {
+				entry.Created = p.Added.UTC().Truncate(time.Second)
+			}
+			if entry.Modified.IsZero() && !p.Modified.IsZero() {
+				entry.Modified = p.Modified.UTC().Truncate(time.Second)
+			}
+		}
+		if entry.Modified.Before(entry.Created) {
+			entry.Modified = entry.Created
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// withOtherHosts adds the entries of current that are for other hosts to
+// entries, which only have the places this host sees, each after the place
+// it followed in current
+func withOtherHosts(entries, current []canonicalPlace) []canonicalPlace {
+	written := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		written[normalizeTarget(entry.Target)] = true
+	}
+	byKey := make(map[string]canonicalPlace, len(current))
+	for _, entry := range current {
+		byKey[normalizeTarget(entry.Target)] = entry
+	}
+	after := make(map[string][]canonicalPlace)
+	previous := ""
+	for _, entry := range current {
+		key := normalizeTarget(entry.Target)
+		// Another copy may have scoped it to other hosts since
+		if newest, _ := newestEntry(byKey, key); !written[key] && !onThisHost(newest.Hosts) {
+			after[previous] = append(after[previous], newest)
+		}
+		if written[key] {
+			previous = key
+		}
+	}
+
+	kept := append([]canonicalPlace(nil), after[""]...)
+	for _, entry := range entries {
+		key := normalizeTarget(entry.Target)
+		kept = append(kept, entry)
+		kept = append(kept, after[key]...)
+	}
+	return kept
+}
+
+// commitCanonical commits the canonical places file when auto_commit is
+// set and it is in a git repository with changes to it, so dotfiles get a
+// history of the places without committing by hand, and pushes the commits
+// when auto_push is set too. Failing to commit or push doesn't fail the
+// sync.
+func (bs *BookmarkSync) commitCanonical() {
+	backend, ok := bs.backends["canonical"]
+	if !config.AutoCommit || !ok || bs.DryRun {
+		return
+	}
+	files, err := backend.Files()
+	if err != nil {
+		return
+	}
+	path := files[0]
+	dir := filepath.Dir(path)
+	git := func(args ...string) ([]byte, error) {
+		return exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	}
+	if _, err := git("rev-parse", "--is-inside-work-tree"); err != nil {
+		return
+	}
+	if config.AutoPush {
+		// Also pushes the commits an earlier push failed for
+		defer pushCanonical(dir, path)
+	}
+	status, err := git("status", "--porcelain", "--", path)
+	if err != nil || len(bytes.TrimSpace(status)) == 0 {
+		return
+	}
+
+	message := "bookmarksync: update places"
+	if data, err := os.ReadFile(path); err == nil {
+		data, _ = openPlaces(path, data)
+		if places, err := parseCanonical(data); err == nil {
+			var old []Place
+			if committed, err := git("show", "HEAD:./"+filepath.Base(path)); err == nil {
+				committed, _ = openPlaces(path, committed)
+				old, _ = parseCanonical(committed)
+			}
+			message = canonicalCommitMessage(old, places)
+		}
+	}
+	if _, err := git("add", "--", path); err != nil {
+		log.Print(tr("Warning: failed to commit %s: %v", path, err))
+		return
+	}
+	if out, err := exec.Command("git", "-C", dir, "commit", "--quiet", "-m", message, "--", path).CombinedOutput(); err != nil {
+		log.Print(tr("Warning: failed to commit %s: %v", path, strings.TrimSpace(string(out))))
+	}
+}
+
+// pushCanonical pushes the commits of the git repository in dir to the
+// upstream of its branch when there are any. A repository without an
+// upstream has nowhere to push to.
+func pushCanonical(dir, path string) {
+	git := func(args ...string) ([]byte, error) {
+		return exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	}
+	ahead, err := git("rev-list", "--count", "@{upstream}..HEAD")
+	if err != nil || strings.TrimSpace(string(ahead)) == "0" {
+		return
+	}
+	if err := checkKeyring(sshKeysInKeyring()); err != nil {
+		log.Print(tr("Warning: failed to push %s: %v", path, err))
+		return
+	}
+	if out, err := git("push", "--quiet"); err != nil {
+		log.Print(tr("Warning: failed to push %s: %v", path, strings.TrimSpace(string(out))))
+	}
+}
+
+// canonicalCommitMessage describes the change from old to places: which
+// places were added and removed, by label
+func canonicalCommitMessage(old, places []Place) string {
+	was := make(map[string]bool, len(old))
+	for _, place := range old {
+		was[normalizeTarget(place.Target)] = true
+	}
+	is := make(map[string]bool, len(places))
+	var added, removed []string
+	for _, place := range places {
+		key := normalizeTarget(place.Target)
+		is[key] = true
+		if !was[key] {
+			added = append(added, place.Label)
+		}
+	}
+	for _, place := range old {
+		if !is[normalizeTarget(place.Target)] {
+			removed = append(removed, place.Label)
+		}
+	}
+
+	var parts []string
+	if len(added) > 0 {
+		parts = append(parts, "add "+strings.Join(added, ", "))
+	}
+	if len(removed) > 0 {
+		parts = append(parts, "remove "+strings.Join(removed, ", "))
+	}
+	if len(parts) == 0 {
+		return "bookmarksync: update places"
+	}
+	message := "bookmarksync: " + strings.Join(parts, "; ")
+	if len(message) > 72 {
+		message = fmt.Sprintf("bookmarksync: add %d places, remove %d", len(added), len(removed))
+	}
+	return message
+}
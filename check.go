@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// driftError is what check fails with when backends differ from the
+// source
+type driftError struct {
+	source   string
+	backends []string
+}
+
+func (e *driftError) Error() string {
+	return tr("%s differ from %s", strings.Join(e.backends, ", "), e.source)
+}
+
+func (e *driftError) ExitCode() int {
+	return exitDrift
+}
+
+// installedReporter is a backend of an application that may not be
+// installed, whose places are only written where it is
+type installedReporter interface {
+	Installed() bool
+}
+
+// installed reports whether the application of the backend is installed,
+// as far as the backend can tell
+func installed(backend BookmarkSyncBackend) bool {
+	reporter, ok := unwrapped[installedReporter](backend)
+	return !ok || reporter.Installed()
+}
+
+// runCheck implements "bookmarksync check [--from BACKEND] [-q]", which
+// fails when a sync from BACKEND would change any other backend, without
+// writing anything. It is meant for login hooks and CI, which want to
+// hear about drift rather than have it fixed.
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	from := fs.String("from", "", "Backend the others have to match (default: from in the configuration, else canonical, else gtk)")
+	quiet := fs.Bool("q", false, "Print nothing, only exit with the result")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		return usageError("bookmarksync-go check [--from BACKEND] [-q]")
+	}
+	// Checking mustn't write, not even to repair a file
+	repairFiles = false
+
+	bs := NewBookmarkSync()
+	bs.Merge = config.Merge
+	name := bs.sourceName(*from)
+	source, exists := bs.backends[name]
+	if !exists {
+		return fmt.Errorf("unknown backend: %s", name)
+	}
+
+	run := func() error {
+		places, err := bs.readSource(source)
+		if err != nil {
+			return err
+		}
+		places = bs.pruned(places)
+		var destinations []BookmarkSyncBackend
+		for _, backend := range bs.Backends() {
+			// A sync wouldn't write the places of applications that
+			// aren't installed either
+			if backend.Name() != name && bs.routed(name, backend.Name()) && bs.writable(backend.Name()) && installed(backend) {
+				destinations = append(destinations, backend)
+			}
+		}
+
+		plans := bs.planChanges(places, destinations)
+		var drifted []string
+		for _, plan := range plans {
+			if plan.Status != "unchanged" {
+				drifted = append(drifted, plan.Name)
+			}
+		}
+		if !*quiet {
+			bs.printPlans(plans)
+		}
+		if len(drifted) > 0 {
+			return &driftError{source: name, backends: drifted}
+		}
+		return nil
+	}
+	if jsonOutput {
+		return bs.withReport(&SyncReport{Mode: "check", Source: name, DryRun: true}, run)
+	}
+	err := run()
+	var drift *driftError
+	switch {
+	case err == nil && !*quiet:
+		fmt.Print(tr("Every backend matches %s\n", name))
+	case errors.As(err, &drift) && *quiet:
+		// The exit status says it all
+		os.Exit(exitDrift)
+	}
+	return err
+}
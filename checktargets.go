@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// unreachableTarget is a local bookmark whose folder can't be opened
+type unreachableTarget struct {
+	Label  string `json:"label"`
+	Target string `json:"target"`
+	// Problem is missing, not-a-directory or the error stat returned
+	Problem string `json:"problem"`
+	// Backends are the backends that have the bookmark
+	Backends []string `json:"backends"`
+}
+
+// folderProblem returns why the folder of a local target can't be opened,
+// or "" if it can
+func folderProblem(path string) string {
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return "missing"
+	case err != nil:
+		return err.Error()
+	case !info.IsDir():
+		return "not-a-directory"
+	}
+	return ""
+}
+
+// runCheckTargets implements "bookmarksync check-targets", which reports
+// the local bookmarks of every backend whose folder is missing or isn't a
+// folder, without changing anything. It fails when there are any, so a
+// cron job can mail the report.
+func runCheckTargets(args []string) error {
+	flags := flag.NewFlagSet("check-targets", flag.ExitOnError)
+	asJSON := flags.Bool("json", jsonOutput, "Print the report as JSON")
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		return usageError("bookmarksync-go check-targets [--json]")
+	}
+
+	bs := NewBookmarkSync()
+	unreachable := []*unreachableTarget{}
+	byTarget := make(map[string]*unreachableTarget)
+	for _, backend := range bs.Backends() {
+		places, err := backend.GetPlaces()
+		if err != nil {
+			return fmt.Errorf("failed to get places from %s: %v", backend.Name(), err)
+		}
+		for _, place := range places {
+			path, err := localPath(place.Target)
+			if err != nil || path == "" {
+				continue
+			}
+			key := normalizeTarget(place.Target)
+			if found, ok := byTarget[key]; ok {
+				found.Backends = append(found.Backends, backend.Name())
+				continue
+			}
+			problem := folderProblem(path)
+			if problem == "" {
+				continue
+			}
+			found := &unreachableTarget{Label: place.Label, Target: place.Target, Problem: problem, Backends: []string{backend.Name()}}
+			byTarget[key] = found
+			unreachable = append(unreachable, found)
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(unreachable); err != nil {
+			return err
+		}
+	} else {
+		for _, target := range unreachable {
+			problem := target.Problem
+			switch problem {
+			case "missing":
+				problem = tr("the folder doesn't exist")
+			case "not-a-directory":
+				problem = tr("not a folder")
+			}
+			fmt.Printf("%s (%s): %s [%s]\n", target.Label, target.Target, problem, strings.Join(target.Backends, ", "))
+		}
+	}
+	if len(unreachable) > 0 {
+		return errors.New(tr("%d bookmarks are unreachable", len(unreachable)))
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cloudFolder is a well-known cloud drive directory offered as a bookmark
+type cloudFolder struct {
+	Label string
+	Path  string
+}
+
+// CloudPlaces returns the cloud drive folders present on this machine as
+// places. Labels are fixed per provider so they stay stable across syncs.
+func CloudPlaces() []Place {
+	var places []Place
+	for _, folder := range cloudFolderCandidates() {
+		info, err := os.Stat(folder.Path)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		places = append(places, Place{
+			Label:  folder.Label,
+			Target: fileURI(folder.Path),
+		})
+	}
+	return places
+}
+
+// fileURI converts a local path into a percent-encoded file:// URL
+func fileURI(path string) string {
+	p := filepath.ToSlash(path)
+	if !strings.HasPrefix(p, "/") {
+		// Windows drive paths (C:/Users/...) need a leading slash
+		p = "/" + p
+	}
+	u := url.URL{Scheme: "file", Path: p}
+	return u.String()
+}
+
+// appendMissingPlaces appends the extra places whose target is not already
+// present in places
+func appendMissingPlaces(places []Place, extra []Place) []Place {
+	seen := make(map[string]bool, len(places))
+	for _, place := range places {
+		seen[place.Target] = true
+	}
+	for _, place := range extra {
+		if !seen[place.Target] {
+			places = append(places, place)
+			seen[place.Target] = true
+		}
+	}
+	return places
+}
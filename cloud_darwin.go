@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func cloudFolderCandidates() []cloudFolder {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+
+	folders := []cloudFolder{
+		{Label: "iCloud Drive", Path: filepath.Join(homeDir, "Library", "Mobile Documents", "com~apple~CloudDocs")},
+		{Label: "Dropbox", Path: filepath.Join(homeDir, "Dropbox")},
+	}
+
+	// File Provider based clients (OneDrive, Google Drive, Dropbox) mount
+	// under ~/Library/CloudStorage as <Provider>-<Account>
+	storageDir := filepath.Join(homeDir, "Library", "CloudStorage")
+	entries, err := os.ReadDir(storageDir)
+	if err != nil {
+		return folders
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		provider, account, _ := strings.Cut(name, "-")
+		var label string
+		switch provider {
+		case "OneDrive":
+			label = "OneDrive"
+			if account != "" && account != "Personal" {
+				label = "OneDrive - " + account
+			}
+		case "GoogleDrive":
+			label = "Google Drive"
+		case "Dropbox":
+			label = "Dropbox"
+		default:
+			continue
+		}
+		folders = append(folders, cloudFolder{Label: label, Path: filepath.Join(storageDir, name)})
+	}
+
+	return folders
+}
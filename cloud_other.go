@@ -0,0 +1,9 @@
+//go:build !darwin && !windows
+
+package main
+
+// Cloud drive clients on Linux have no standard location, so nothing is
+// detected here.
+func cloudFolderCandidates() []cloudFolder {
+	return nil
+}
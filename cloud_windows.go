@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func cloudFolderCandidates() []cloudFolder {
+	var folders []cloudFolder
+
+	// The OneDrive client exports its sync roots through the environment
+	if path := os.Getenv("OneDriveConsumer"); path != "" {
+		folders = append(folders, cloudFolder{Label: "OneDrive", Path: path})
+	}
+	if path := os.Getenv("OneDriveCommercial"); path != "" {
+		label := "OneDrive"
+		if _, org, ok := strings.Cut(filepath.Base(path), " - "); ok {
+			label = "OneDrive - " + org
+		}
+		folders = append(folders, cloudFolder{Label: label, Path: path})
+	}
+	if len(folders) == 0 {
+		if path := os.Getenv("OneDrive"); path != "" {
+			folders = append(folders, cloudFolder{Label: "OneDrive", Path: path})
+		}
+	}
+
+	if profile := os.Getenv("USERPROFILE"); profile != "" {
+		folders = append(folders,
+			cloudFolder{Label: "iCloud Drive", Path: filepath.Join(profile, "iCloudDrive")},
+			cloudFolder{Label: "Dropbox", Path: filepath.Join(profile, "Dropbox")},
+		)
+	}
+
+	return folders
+}
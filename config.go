@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config holds the settings of ~/.config/bookmarksync/config.toml. The
+// zero value of every setting keeps the built-in behavior, and options
+// given on the command line take precedence.
+type Config struct {
+	// From is the backend sync copies from when --from isn't given,
+	// instead of the most recently modified one
+	From string `toml:"from"`
+	// Merge unions places into destinations instead of replacing them
+	Merge bool `toml:"merge"`
+	// Backends are the only backends to sync, all of them when empty
+	Backends []string `toml:"backends"`
+	// Disable are backends to leave alone
+	Disable []string `toml:"disable"`
+	// Exclude are glob or re: patterns for places that are never copied
+	// between backends, matched against the label, the target and its
+	// parents and, for local places, the folder and its parents
+	Exclude []string `toml:"exclude"`
+	// Include are patterns like Exclude; when set, only places matching
+	// one of them are copied
+	Include []string `toml:"include"`
+	// ExportExclude are rules for places kept out of exports and remote
+	// stores but still synced between backends: group:NAME, scheme:NAME,
+	// or patterns like Exclude
+	ExportExclude []string `toml:"export_exclude"`
+	// Apps adds the backends of applications with places of their own,
+	// libreoffice and blender, which syncs leave alone unless listed.
+	// They are only synced where the application is installed.
+	Apps []string `toml:"apps"`
+	// Canonical adds the canonical backend, a places file kept with the
+	// dotfiles that syncs are made from unless from says otherwise
+	Canonical bool `toml:"canonical"`
+	// AutoCommit commits the canonical places file after every change when
+	// it is in a git repository
+	AutoCommit bool `toml:"auto_commit"`
+	// AutoPush pushes the commits of auto_commit to the upstream of the
+	// branch
+	AutoPush bool `toml:"auto_push"`
+	// GitRemote adds the git backend, which keeps the places in this git
+	// repository to share them between machines
+	GitRemote string `toml:"git_remote"`
+	// GitBranch is the branch of GitRemote the places are on, main when
+	// unset
+	GitBranch string `toml:"git_branch"`
+	// Remote adds the remote backend, a canonical places file on another
+	// host read and written with ssh, at this ssh://[USER@]HOST/PATH
+	Remote string `toml:"remote"`
+	// WebDAVURL adds the webdav backend, a canonical places file on a
+	// WebDAV server like Nextcloud, at this http(s) URL
+	WebDAVURL string `toml:"webdav_url"`
+	// WebDAVUser is who the webdav backend logs in as
+	WebDAVUser string `toml:"webdav_user"`
+	// WebDAVPasswordCommand prints the password of WebDAVUser, like
+	// "pass show nextcloud"
+	WebDAVPasswordCommand string `toml:"webdav_password_command"`
+	// AgeIdentity is an age key file: when set, the canonical places
+	// file and the places kept by git, remote and webdav are encrypted
+	// with it. $AGE_IDENTITY takes precedence.
+	AgeIdentity string `toml:"age_identity"`
+	// AgeRecipients are the age public keys of other machines the places
+	// are encrypted to as well
+	AgeRecipients []string `toml:"age_recipients"`
+	// Paths are the files of the gtk, gtk2, kde, qt and canonical
+	// backends, by backend name
+	Paths map[string]string `toml:"paths"`
+	// Routes are the backends each backend feeds, by source backend name.
+	// When set, syncs from one backend only write along these routes.
+	Routes map[string][]string `toml:"routes"`
+	// ReadOnly are backends that are synced from but never written
+	ReadOnly []string `toml:"readonly"`
+	// WriteOnly are backends that are written but never synced from
+	WriteOnly []string `toml:"writeonly"`
+	// Order is how written places are ordered, one of placeOrders
+	Order string `toml:"order"`
+	// CustomOrder are the labels or paths that come first, in this order,
+	// with order = "custom"
+	CustomOrder []string `toml:"custom_order"`
+	// Backups is how many copies of each backend's files to keep from
+	// before it was written, 10 when unset; 0 keeps none
+	Backups *int `toml:"backups"`
+	// Simulate are the strategies for what a backend can't store, by
+	// backend name: drop it, or links to give labels to a backend without
+	Simulate map[string]string `toml:"simulate"`
+	// Pins are paths or URLs a backend keeps when it is replaced, by
+	// backend name
+	Pins map[string][]string `toml:"pins"`
+	// Rewrite are rules for the targets of some backends, like a folder
+	// mounted elsewhere on one host
+	Rewrite []rewriteRule `toml:"rewrite"`
+	// Vaults are encrypted folders, besides the Plasma Vaults found in
+	// plasmavaultrc, whose places are only written while they are mounted
+	Vaults []string `toml:"vaults"`
+	// PrivateVolumes keeps the places in vaults and on encrypted or
+	// removable volumes out of exports and remote stores
+	PrivateVolumes bool `toml:"private_volumes"`
+	// Debounce is how long the daemon waits for writes to settle before
+	// syncing, like "2s"; 500ms when unset
+	Debounce time.Duration `toml:"debounce"`
+	// MaxDelay is the longest the daemon puts a sync off while writes keep
+	// coming, like "30s"; unlimited when unset
+	MaxDelay time.Duration `toml:"max_delay"`
+	// Aliases are commands of their own, by name: bookmarksync command
+	// lines chained with &&, like "group enable work && sync --from kde"
+	Aliases map[string]string `toml:"aliases"`
+
+	// Mandated are the bookmarks of the policy file, which every backend
+	// is written with
+	Mandated []Place `toml:"-"`
+	// ForbidSchemes are the URL schemes the policy file forbids
+	ForbidSchemes []string `toml:"-"`
+	// Locked are the settings the policy file locks
+	Locked []string `toml:"-"`
+}
+
+// config is the loaded configuration file
+var config Config
+
+// configDir returns the directory holding bookmarksync's configuration
+func configDir() (string, error) {
+	dir, err := xdgConfigHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bookmarksync"), nil
+}
+
+// LoadConfig reads config.toml over the settings of the policy file,
+// returning an empty configuration if there is neither
+func LoadConfig() (Config, error) {
+	var cfg Config
+	dir, err := configDir()
+	if err != nil {
+		return cfg, err
+	}
+	file := filepath.Join(dir, "config.toml")
+	meta, err := toml.DecodeFile(file, &cfg)
+	if err != nil && !os.IsNotExist(err) {
+		return cfg, fmt.Errorf("%s: %v", file, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return cfg, fmt.Errorf("%s: unknown setting %s", file, undecoded[0])
+	}
+	if err := applyPolicy(&cfg, meta); err != nil {
+		return cfg, err
+	}
+
+	if _, err := NewPlaceFilter(cfg.Include, cfg.Exclude); err != nil {
+		return cfg, fmt.Errorf("%s: %v", file, err)
+	}
+	for _, rule := range cfg.ExportExclude {
+		if _, err := parseExportRule(rule); err != nil {
+			return cfg, fmt.Errorf("%s: export_exclude: %v", file, err)
+		}
+	}
+	for _, name := range cfg.Apps {
+		if !slices.ContainsFunc(appBackends(), func(app BookmarkSyncBackend) bool { return strings.EqualFold(app.Name(), name) }) {
+			return cfg, fmt.Errorf("%s: unknown app %s", file, name)
+		}
+	}
+	for _, name := range cfg.ReadOnly {
+		if slices.Contains(cfg.WriteOnly, name) {
+			return cfg, fmt.Errorf("%s: %s can't be both read-only and write-only", file, name)
+		}
+	}
+	for name, value := range cfg.Paths {
+		if !pathConfigurable(name) {
+			return cfg, fmt.Errorf("%s: the path of %s can't be configured", file, name)
+		}
+		if cfg.Paths[name], err = expandHome(value); err != nil {
+			return cfg, err
+		}
+	}
+	if cfg.GitRemote, err = expandHome(cfg.GitRemote); err != nil {
+		return cfg, err
+	}
+	if cfg.AgeIdentity, err = expandHome(cfg.AgeIdentity); err != nil {
+		return cfg, err
+	}
+	if cfg.Remote != "" {
+		if _, err := parseRemoteURL(cfg.Remote); err != nil {
+			return cfg, fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	if cfg.WebDAVURL != "" {
+		if err := checkWebDAVURL(cfg.WebDAVURL); err != nil {
+			return cfg, fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	if cfg.Debounce < 0 || cfg.MaxDelay < 0 {
+		return cfg, fmt.Errorf("%s: debounce and max_delay can't be negative", file)
+	}
+	if cfg.Backups != nil && *cfg.Backups < 0 {
+		return cfg, fmt.Errorf("%s: backups can't be negative", file)
+	}
+	if err := checkOrder(cfg.Order); err != nil {
+		return cfg, fmt.Errorf("%s: %v", file, err)
+	}
+	for name, strategy := range cfg.Simulate {
+		if !slices.Contains(simulationStrategies, strategy) {
+			return cfg, fmt.Errorf("%s: unknown strategy %q for %s, expected one of %s", file, strategy, name, strings.Join(simulationStrategies, ", "))
+		}
+	}
+	for i, vault := range cfg.Vaults {
+		if cfg.Vaults[i], err = expandHome(vault); err != nil {
+			return cfg, err
+		}
+		if !filepath.IsAbs(cfg.Vaults[i]) {
+			return cfg, fmt.Errorf("%s: vault %q is not an absolute path", file, vault)
+		}
+		cfg.Vaults[i] = filepath.Clean(cfg.Vaults[i])
+	}
+	for name, command := range cfg.Aliases {
+		if err := checkAlias(name, command); err != nil {
+			return cfg, fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	for i := range cfg.Rewrite {
+		if err := cfg.Rewrite[i].check(); err != nil {
+			return cfg, fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	for name, pins := range cfg.Pins {
+		for i, pin := range pins {
+			target, err := placeArg(pin)
+			if err != nil {
+				return cfg, err
+			}
+			if target == "" {
+				return cfg, fmt.Errorf("%s: pin %q of %s is not a path or URL", file, pin, name)
+			}
+			pins[i] = target
+		}
+	}
+	return cfg, nil
+}
+
+// pathConfigurable reports whether the file of the backend called name can
+// be set in the configuration or with --backend-path
+func pathConfigurable(name string) bool {
+	return name == "gtk" || name == "gtk2" || name == "kde" || name == "qt" || name == "canonical"
+}
+
+// backendPathFlag collects --backend-path BACKEND=FILE values into the
+// configured paths
+type backendPathFlag map[string]string
+
+func (f backendPathFlag) String() string {
+	var paths []string
+	for name, path := range f {
+		paths = append(paths, name+"="+path)
+	}
+	return strings.Join(paths, ",")
+}
+
+func (f backendPathFlag) Set(value string) error {
+	name, file, ok := strings.Cut(value, "=")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !ok || file == "" || !pathConfigurable(name) {
+		return fmt.Errorf("expected BACKEND=FILE with BACKEND one of gtk, gtk2, kde, qt, canonical, got %q", value)
+	}
+	file, err := expandHome(file)
+	if err != nil {
+		return err
+	}
+	f[name] = file
+	return nil
+}
+
+// enabled reports whether the configuration lets bookmarksync use the
+// backend called name
+func (c Config) enabled(name string) bool {
+	for _, disabled := range c.Disable {
+		if strings.EqualFold(disabled, name) {
+			return false
+		}
+	}
+	if len(c.Backends) == 0 {
+		return true
+	}
+	for _, enabled := range c.Backends {
+		if strings.EqualFold(enabled, name) {
+			return true
+		}
+	}
+	return false
+}
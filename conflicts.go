@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const conflictHeader = `# The backends' edits since the last sync conflict. Every conflict is
+# a block from <<<<<<< to >>>>>>> with a section per version of the place,
+# headed by the backends it comes from: keep the lines you want, and
+# delete the others and the markers. A section with no line removes it.
+# The other lines are the result of the sync; edit them like with
+# "bookmarksync edit". Quit without saving to leave everything as it is.
+`
+
+// Markers of the conflict blocks, which are git's
+const (
+	conflictStart     = "<<<<<<<"
+	conflictSeparator = "======="
+	conflictEnd       = ">>>>>>>"
+)
+
+// isConflictMarker reports whether line is a marker of a conflict block
+func isConflictMarker(line string) bool {
+	for _, marker := range []string{conflictStart, conflictSeparator, conflictEnd} {
+		if strings.HasPrefix(line, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatConflicts renders merged, the result of a two-way merge, in the
+// edit format with the versions of the conflicting places in conflict
+// blocks where the merge put them
+func formatConflicts(merged []Place, conflicts []Conflict) string {
+	byKey := make(map[string][]Conflict)
+	for _, conflict := range conflicts {
+		byKey[conflict.key] = append(byKey[conflict.key], conflict)
+	}
+
+	var buf strings.Builder
+	buf.WriteString(conflictHeader)
+	for _, place := range merged {
+		found, ok := byKey[normalizeTarget(place.Target)]
+		if !ok {
+			buf.WriteString(editableLine(place))
+			continue
+		}
+		for _, conflict := range found {
+			fmt.Fprintf(&buf, "# %s\n", conflict)
+		}
+		for i, section := range conflictSections(found) {
+			marker := conflictSeparator
+			if i == 0 {
+				marker = conflictStart
+			}
+			fmt.Fprintf(&buf, "%s %s\n%s", marker, strings.Join(section.backends, ", "), section.line)
+		}
+		buf.WriteString(conflictEnd + "\n")
+	}
+	return buf.String()
+}
+
+// conflictSection is a version of a conflicting place and the backends
+// that have it
+type conflictSection struct {
+	backends []string
+	line     string
+}
+
+// conflictSections returns the distinct versions of the conflicts of a
+// place, in the order the backends come in
+func conflictSections(conflicts []Conflict) []conflictSection {
+	var sections []conflictSection
+	index := make(map[string]int)
+	for _, conflict := range conflicts {
+		for _, version := range conflict.versions {
+			line := "# removed\n"
+			if !version.removed {
+				line = editableLine(version.place)
+			}
+			i, ok := index[line]
+			if !ok {
+				i = len(sections)
+				index[line] = i
+				sections = append(sections, conflictSection{line: line})
+			}
+			if !slices.Contains(sections[i].backends, version.backend) {
+				sections[i].backends = append(sections[i].backends, version.backend)
+			}
+		}
+	}
+	return sections
+}
+
+// editConflicts has the user resolve the conflicts of a two-way merge in
+// their editor, and returns the places they settled on
+func editConflicts(merged []Place, conflicts []Conflict) ([]Place, error) {
+	resolved, changed, err := editPlaces(formatConflicts(merged, conflicts), nil)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return nil, errors.New(tr("conflicts not resolved; nothing was written"))
+	}
+	return resolved, nil
+}
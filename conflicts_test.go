@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatConflicts(t *testing.T) {
+	baseline := places("a", "file:///a", "b", "file:///b", "c", "file:///c")
+	merged, conflicts := threeWayMerge(baseline, []placeEdits{
+		diffPlaces("gtk", withLabels, baseline, places("X", "file:///a", "c", "file:///c")),
+		diffPlaces("kde", withLabels, baseline, places("Y", "file:///a", "B", "file:///b", "c", "file:///c")),
+		diffPlaces("qt", withLabels, baseline, places("X", "file:///a", "B", "file:///b", "c", "file:///c")),
+	})
+	got := strings.TrimPrefix(formatConflicts(merged, conflicts), conflictHeader)
+	want := `# file:///a: renamed to "X" and "Y" and "X" in gtk, kde, qt; keeping "X"
+<<<<<<< gtk, qt
+file:///a X
+======= kde
+file:///a Y
+>>>>>>>
+# file:///b: removed in gtk but renamed in kde, qt; keeping it
+<<<<<<< gtk
+# removed
+======= kde, qt
+file:///b B
+>>>>>>>
+file:///c c
+`
+	if got != want {
+		t.Errorf("formatted\n%s\nwant\n%s", got, want)
+	}
+
+	// Left as it is, the markers are problems
+	_, markerProblems := parseEditable(got)
+	markers := 0
+	for _, problem := range markerProblems {
+		if strings.HasSuffix(problem, "unresolved conflict marker") {
+			markers++
+		}
+	}
+	if markers != 6 {
+		t.Errorf("problems %q, want one per marker", markerProblems)
+	}
+	resolved, problems := parseEditable(`# file:///a
+file:///a Y
+file:///c c
+`)
+	if len(problems) != 0 || !reflect.DeepEqual(resolved, places("Y", "file:///a", "c", "file:///c")) {
+		t.Errorf("resolved %v, problems %q", resolved, problems)
+	}
+}
+
+func TestSyncBidirectionalEditConflicts(t *testing.T) {
+	home := testHome(t)
+	testWithoutGTK2(t)
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\n")
+	if _, err := NewBookmarkSync().SyncBidirectional(); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a X\n")
+	if err := NewBookmarkSync().backends["kde"].Replace(places("Y", "file:///a")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Quitting without saving cancels the sync
+	t.Setenv("VISUAL", "true")
+	bs := NewBookmarkSync()
+	bs.EditConflicts = true
+	if _, err := bs.SyncBidirectional(); err == nil {
+		t.Fatal("sync with unresolved conflicts went ahead")
+	}
+
+	// Keep kde's version
+	t.Setenv("VISUAL", `sed -i -e '/^[<=>]\{7\}/d' -e '/^file:\/\/\/a X$/d'`)
+	conflicts, err := bs.SyncBidirectional()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("conflicts %v left after editing them", conflicts)
+	}
+	for _, name := range []string{"gtk", "kde"} {
+		got, err := bs.backends[name].GetPlaces()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := places("Y", "file:///a"); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s holds %v, want %v", name, got, want)
+		}
+	}
+}
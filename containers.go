@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// devContainer is a distrobox or toolbox container as seen from the host
+type devContainer struct {
+	Name       string
+	Manager    string
+	Home       string
+	ConfigHome string
+	DataHome   string
+}
+
+// SharesHost reports whether the container reads the host's own bookmark
+// files, in which case there is nothing to mirror
+func (c devContainer) SharesHost(configHome, dataHome string) bool {
+	return c.ConfigHome == configHome && c.DataHome == dataHome
+}
+
+// Backends returns the GTK, KDE and Qt backends for the container's config
+func (c devContainer) Backends() []BookmarkSyncBackend {
+	prefix := "container:" + c.Name + ":"
+	return []BookmarkSyncBackend{
+		&GTKBackend{BackendName: prefix + "gtk", Path: filepath.Join(c.ConfigHome, "gtk-3.0", "bookmarks")},
+		&KDEBackend{BackendName: prefix + "kde", Path: filepath.Join(c.DataHome, "user-places.xbel")},
+		&QtBackend{BackendName: prefix + "qt", Path: filepath.Join(c.ConfigHome, "QtProject.conf")},
+	}
+}
+
+type containerInspect struct {
+	Name   string
+	Config struct {
+		Env    []string
+		Labels map[string]string
+	}
+}
+
+// listDevContainers finds distrobox and toolbox containers using podman,
+// falling back to docker
+func listDevContainers() ([]devContainer, error) {
+	engine := ""
+	for _, candidate := range []string{"podman", "docker"} {
+		if _, err := exec.LookPath(candidate); err == nil {
+			engine = candidate
+			break
+		}
+	}
+	if engine == "" {
+		return nil, fmt.Errorf("neither podman nor docker found in PATH")
+	}
+
+	out, err := exec.Command(engine, "ps", "-aq").Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s ps: %v", engine, err)
+	}
+	ids := strings.Fields(string(out))
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	out, err = exec.Command(engine, append([]string{"inspect", "--format", "json"}, ids...)...).Output()
+	if err != nil {
+		// docker only accepts Go templates for --format
+		out, err = exec.Command(engine, append([]string{"inspect"}, ids...)...).Output()
+		if err != nil {
+			return nil, fmt.Errorf("%s inspect: %v", engine, err)
+		}
+	}
+
+	var inspected []containerInspect
+	if err := json.Unmarshal(out, &inspected); err != nil {
+		return nil, fmt.Errorf("%s inspect: %v", engine, err)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var containers []devContainer
+	for _, info := range inspected {
+		manager := ""
+		switch {
+		case info.Config.Labels["manager"] == "distrobox":
+			manager = "distrobox"
+		case info.Config.Labels["com.github.containers.toolbox"] == "true":
+			manager = "toolbox"
+		default:
+			continue
+		}
+
+		env := make(map[string]string)
+		for _, kv := range info.Config.Env {
+			if k, v, ok := strings.Cut(kv, "="); ok {
+				env[k] = v
+			}
+		}
+
+		c := devContainer{
+			Name:    strings.TrimPrefix(info.Name, "/"),
+			Manager: manager,
+			Home:    homeDir,
+		}
+		// A distrobox created with --home uses a separate host directory
+		// mounted at the same path
+		if home := env["HOME"]; home != "" {
+			c.Home = home
+		}
+		c.ConfigHome = filepath.Join(c.Home, ".config")
+		if dir := env["XDG_CONFIG_HOME"]; dir != "" {
+			c.ConfigHome = dir
+		}
+		c.DataHome = filepath.Join(c.Home, ".local", "share")
+		if dir := env["XDG_DATA_HOME"]; dir != "" {
+			c.DataHome = dir
+		}
+		containers = append(containers, c)
+	}
+
+	return containers, nil
+}
+
+// runContainers implements "bookmarksync containers list|sync"
+func runContainers(args []string) error {
+	if len(args) == 0 || (args[0] != "list" && args[0] != "sync") {
+		return usageError("bookmarksync-go containers list|sync [-f BACKEND]")
+	}
+
+	fs := flag.NewFlagSet("containers "+args[0], flag.ExitOnError)
+	syncFrom := fs.String("f", "", "Host backend to sync from (default: from in the configuration, else canonical, else gtk)")
+	fs.StringVar(syncFrom, "sync-from", "", "Host backend to sync from (default: from in the configuration, else canonical, else gtk)")
+	fs.Parse(args[1:])
+
+	containers, err := listDevContainers()
+	if err != nil {
+		return err
+	}
+	configHome, err := xdgConfigHome()
+	if err != nil {
+		return err
+	}
+	dataHome, err := xdgDataHome()
+	if err != nil {
+		return err
+	}
+
+	var places []Place
+	if args[0] == "sync" {
+		bs := NewBookmarkSync()
+		source, exists := bs.backends[bs.sourceName(*syncFrom)]
+		if !exists {
+			return fmt.Errorf("unknown backend: %s", bs.sourceName(*syncFrom))
+		}
+		places, err = source.GetPlaces()
+		if err != nil {
+			return fmt.Errorf("failed to get places from %s: %v", source.Name(), err)
+		}
+	}
+
+	for _, c := range containers {
+		if c.SharesHost(configHome, dataHome) {
+			fmt.Print(tr("%s (%s): shares the host home, already in sync\n", c.Name, c.Manager))
+			continue
+		}
+		if args[0] == "list" {
+			fmt.Print(tr("%s (%s): separate home %s\n", c.Name, c.Manager, c.Home))
+			continue
+		}
+
+		fmt.Print(tr("%s (%s): syncing into %s\n", c.Name, c.Manager, c.Home))
+		err := inTransaction(c.Backends(), func() error {
+			for _, backend := range c.Backends() {
+				if err := backend.Replace(places); err != nil {
+					return fmt.Errorf("failed to sync to %s: %v", backend.Name(), err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+const (
+	// dbusName is the well-known session bus name the daemon owns
+	dbusName = "org.gudata.BookmarkSync1"
+	// dbusPath is the object path the daemon's interface is exported at
+	dbusPath = dbus.ObjectPath("/org/gudata/BookmarkSync1")
+	// dbusInterface is the name of the daemon's D-Bus interface
+	dbusInterface = "org.gudata.BookmarkSync1"
+)
+
+// dbusIntrospection describes dbusService for introspecting clients
+const dbusIntrospection = `
+<node>
+	<interface name="` + dbusInterface + `">
+		<method name="Sync"/>
+		<method name="SyncFrom">
+			<arg name="backend" direction="in" type="s"/>
+		</method>
+		<method name="ListBackends">
+			<arg name="backends" direction="out" type="as"/>
+		</method>
+		<method name="ListPlaces">
+			<arg name="backend" direction="in" type="s"/>
+			<arg name="places" direction="out" type="a(ss)"/>
+		</method>
+		<method name="Status">
+			<arg name="status" direction="out" type="s"/>
+			<arg name="paused" direction="out" type="b"/>
+		</method>
+		<method name="Pause"/>
+		<method name="Resume"/>
+		<method name="Stats">
+			<arg name="started" direction="out" type="x"/>
+			<arg name="syncs" direction="out" type="u"/>
+			<arg name="last_sync" direction="out" type="x"/>
+			<arg name="written" direction="out" type="a{sx}"/>
+			<arg name="last_error" direction="out" type="s"/>
+			<arg name="last_error_at" direction="out" type="x"/>
+		</method>
+		<signal name="Synced">
+			<arg name="backend" type="s"/>
+		</signal>
+		<signal name="PausedChanged">
+			<arg name="paused" type="b"/>
+		</signal>
+	</interface>` + introspect.IntrospectDataString + `</node>`
+
+// dbusService is the daemon's session bus interface, which is also what a
+// panel indicator like a GNOME Shell extension talks to. Calls are
+// serialized with the watcher's own syncs, and every sync emits a Synced
+// signal carrying the source backend, or "" when the daemon's configured
+// mode picked it.
+type dbusService struct {
+	conn *dbus.Conn
+	bs   *BookmarkSync
+	run  func() error
+	idle *idleTimer
+	mu   sync.Mutex
+	// paused keeps the watcher from syncing until Resume
+	paused bool
+}
+
+// dbusPlace is how a Place is marshalled on the bus: (label, target)
+type dbusPlace struct {
+	Label  string
+	Target string
+}
+
+// ExportDBus claims dbusName on the session bus and exports the interface.
+// run is the sync the daemon performs on file changes. Every call counts
+// as activity of idle.
+func ExportDBus(bs *BookmarkSync, run func() error, idle *idleTimer) (*dbusService, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &dbusService{conn: conn, bs: bs, run: run, idle: idle}
+	if err := conn.Export(s, dbusPath, dbusInterface); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.Export(introspect.Introspectable(dbusIntrospection), dbusPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reply, err := conn.RequestName(dbusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		conn.Close()
+		return nil, fmt.Errorf("%s is already owned by another process", dbusName)
+	}
+	return s, nil
+}
+
+// Close releases the bus name and the connection
+func (s *dbusService) Close() error {
+	return s.conn.Close()
+}
+
+// Locked wraps the daemon's sync so it never runs concurrently with a
+// D-Bus call and announces itself like one
+func (s *dbusService) Locked(run func() error) func() error {
+	return func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.paused {
+			// Resume syncs what changed in the meantime
+			return nil
+		}
+		if err := run(); err != nil {
+			return err
+		}
+		s.emitSynced("")
+		return nil
+	}
+}
+
+// emitSynced sends the Synced signal
+func (s *dbusService) emitSynced(backend string) {
+	s.conn.Emit(dbusPath, dbusInterface+".Synced", backend)
+}
+
+// Sync runs the daemon's configured sync
+func (s *dbusService) Sync() *dbus.Error {
+	defer s.idle.Begin()()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.run(); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	s.emitSynced("")
+	return nil
+}
+
+// SyncFrom syncs from the named backend to all others
+func (s *dbusService) SyncFrom(backend string) *dbus.Error {
+	defer s.idle.Begin()()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	syncFrom := s.bs.Stats.Wrap(func() error { return s.bs.SyncFrom(backend) })
+	if err := syncFrom(); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	s.emitSynced(backend)
+	return nil
+}
+
+// ListBackends returns the names of the registered backends
+func (s *dbusService) ListBackends() ([]string, *dbus.Error) {
+	defer s.idle.Begin()()
+	return append([]string(nil), s.bs.order...), nil
+}
+
+// ListPlaces returns the places the named backend currently holds. With
+// "" it is the backend syncs and check take for the source: from in the
+// configuration, else canonical, else gtk.
+func (s *dbusService) ListPlaces(backend string) ([]dbusPlace, *dbus.Error) {
+	defer s.idle.Begin()()
+	backend = s.bs.sourceName(backend)
+	source, ok := s.bs.backends[backend]
+	if !ok {
+		return nil, dbus.MakeFailedError(fmt.Errorf("unknown backend: %s", backend))
+	}
+
+	s.mu.Lock()
+	places, err := source.GetPlaces()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, dbus.MakeFailedError(err)
+	}
+
+	result := make([]dbusPlace, 0, len(places))
+	for _, place := range places {
+		result = append(result, dbusPlace{Label: place.Label, Target: place.Target})
+	}
+	return result, nil
+}
+
+// Status returns what status --prompt sums the state up as, synced,
+// diverged or failed, and whether the watcher is paused
+func (s *dbusService) Status() (string, bool, *dbus.Error) {
+	defer s.idle.Begin()()
+	state, err := LoadState()
+	if err != nil {
+		return "", false, dbus.MakeFailedError(err)
+	}
+	s.mu.Lock()
+	paused := s.paused
+	s.mu.Unlock()
+	return statusName(promptStatus(state)), paused, nil
+}
+
+// Pause stops the watcher from syncing on changes until Resume. Sync and
+// SyncFrom still do.
+func (s *dbusService) Pause() *dbus.Error {
+	defer s.idle.Begin()()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.paused {
+		s.paused = true
+		s.conn.Emit(dbusPath, dbusInterface+".PausedChanged", true)
+	}
+	return nil
+}
+
+// Resume lets the watcher sync again, and syncs what changed while it was
+// paused
+func (s *dbusService) Resume() *dbus.Error {
+	defer s.idle.Begin()()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.paused {
+		return nil
+	}
+	s.paused = false
+	s.conn.Emit(dbusPath, dbusInterface+".PausedChanged", false)
+	if err := s.run(); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	s.emitSynced("")
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// kdePlacesPath is the object path the KDE places panel's bookmark manager
+// announces its changes at
+const kdePlacesPath = dbus.ObjectPath("/KBookmarkManager/kfilePlaces")
+
+// fileManagerSignals are the session bus signals file managers send when
+// they change bookmarks, which arrive even where inotify misses writes,
+// like on network home directories
+var fileManagerSignals = []struct {
+	iface, member string
+}{
+	// Dolphin and the KDE file dialogs saving user-places.xbel
+	{"org.kde.KIO.KBookmarkManager", "bookmarksChanged"},
+	{"org.kde.KIO.KBookmarkManager", "bookmarkCompleteChange"},
+	// KIO writing a file, with its URL
+	{"org.kde.KDirNotify", "FilesChanged"},
+}
+
+// signalPaths returns the files a file manager signal says changed: the
+// KDE places file the file managers share for a change of their places,
+// and the local files of the URLs KIO names
+func signalPaths(signal *dbus.Signal) []string {
+	switch signal.Name {
+	case "org.kde.KIO.KBookmarkManager.bookmarksChanged", "org.kde.KIO.KBookmarkManager.bookmarkCompleteChange":
+		if signal.Path != kdePlacesPath {
+			return nil
+		}
+		files, _ := (&KDEBackend{}).Files()
+		return files
+	case "org.kde.KDirNotify.FilesChanged":
+		if len(signal.Body) == 0 {
+			return nil
+		}
+		urls, _ := signal.Body[0].([]string)
+		var paths []string
+		for _, url := range urls {
+			if path, err := localPath(url); err == nil {
+				paths = append(paths, path)
+			}
+		}
+		return paths
+	}
+	return nil
+}
+
+// fileManagerTriggers subscribes to fileManagerSignals until ctx is done.
+// The channel carries the files they say changed. Without a session bus
+// it is nil, and the watcher only has the files to go by.
+func fileManagerTriggers(ctx context.Context) <-chan string {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil
+	}
+	for _, signal := range fileManagerSignals {
+		if err := conn.AddMatchSignal(dbus.WithMatchInterface(signal.iface), dbus.WithMatchMember(signal.member)); err != nil {
+			conn.Close()
+			return nil
+		}
+	}
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	triggers := make(chan string)
+	go func() {
+		defer conn.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case signal, ok := <-signals:
+				if !ok {
+					return
+				}
+				for _, path := range signalPaths(signal) {
+					select {
+					case triggers <- path:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return triggers
+}
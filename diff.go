@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// placeDiffLine is one line of a diff between two place lists: Op is " "
+// for a place both have, "-" for one only the first has and "+" for one
+// only the second has
+type placeDiffLine struct {
+	Op     string `json:"op"`
+	Label  string `json:"label"`
+	Target string `json:"target"`
+}
+
+// diffPlaceLists computes a minimal line diff of two place lists, where
+// places are equal if their normalized targets and labels are
+func diffPlaceLists(a, b []Place) []placeDiffLine {
+	key := func(place Place) string {
+		return normalizeTarget(place.Target) + "\x00" + place.Label
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if key(a[i]) == key(b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []placeDiffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && key(a[i]) == key(b[j]):
+			lines = append(lines, placeDiffLine{" ", a[i].Label, a[i].Target})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, placeDiffLine{"-", a[i].Label, a[i].Target})
+			i++
+		default:
+			lines = append(lines, placeDiffLine{"+", b[j].Label, b[j].Target})
+			j++
+		}
+	}
+	return lines
+}
+
+// runDiff implements "bookmarksync diff BACKEND BACKEND", printing how the
+// second backend's places differ from the first's
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	asJSON := fs.Bool("json", jsonOutput, "Print the diff as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return usageError("bookmarksync-go diff [--json] BACKEND BACKEND")
+	}
+
+	bs := NewBookmarkSync()
+	var lists [2][]Place
+	for i, name := range fs.Args() {
+		backend, exists := bs.backends[strings.ToLower(name)]
+		if !exists {
+			return fmt.Errorf("unknown backend: %s", name)
+		}
+		places, err := backend.GetPlaces()
+		if err != nil {
+			return fmt.Errorf("failed to get places from %s: %v", backend.Name(), err)
+		}
+		lists[i] = places
+	}
+
+	lines := diffPlaceLists(lists[0], lists[1])
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(map[string]interface{}{
+			"from":  fs.Arg(0),
+			"to":    fs.Arg(1),
+			"lines": lines,
+		})
+	}
+
+	fmt.Printf("--- %s\n+++ %s\n", fs.Arg(0), fs.Arg(1))
+	for _, line := range lines {
+		if line.Label != "" {
+			fmt.Printf("%s%s %s\n", line.Op, line.Target, line.Label)
+		} else {
+			fmt.Printf("%s%s\n", line.Op, line.Target)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// command describes a subcommand: how it is dispatched and how it is
+// documented in --help, the man page and the markdown reference
+type command struct {
+	Name    string
+	Usage   string
+	Summary string
+	Run     func(args []string) error
+}
+
+// option describes a flag of the sync itself
+type option struct {
+	Name  string
+	Short string
+	// Arg is the placeholder for the flag's value, empty for booleans
+	Arg  string
+	Help string
+}
+
+// commands are the subcommands, dispatched on the first argument. They are
+// filled in by init because gen-man and gen-markdown read the list
+// themselves. sync comes first: it is the default command, and usage lines
+// show it as optional.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"sync", "[OPTIONS]", "Sync bookmarks between backends (the default command)", runSync},
+		{"daemon", "[OPTIONS]", "Keep syncing whenever a backend's bookmarks change (same as sync --watch)", runDaemon},
+		{"add", "PATH|URL [LABEL]", "Add a bookmark to every backend", runAdd},
+		{"add-project", "[-t TEMPLATE] [--name NAME] [--var KEY=VALUE] [--all] ROOT", "Bookmark a project's folders using a template", runAddProject},
+		{"audit-gtk", "[--fix] [--backend-path gtk=FILE] [FILE...]", "Report GTK bookmarks lines that don't round-trip, and repair them with --fix", runAuditGTK},
+		{"check", "[--from BACKEND] [-q]", "Fail with status 3 when any backend differs from the source, without writing anything", runCheck},
+		{"check-targets", "[--json]", "Report local bookmarks whose folder is missing, without changing anything", runCheckTargets},
+		{"containers", "list|sync [-f BACKEND]", "List running containers or sync bookmarks into them", runContainers},
+		{"diff", "[--json] BACKEND BACKEND", "Show how the second backend's places differ from the first's", runDiff},
+		{"doctor", "", "Check that every backend can be read and show the corrupt files that were quarantined", runDoctor},
+		{"edit", "[-f BACKEND] [--force]", "Edit bookmarks in $EDITOR and write the result to every backend", runEdit},
+		{"export", "[--from BACKEND] [--format json|csv|xbel|html]", "Print a backend's places as JSON, CSV, XBEL or Netscape bookmark HTML", runExport},
+		{"fixtures", "list | fixtures generate [--set NAME] DIR", "Write sample backend files from fixed sets of places, for testing backends", runFixtures},
+		{"gen-launchers", "[-f BACKEND]", "Write a .desktop launcher for every bookmark and remove those of deleted ones", runGenLaunchers},
+		{"gen-man", "", "Print the man page", runGenMan},
+		{"gen-markdown", "", "Print the command reference as markdown", runGenMarkdown},
+		{"group", "list|enable NAME|disable NAME", "List bookmark groups or switch them on and off", runGroup},
+		{"hosts", "[--all] PATH|LABEL [HOST...]", "Show or set the hosts a place of the canonical places file is written on", runHosts},
+		{"install-service", "[--path|--timer INTERVAL|--dbus] [--idle-exit DURATION] [--no-enable] [-- SYNC OPTIONS]", "Install and enable systemd user units that sync on login, or start the daemon on demand with --dbus", runInstallService},
+		{"list", "[--format table|json|csv|tsv] [-l] [BACKEND]", "Print a backend's places, with -l also where each came from", runList},
+		{"open", "[-f BACKEND] NAME", "Open a bookmark with its configured application", runOpen},
+		{"path", "[-f BACKEND] [-l] NAME", "Print the local directory of a bookmark", runPath},
+		{"lock", "PATH|LABEL | lock list", "Keep a place of the canonical places file from being removed or relabelled without --force", runLockPlace},
+		{"pin", "[-f BACKEND] PATH|LABEL | pin list", "Keep a bookmark in a backend even when syncs from others don't have it", runPin},
+		{"prune", "[--dry-run] [--force] [--older-than DURATION]", "Remove the bookmarks of folders that no longer exist from every backend", runPrune},
+		{"remove", "[-f BACKEND] [--force] PATH|LABEL", "Remove a bookmark from every backend", runRemove},
+		{"remove-project", "NAME", "Remove the bookmarks added for a project", runRemoveProject},
+		{"render", "--backend BACKEND [--from BACKEND]", "Print the file a backend would be written with, without writing it", runRender},
+		{"rename", "[-f BACKEND] [--force] PATH|LABEL NEW", "Rename a bookmark in every backend", runRename},
+		{"set-app", "[-f BACKEND] NAME [COMMAND]", "Set the application a bookmark opens with", runSetApp},
+		{"shell-init", "[-f BACKEND] bash|zsh|fish", "Print a shell function that cd's into bookmarks", runShellInit},
+		{"snapshot", "save [--force] NAME | restore NAME [BACKEND...] | list | delete NAME", "Save every backend's places under a name and put them back later", runSnapshot},
+		{"status", "[--json|--prompt|--waybar]", "Show each backend's file, place count and whether it changed since the last sync, and what a running daemon did; --prompt prints one character for shell prompts, --waybar a Waybar module", runStatus},
+		{"temp", "add [--ttl DURATION] [--label LABEL] PATH | temp list", "Add a bookmark that expires, or list them", runTemp},
+		{"undo", "[BACKEND]", "Put the backends' files back as they were before the last sync", runUndo},
+		{"unlock", "PATH|LABEL", "Let syncs and commands remove and relabel a locked place again", runUnlockPlace},
+		{"unpin", "[-f BACKEND] PATH|LABEL", "Stop keeping a pinned bookmark", runUnpin},
+	}
+}
+
+// exitStatuses are the statuses bookmarksync exits with, so scripts can
+// tell failures apart
+var exitStatuses = []struct {
+	Status  int
+	Meaning string
+}{
+	{0, "Success"},
+	{1, "An error stopped the command"},
+	{2, "Invalid command line"},
+	{exitDrift, "check found backends that differ from the source"},
+	{exitReadOnly, "Backends can't be written (read-only filesystem or no permission); nothing was written"},
+	{exitPartialFailure, "Writing failed for some backends; the others were written"},
+	{exitTotalFailure, "Writing failed for every backend"},
+}
+
+// syncOptions are the flags of the sync and daemon commands, in the order
+// they are documented. -f and --sync-from are deprecated aliases of --from
+// and left out.
+var syncOptions = []option{
+	{"from", "", "BACKEND", "Sync from a particular backend (canonical, gtk, gtk2, kde, qt, libreoffice, blender, git, remote, webdav, gtk:NAME)"},
+	{"sync-to", "", "BACKEND,...", "Only write these backends (repeatable)"},
+	{"exclude", "", "PATTERN", "Never copy places whose label, target or folder matches PATTERN, a glob or re:REGEXP (repeatable)"},
+	{"include", "", "PATTERN", "Only copy places matching PATTERN (repeatable)"},
+	{"allow-unsafe", "", "", "Copy places with control characters or javascript:/data: targets instead of ignoring them"},
+	{"merge", "", "", "Merge into destinations instead of replacing them"},
+	{"order", "", "ORDER", "Write places in this order: preserve-source (default), alphabetical-by-label, alphabetical-by-path or custom"},
+	{"prune", "", "", "Drop places whose folder no longer exists before writing"},
+	{"safe", "", "", "Refuse to overwrite backends modified since the last sync"},
+	{"force", "", "", "Let the sync remove and relabel locked places"},
+	{"dry-run", "", "", "Show what would change in each backend without writing"},
+	{"auto", "", "", "Sync from the most recently modified backend (default)"},
+	{"two-way", "", "", "Propagate changes made in any backend since the last run"},
+	{"conflict", "", "MODE", "How two-way syncs settle conflicting edits: auto keeps the newest, or the first backend's (default); edit opens them in $EDITOR"},
+	{"watch", "", "", "Keep running and sync whenever a backend's bookmarks change"},
+	{"debounce", "", "DURATION", "Wait for writes to settle before syncing (default 500ms)"},
+	{"max-delay", "", "DURATION", "Sync at the latest this long after the first write, even while writes keep coming"},
+	{"idle-exit", "", "DURATION", "With --watch, exit after DURATION without syncs or D-Bus calls"},
+	{"cloud-folders", "", "", "Add detected cloud drive folders (macOS, Windows)"},
+	{"gtk-app", "", "NAME=PATH", "Also sync an app-specific GTK bookmarks file (repeatable)"},
+	{"wine-prefix", "", "PATH", "Write places as shortcuts into a Wine/Proton prefix (repeatable)"},
+	{"appimages", "", "", "Also sync AppImages with a portable home/config directory"},
+	{"appimage-dir", "", "DIR", "Directory to scan for AppImages (repeatable)"},
+	{"file-manager-scripts", "", "", "Also write a Bookmarks menu of scripts for Nautilus and Nemo"},
+	{"launchers", "", "", "Also write a .desktop launcher for every bookmark (see gen-launchers)"},
+	{"ssh-hosts", "", "HOST,...", "Add sftp:// places for ~/.ssh/config hosts (* for all)"},
+	{"backend-path", "", "BACKEND=FILE", "Read and write the gtk, gtk2, kde, qt or canonical backend at FILE (repeatable)"},
+	{"simulate", "", "BACKEND=STRATEGY", "How a backend stores what it can't: links gives Qt labels through symlinks named after them, drop loses them (repeatable)"},
+	{"retry", "", "CLASS=N[:BACKOFF]", "Try operations failing with io, stale, busy or timeout errors N times (repeatable)"},
+	{"json", "", "", "Print the result of every sync as a line of JSON (also accepted before any command)"},
+	{"version", "", "", "Show version information"},
+	{"help", "", "", "Show this help message"},
+}
+
+// programName is the name of the binary in usage lines
+const programName = "bookmarksync-go"
+
+// findCommand returns the subcommand called name
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// optionHelp returns the help text of the sync option called name
+func optionHelp(name string) string {
+	for _, opt := range syncOptions {
+		if opt.Name == name || opt.Short == name {
+			return opt.Help
+		}
+	}
+	return ""
+}
+
+// flags formats an option's flags and placeholder, e.g. "--from BACKEND"
+func (o option) flags() string {
+	flags := "--" + o.Name
+	if o.Short != "" {
+		flags = "-" + o.Short + ", " + flags
+	}
+	if o.Arg != "" {
+		flags += " " + o.Arg
+	}
+	return flags
+}
+
+// usageError is the error of a command given the wrong arguments, with
+// its synopsis
+func usageError(synopsis string) error {
+	return errors.New(tr("usage: %s", synopsis))
+}
+
+// printHelp writes the --help text in the user's language
+func printHelp(w io.Writer) {
+	fmt.Fprintln(w, tr("BookmarkSync - A utility to sync bookmarks between GTK+, KDE, and Qt file dialogs"))
+	fmt.Fprint(w, tr("Version: %s\n\n", Version))
+	fmt.Fprintln(w, tr("Usage:"))
+	fmt.Fprintf(w, "  %s [sync] [OPTIONS]\n", programName)
+	for _, cmd := range commands[1:] {
+		fmt.Fprintf(w, "  %s\n", strings.TrimSpace(programName+" "+cmd.Name+" "+cmd.Usage))
+	}
+
+	fmt.Fprintln(w, tr("\nCommands:"))
+	width := 0
+	for _, cmd := range commands {
+		width = max(width, len(cmd.Name))
+	}
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, cmd.Name, tr(cmd.Summary))
+	}
+
+	if len(config.Aliases) > 0 {
+		fmt.Fprintln(w, tr("\nAliases:"))
+		var names []string
+		width := 0
+		for name := range config.Aliases {
+			names = append(names, name)
+			width = max(width, len(name))
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "  %-*s  %s\n", width, name, config.Aliases[name])
+		}
+	}
+
+	fmt.Fprintln(w, tr("\nSync options:"))
+	width = 0
+	for _, opt := range syncOptions {
+		width = max(width, len(opt.flags()))
+	}
+	for _, opt := range syncOptions {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, opt.flags(), tr(opt.Help))
+	}
+}
+
+// runGenMan implements "bookmarksync gen-man", printing a man page in roff
+func runGenMan(args []string) error {
+	w := os.Stdout
+	fmt.Fprintf(w, ".TH BOOKMARKSYNC 1 \"\" \"BookmarkSync %s\" \"User Commands\"\n", Version)
+	fmt.Fprintln(w, ".SH NAME")
+	fmt.Fprintf(w, "%s \\- sync file dialog bookmarks between GTK+, KDE, Qt and other applications\n", roffEscape(programName))
+
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	fmt.Fprintf(w, ".B %s\n[\\fBsync\\fR] [\\fIOPTIONS\\fR]\n", roffEscape(programName))
+	for _, cmd := range commands[1:] {
+		fmt.Fprintf(w, ".br\n.B %s %s\n", roffEscape(programName), roffEscape(cmd.Name))
+		if cmd.Usage != "" {
+			fmt.Fprintln(w, roffEscape(cmd.Usage))
+		}
+	}
+
+	fmt.Fprintln(w, ".SH DESCRIPTION")
+	fmt.Fprintln(w, "Without a command, "+roffEscape(programName)+" syncs the places shown in the sidebar of file dialogs between backends. "+
+		"By default it syncs from whichever backend was modified most recently since the last sync.")
+
+	fmt.Fprintln(w, ".SH COMMANDS")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, ".TP\n.B %s\n%s\n", roffEscape(strings.TrimSpace(cmd.Name+" "+cmd.Usage)), roffEscape(cmd.Summary))
+	}
+
+	fmt.Fprintln(w, ".SH SYNC OPTIONS")
+	for _, opt := range syncOptions {
+		flags := "\\fB\\-\\-" + roffEscape(opt.Name) + "\\fR"
+		if opt.Short != "" {
+			flags = "\\fB\\-" + roffEscape(opt.Short) + "\\fR, " + flags
+		}
+		if opt.Arg != "" {
+			flags += " \\fI" + roffEscape(opt.Arg) + "\\fR"
+		}
+		fmt.Fprintf(w, ".TP\n%s\n%s\n", flags, roffEscape(opt.Help))
+	}
+
+	fmt.Fprintln(w, ".SH EXIT STATUS")
+	for _, exit := range exitStatuses {
+		fmt.Fprintf(w, ".TP\n.B %d\n%s\n", exit.Status, roffEscape(exit.Meaning))
+	}
+	return nil
+}
+
+// roffEscape escapes the characters roff treats specially in running text
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+// runGenMarkdown implements "bookmarksync gen-markdown", printing the
+// command reference as markdown
+func runGenMarkdown(args []string) error {
+	w := os.Stdout
+	fmt.Fprintf(w, "# %s\n\n", programName)
+	fmt.Fprintln(w, "Sync file dialog bookmarks between GTK+, KDE, Qt and other applications.")
+
+	fmt.Fprintln(w, "\n## Usage\n\n```")
+	fmt.Fprintf(w, "%s [sync] [OPTIONS]\n", programName)
+	for _, cmd := range commands[1:] {
+		fmt.Fprintln(w, strings.TrimSpace(programName+" "+cmd.Name+" "+cmd.Usage))
+	}
+	fmt.Fprintln(w, "```")
+
+	fmt.Fprintln(w, "\n## Commands")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "\n### %s\n\n", cmd.Name)
+		fmt.Fprintf(w, "`%s`\n\n", strings.TrimSpace(programName+" "+cmd.Name+" "+cmd.Usage))
+		fmt.Fprintln(w, cmd.Summary+".")
+	}
+
+	fmt.Fprintln(w, "\n## Sync options\n\n| Option | Description |\n| --- | --- |")
+	for _, opt := range syncOptions {
+		fmt.Fprintf(w, "| `%s` | %s |\n", opt.flags(), strings.ReplaceAll(opt.Help, "|", `\|`))
+	}
+
+	fmt.Fprintln(w, "\n## Exit status\n\n| Status | Meaning |\n| --- | --- |")
+	for _, exit := range exitStatuses {
+		fmt.Fprintf(w, "| %d | %s |\n", exit.Status, exit.Meaning)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import "fmt"
+
+// planChanges computes what syncing places into each destination would
+// change, without writing anything
+func (bs *BookmarkSync) planChanges(places []Place, destinations []BookmarkSyncBackend) []BackendReport {
+	state, err := LoadState()
+	if err != nil {
+		state = &State{}
+	}
+	var plans []BackendReport
+	for _, backend := range destinations {
+		plan := BackendReport{Name: backend.Name(), Status: "planned"}
+		current, err := backend.GetPlaces()
+		if err != nil {
+			plan.Status, plan.Error = "failed", err.Error()
+			plans = append(plans, plan)
+			continue
+		}
+
+		caps := capabilitiesOf(backend)
+		result := bs.written(state, plan.Name, caps, current, places)
+
+		edits := diffPlaces(plan.Name, caps, current, result)
+		for _, key := range edits.order {
+			plan.Added = append(plan.Added, edits.added[key])
+		}
+		for _, place := range current {
+			key := normalizeTarget(place.Target)
+			if edits.deleted[key] {
+				plan.Removed = append(plan.Removed, place)
+			}
+			if moved, ok := edits.moves[key]; ok {
+				plan.Moved = append(plan.Moved, PlaceMove{Label: place.Label, From: place.Target, To: moved.Target})
+			}
+			if label, ok := edits.relabels[key]; ok {
+				plan.Relabeled = append(plan.Relabeled, PlaceRelabel{Target: place.Target, From: place.Label, To: label})
+			}
+		}
+		if len(plan.Added)+len(plan.Removed)+len(plan.Moved)+len(plan.Relabeled) == 0 {
+			if samePlaces(current, result, caps) {
+				plan.Status = "unchanged"
+			} else {
+				plan.Reordered = true
+			}
+		}
+		plans = append(plans, plan)
+	}
+	return plans
+}
+
+// written returns what syncing places into the backend called name, which
+// currently has current, leaves there
+func (bs *BookmarkSync) written(state *State, name string, caps Capabilities, current, places []Place) []Place {
+	result := representable(places, caps)
+	if bs.Merge {
+		return orderPlaces(withLocked(withoutForbidden(mergePlaces(current, result))))
+	}
+	return orderPlaces(withLocked(withoutForbidden(withPins(pinnedPlaces(state, name), current, bs.Filter.Held(current, result)))))
+}
+
+// printPlan shows what syncing places into each destination would change,
+// or adds it to the report with --json
+func (bs *BookmarkSync) printPlan(places []Place, destinations []BookmarkSyncBackend) {
+	bs.printPlans(bs.planChanges(places, destinations))
+}
+
+// printPlans shows planned changes, or adds them to the report with --json
+func (bs *BookmarkSync) printPlans(plans []BackendReport) {
+	if bs.Report != nil {
+		bs.Report.Backends = append(bs.Report.Backends, plans...)
+		return
+	}
+
+	for _, plan := range plans {
+		switch {
+		case plan.Error != "":
+			fmt.Print(tr("%s: cannot read current places: %v\n", plan.Name, plan.Error))
+			continue
+		case plan.Status == "unchanged":
+			fmt.Print(tr("%s: no changes\n", plan.Name))
+			continue
+		case plan.Reordered:
+			fmt.Print(tr("%s: reordered\n", plan.Name))
+			continue
+		}
+
+		fmt.Printf("%s:\n", plan.Name)
+		for _, place := range plan.Added {
+			fmt.Printf("  + %s\n", describePlace(place))
+		}
+		for _, place := range plan.Removed {
+			fmt.Printf("  - %s\n", describePlace(place))
+		}
+		for _, move := range plan.Moved {
+			fmt.Printf("  > %s: %s -> %s\n", move.Label, move.From, move.To)
+		}
+		for _, relabel := range plan.Relabeled {
+			fmt.Printf("  ~ %s: %q -> %q\n", relabel.Target, relabel.From, relabel.To)
+		}
+	}
+}
+
+// describePlace formats a place as its label and target
+func describePlace(place Place) string {
+	if place.Label == "" {
+		return place.Target
+	}
+	return fmt.Sprintf("%s (%s)", place.Label, place.Target)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const editHeader = `# Edit your bookmarks, one per line: TARGET LABEL
+# TARGET is a URL (file:///home/me/Music, sftp://host/path) or an absolute
+# path. The label is everything after the first space and may be omitted.
+# Reorder lines to reorder bookmarks, delete a line to remove it.
+# Lines starting with # are ignored; an empty file removes everything.
+`
+
+// formatEditable renders places in the edit format
+func formatEditable(places []Place) string {
+	var buf strings.Builder
+	buf.WriteString(editHeader)
+	for _, place := range places {
+		buf.WriteString(editableLine(place))
+	}
+	return buf.String()
+}
+
+// editableLine renders a place as a line of the edit format
+func editableLine(place Place) string {
+	if place.Label != "" {
+		return fmt.Sprintf("%s %s\n", place.Target, place.Label)
+	}
+	return place.Target + "\n"
+}
+
+// parseEditable parses and validates the edit format, collecting every
+// problem with its line number
+func parseEditable(content string) ([]Place, []string) {
+	var places []Place
+	var problems []string
+	seen := make(map[string]int)
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if isConflictMarker(line) {
+			problems = append(problems, fmt.Sprintf("line %d: unresolved conflict marker", lineNo))
+			continue
+		}
+
+		target, label, _ := strings.Cut(line, " ")
+		label = strings.TrimSpace(label)
+		if strings.HasPrefix(target, "/") {
+			target = fileURI(target)
+		}
+
+		u, err := url.Parse(target)
+		if err != nil || u.Scheme == "" {
+			problems = append(problems, fmt.Sprintf("line %d: %q is not a URL or absolute path", lineNo, target))
+			continue
+		}
+		key := normalizeTarget(target)
+		if first, ok := seen[key]; ok {
+			problems = append(problems, fmt.Sprintf("line %d: duplicate of line %d", lineNo, first))
+			continue
+		}
+		seen[key] = lineNo
+
+		if label == "" {
+			label = filepath.Base(u.Path)
+			if decoded, err := url.PathUnescape(label); err == nil {
+				label = decoded
+			}
+		}
+		places = append(places, Place{Label: label, Target: target})
+	}
+
+	return places, problems
+}
+
+// runEditor opens path in $VISUAL or $EDITOR (vi by default)
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// Go through the shell so EDITOR may carry arguments ("code --wait")
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// runEdit implements "bookmarksync edit": the bookmarks of one backend are
+// opened in the user's editor and the result is written to every backend
+func runEdit(args []string) error {
+	fs := flag.NewFlagSet("edit", flag.ExitOnError)
+	force := fs.Bool("force", false, "Let the edit remove and relabel locked bookmarks")
+	places, err := sourcePlaces(fs, args)
+	if err != nil {
+		return err
+	}
+	keepLocked = !*force
+
+	edited, changed, err := editPlaces(formatEditable(places), changedLocked)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		fmt.Println(tr("No changes"))
+		return nil
+	}
+	return NewBookmarkSync().ReplaceAll(edited)
+}
+
+// editPlaces opens content, in the edit format, in the user's editor until
+// it is saved without problems, those of check included, and returns the
+// places it holds and whether it was changed at all
+func editPlaces(content string, check func([]Place) []string) ([]Place, bool, error) {
+	file, err := os.CreateTemp("", "bookmarksync-*.txt")
+	if err != nil {
+		return nil, false, err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(content)
+	file.Close()
+	if err != nil {
+		return nil, false, err
+	}
+
+	for {
+		if err := runEditor(file.Name()); err != nil {
+			return nil, false, fmt.Errorf("editor failed: %v", err)
+		}
+		data, err := os.ReadFile(file.Name())
+		if err != nil {
+			return nil, false, err
+		}
+		if string(data) == content {
+			return nil, false, nil
+		}
+
+		edited, problems := parseEditable(string(data))
+		if check != nil {
+			problems = append(problems, check(edited)...)
+		}
+		if len(problems) == 0 {
+			return edited, true, nil
+		}
+
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, problem)
+		}
+		fmt.Fprint(os.Stderr, "Edit again? [Y/n] ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(answer)), "n") {
+			return nil, false, fmt.Errorf("bookmarks not changed")
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// ageMagic starts an age encrypted file, and armor.Header one armored as
+// text
+const ageMagic = "age-encryption.org/v1\n"
+
+// encryptionKeys returns the identities the places files of the canonical
+// store are encrypted with, from $AGE_IDENTITY, a key or the file of one,
+// or else the age_identity key file; none when encryption is off
+func encryptionKeys() ([]age.Identity, error) {
+	source := os.Getenv("AGE_IDENTITY")
+	name := "AGE_IDENTITY"
+	if source == "" {
+		source, name = config.AgeIdentity, config.AgeIdentity
+	}
+	if source == "" {
+		return nil, nil
+	}
+	var r io.Reader = strings.NewReader(source)
+	if !strings.HasPrefix(source, "AGE-SECRET-KEY-") {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+		r, name = bytes.NewReader(data), source
+	}
+	identities, err := age.ParseIdentities(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return identities, nil
+}
+
+// encryptedHeader starts an encrypted places file, followed by a comment
+// line for every key it is encrypted to
+const encryptedHeader = "# Places synced by bookmarksync, encrypted with age to:\n"
+
+// sealPlaces encrypts data, a rendered places file, to the identities of
+// encryptionKeys and the age_recipients, armored so that it stays text.
+// Without keys it is returned as is. When the file at path, if given,
+// already holds data encrypted to the same keys, that is returned instead:
+// encrypting again would change every byte, and have git commit and file
+// synchronizers copy a file whose places are the same.
+func sealPlaces(data []byte, path string) ([]byte, error) {
+	identities, err := encryptionKeys()
+	if err != nil || identities == nil {
+		return data, err
+	}
+	var recipients []age.Recipient
+	for _, identity := range identities {
+		if x25519, ok := identity.(*age.X25519Identity); ok {
+			recipients = append(recipients, x25519.Recipient())
+		}
+	}
+	for _, recipient := range config.AgeRecipients {
+		parsed, err := age.ParseRecipients(strings.NewReader(recipient))
+		if err != nil {
+			return nil, fmt.Errorf("age_recipients: %v", err)
+		}
+		recipients = append(recipients, parsed...)
+	}
+	if len(recipients) == 0 {
+		return nil, errors.New("the age identity has no X25519 key to encrypt to")
+	}
+	var buf bytes.Buffer
+	buf.WriteString(encryptedHeader)
+	for _, recipient := range recipients {
+		fmt.Fprintf(&buf, "#   %s\n", recipient)
+	}
+
+	if path != "" {
+		current, err := os.ReadFile(path)
+		if err == nil && bytes.HasPrefix(current, buf.Bytes()) {
+			if plain, err := openPlaces(path, current); err == nil && bytes.Equal(plain, data) {
+				return current, nil
+			}
+		}
+	}
+
+	armored := armor.NewWriter(&buf)
+	w, err := age.Encrypt(armored, recipients...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if err := armored.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// openPlaces decrypts data, the places file called name, when it is
+// encrypted, and returns it as is when it isn't, so that a store can be
+// encrypted from the next write on. A file that was cut short is corrupt.
+func openPlaces(name string, data []byte) ([]byte, error) {
+	// Past the comments of encryptedHeader
+	text := data
+	for bytes.HasPrefix(text, []byte("#")) {
+		_, rest, found := bytes.Cut(text, []byte("\n"))
+		if !found {
+			break
+		}
+		text = rest
+	}
+	text = bytes.TrimLeft(text, " \t\r\n")
+	isArmored := bytes.HasPrefix(text, []byte(armor.Header))
+	if !isArmored && !bytes.HasPrefix(data, []byte(ageMagic)) {
+		return data, nil
+	}
+	identities, err := encryptionKeys()
+	if err != nil {
+		return nil, err
+	}
+	if identities == nil {
+		return nil, fmt.Errorf("%s is encrypted: set age_identity or AGE_IDENTITY to its key", name)
+	}
+
+	var r io.Reader = bytes.NewReader(data)
+	if isArmored {
+		r = armor.NewReader(bytes.NewReader(text))
+	}
+	plain, err := age.Decrypt(r, identities...)
+	if err == nil {
+		data, err = io.ReadAll(plain)
+	}
+	var noMatch *age.NoIdentityMatchError
+	switch {
+	case errors.As(err, &noMatch):
+		return nil, fmt.Errorf("%s is encrypted with another key than age_identity or AGE_IDENTITY", name)
+	case err != nil:
+		return nil, &corruptError{File: name, Err: err}
+	}
+	return data, nil
+}
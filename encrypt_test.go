@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// testAgeKey makes a new age key the encryption key of the test, and
+// returns it
+func testAgeKey(t *testing.T) *age.X25519Identity {
+	t.Helper()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AGE_IDENTITY", identity.String())
+	return identity
+}
+
+func TestEncryptedCanonicalRoundTrip(t *testing.T) {
+	home := testHome(t)
+	identity := testAgeKey(t)
+	path := filepath.Join(home, "places.toml")
+	backend := &CanonicalBackend{Path: path}
+
+	written := places("Secret project", "file:///srv/secret", "Server", "sftp://host/srv")
+	if err := backend.Replace(written); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), encryptedHeader) || !strings.Contains(string(data), identity.Recipient().String()) {
+		t.Errorf("file doesn't start with the header naming the key:\n%s", data)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("file holds the places in the clear:\n%s", data)
+	}
+	got, err := backend.GetPlaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, written) {
+		t.Errorf("read %v, want %v", got, written)
+	}
+
+	// Writing the same places again leaves the file as it is
+	if err := backend.Replace(written); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(path); string(again) != string(data) {
+		t.Error("rewriting the same places encrypted them again")
+	}
+}
+
+func TestOpenPlacesErrors(t *testing.T) {
+	testHome(t)
+	testAgeKey(t)
+	sealed, err := sealPlaces([]byte("[[place]]\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another machine's key
+	testAgeKey(t)
+	if _, err := openPlaces("places.toml", sealed); err == nil || !strings.Contains(err.Error(), "another key") {
+		t.Errorf("with a wrong key: error %v, want one naming the key", err)
+	}
+
+	// No key at all
+	t.Setenv("AGE_IDENTITY", "")
+	if _, err := openPlaces("places.toml", sealed); err == nil || !strings.Contains(err.Error(), "set age_identity") {
+		t.Errorf("without a key: error %v, want one asking for it", err)
+	}
+
+	// A file a write cut short is corrupt, and gets recovered
+	testAgeKey(t)
+	sealed, err = sealPlaces([]byte("[[place]]\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var corrupt *corruptError
+	if _, err := openPlaces("places.toml", sealed[:len(sealed)-40]); !errors.As(err, &corrupt) {
+		t.Errorf("cut short: error %v, want a corrupt file", err)
+	}
+
+	// Plain files are read as they are, to encrypt from the next write on
+	if data, err := openPlaces("places.toml", []byte("[[place]]\n")); err != nil || string(data) != "[[place]]\n" {
+		t.Errorf("plain file read as %q, %v", data, err)
+	}
+}
+
+func TestEncryptionKeysFromFile(t *testing.T) {
+	testHome(t)
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AGE_IDENTITY", "")
+	config.AgeIdentity = writeFile(t, t.TempDir(), "key.txt", "# created: today\n"+identity.String()+"\n")
+	keys, err := encryptionKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].(*age.X25519Identity).String() != identity.String() {
+		t.Errorf("keys %v, want the key file's", keys)
+	}
+
+	config.AgeIdentity = writeFile(t, t.TempDir(), "key.txt", "not a key\n")
+	if _, err := encryptionKeys(); err == nil {
+		t.Error("read a key file without a key")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Exit statuses besides 1 for errors in general and 2 for bad usage
+const (
+	// exitDrift is the exit status of a check that found backends which
+	// differ from the source
+	exitDrift = 3
+	// exitReadOnly is the exit status of a sync that found backends it
+	// can't write and only reported what it would change
+	exitReadOnly = 4
+	// exitPartialFailure is the exit status of a write that failed for
+	// some backends and succeeded for others
+	exitPartialFailure = 5
+	// exitTotalFailure is the exit status of a write that failed for
+	// every backend it tried
+	exitTotalFailure = 6
+)
+
+// exitCoder is an error that ends bookmarksync with a status of its own
+type exitCoder interface {
+	ExitCode() int
+}
+
+// exitStatus returns the status bookmarksync ends with after err
+func exitStatus(err error) int {
+	var coder exitCoder
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+	return 1
+}
+
+// backendError is why a backend couldn't be written
+type backendError struct {
+	Backend string
+	Err     error
+}
+
+// backendErrors collects the outcome of writing several backends, so that
+// one failing doesn't stop the others and all failures are summed up at
+// the end
+type backendErrors struct {
+	// attempted counts the backends a write was tried on
+	attempted int
+	failed    []backendError
+}
+
+// note records the outcome of writing a backend, err being nil when it
+// worked
+func (e *backendErrors) note(backend string, err error) {
+	e.attempted++
+	if err != nil {
+		e.failed = append(e.failed, backendError{Backend: backend, Err: err})
+	}
+}
+
+// err returns e as an error when any backend failed, nil otherwise
+func (e *backendErrors) err() error {
+	if len(e.failed) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error lists every failed backend with its error, one per line
+func (e *backendErrors) Error() string {
+	var b strings.Builder
+	b.WriteString(tr("%d of %d backends failed:", len(e.failed), e.attempted))
+	for _, failure := range e.failed {
+		fmt.Fprintf(&b, "\n  %s: %v", failure.Backend, failure.Err)
+	}
+	return b.String()
+}
+
+func (e *backendErrors) Unwrap() []error {
+	errs := make([]error, len(e.failed))
+	for i, failure := range e.failed {
+		errs[i] = failure.Err
+	}
+	return errs
+}
+
+// ExitCode tells a partial failure from a total one
+func (e *backendErrors) ExitCode() int {
+	if len(e.failed) == e.attempted {
+		return exitTotalFailure
+	}
+	return exitPartialFailure
+}
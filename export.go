@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportXBEL is a plain XBEL document, without the metadata KDE adds
+type exportXBEL struct {
+	XMLName xml.Name `xml:"xbel"`
+	Version string   `xml:"version,attr"`
+	// Namespace declares the prefix of the freedesktop.org metadata
+	Namespace string           `xml:"xmlns:bookmark,attr,omitempty"`
+	Title     string           `xml:"title"`
+	Bookmarks []exportBookmark `xml:"bookmark"`
+}
+
+type exportBookmark struct {
+	Href     string      `xml:"href,attr"`
+	Added    string      `xml:"added,attr,omitempty"`
+	Modified string      `xml:"modified,attr,omitempty"`
+	Title    string      `xml:"title"`
+	Info     *exportInfo `xml:"info,omitempty"`
+}
+
+// exportInfo holds the application a place opens with, the way the
+// freedesktop.org desktop bookmark spec stores it in XBEL
+type exportInfo struct {
+	Metadata struct {
+		Owner string `xml:"owner,attr"`
+		App   struct {
+			Name string `xml:"name,attr"`
+			Exec string `xml:"exec,attr"`
+		} `xml:"bookmark:applications>bookmark:application"`
+	} `xml:"metadata"`
+}
+
+// newExportInfo returns the info of a place that opens with command
+func newExportInfo(command string) *exportInfo {
+	info := &exportInfo{}
+	info.Metadata.Owner = "http://freedesktop.org"
+	info.Metadata.App.Name = filepath.Base(strings.Fields(command)[0])
+	info.Metadata.App.Exec = command
+	return info
+}
+
+// exportMeta is what exports that can hold it, which browsers sort and
+// search by, get to know about a place besides its label and target: when
+// it was added and relabelled, from the canonical places file or else its
+// provenance, and the groups it was added with as tags, and the command
+// set-app chose to open it with
+type exportMeta struct {
+	Added    time.Time
+	Modified time.Time
+	Tags     []string
+	App      string
+}
+
+// exportedPlace is a place as the json format exports it
+type exportedPlace struct {
+	Place
+	Added    string   `json:"added,omitempty"`
+	Modified string   `json:"modified,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	App      string   `json:"app,omitempty"`
+}
+
+// exportMetadata returns the metadata of places, by normalized target,
+// with the times of entries, those of the canonical places file
+func exportMetadata(places []Place, entries map[string]canonicalPlace) (map[string]exportMeta, error) {
+	state, err := LoadState()
+	if err != nil {
+		return nil, err
+	}
+	var groups []string
+	for name := range state.Groups {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+	hints, err := readOpenWith()
+	if err != nil {
+		return nil, err
+	}
+	apps := make(map[string]string, len(hints))
+	for target, command := range hints {
+		if command = strings.TrimSpace(command); command != "" {
+			apps[normalizeTarget(target)] = command
+		}
+	}
+
+	meta := make(map[string]exportMeta, len(places))
+	for _, place := range places {
+		key := normalizeTarget(place.Target)
+		p := state.Provenance[key]
+		m := exportMeta{Added: p.Added, Modified: p.Modified, App: apps[key]}
+		// The canonical file's times are the same on every machine
+		if entry, ok := entries[key]; ok && !entry.Created.IsZero() {
+			m.Added, m.Modified = entry.Created, entry.Modified
+		}
+		for _, name := range groups {
+			if slices.ContainsFunc(state.Groups[name].Places, func(member Place) bool { return normalizeTarget(member.Target) == key }) {
+				m.Tags = append(m.Tags, name)
+			}
+		}
+		meta[key] = m
+	}
+	return meta, nil
+}
+
+// formatExportTime returns t as seconds since the epoch when unix is
+// set, RFC 3339 otherwise, and "" when it isn't known
+func formatExportTime(t time.Time, unix bool) string {
+	switch {
+	case t.IsZero():
+		return ""
+	case unix:
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// runExport implements "bookmarksync export [--from BACKEND] [--format
+// FORMAT]", printing a backend's places for archiving them or handing them
+// to tools that aren't file managers
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	from := fs.String("from", "", "Backend to export the places of (default: from in the configuration, else canonical, else gtk)")
+	format := fs.String("format", "json", "Output format: json, csv, xbel or html")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		return usageError("bookmarksync-go export [--from BACKEND] [--format json|csv|xbel|html]")
+	}
+
+	bs := NewBookmarkSync()
+	backend, exists := bs.backends[bs.sourceName(*from)]
+	if !exists {
+		return fmt.Errorf("unknown backend: %s", bs.sourceName(*from))
+	}
+	places, err := backend.GetPlaces()
+	if err != nil {
+		return fmt.Errorf("failed to get places from %s: %v", backend.Name(), err)
+	}
+	places, err = exportedPlaces(places)
+	if err != nil {
+		return err
+	}
+
+	meta, err := exportMetadata(places, bs.canonicalEntries())
+	if err != nil {
+		return err
+	}
+	data, err := exportPlaces(places, meta, *format)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// exportPlaces returns places in format, with what meta knows about them
+// where the format has room for it
+func exportPlaces(places []Place, meta map[string]exportMeta, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		exported := []exportedPlace{}
+		for _, place := range places {
+			m := meta[normalizeTarget(place.Target)]
+			exported = append(exported, exportedPlace{
+				Place:    place,
+				Added:    formatExportTime(m.Added, false),
+				Modified: formatExportTime(m.Modified, false),
+				Tags:     m.Tags,
+				App:      m.App,
+			})
+		}
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(exported); err != nil {
+			return nil, err
+		}
+	case "csv":
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"label", "target", "added", "modified", "tags", "app"})
+		for _, place := range places {
+			m := meta[normalizeTarget(place.Target)]
+			w.Write([]string{place.Label, place.Target, formatExportTime(m.Added, false), formatExportTime(m.Modified, false), strings.Join(m.Tags, ","), m.App})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, err
+		}
+	case "xbel":
+		xbel := exportXBEL{Version: "1.0", Title: "Places"}
+		for _, place := range places {
+			m := meta[normalizeTarget(place.Target)]
+			bookmark := exportBookmark{
+				Href:     place.Target,
+				Added:    formatExportTime(m.Added, false),
+				Modified: formatExportTime(m.Modified, false),
+				Title:    exportLabel(place),
+			}
+			if m.App != "" {
+				bookmark.Info = newExportInfo(m.App)
+				xbel.Namespace = "http://www.freedesktop.org/standards/desktop-bookmarks"
+			}
+			xbel.Bookmarks = append(xbel.Bookmarks, bookmark)
+		}
+		buf.WriteString(xml.Header)
+		buf.WriteString(`<!DOCTYPE xbel PUBLIC "+//IDN python.org//DTD XML Bookmark Exchange Language 1.0//EN//XML" "http://www.python.org/topics/xml/dtds/xbel-1.0.dtd">` + "\n")
+		encoder := xml.NewEncoder(&buf)
+		encoder.Indent("", "  ")
+		if err := encoder.Encode(&xbel); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\n")
+	case "html":
+		// The Netscape bookmark file format every browser imports
+		buf.WriteString("<!DOCTYPE NETSCAPE-Bookmark-file-1>\n")
+		buf.WriteString(`<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">` + "\n")
+		buf.WriteString("<TITLE>Places</TITLE>\n<H1>Places</H1>\n<DL><p>\n")
+		for _, place := range places {
+			m := meta[normalizeTarget(place.Target)]
+			attrs := fmt.Sprintf(" HREF=\"%s\"", html.EscapeString(place.Target))
+			if added := formatExportTime(m.Added, true); added != "" {
+				attrs += fmt.Sprintf(" ADD_DATE=\"%s\"", added)
+			}
+			if modified := formatExportTime(m.Modified, true); modified != "" {
+				attrs += fmt.Sprintf(" LAST_MODIFIED=\"%s\"", modified)
+			}
+			if len(m.Tags) > 0 {
+				attrs += fmt.Sprintf(" TAGS=\"%s\"", html.EscapeString(strings.Join(m.Tags, ",")))
+			}
+			fmt.Fprintf(&buf, "    <DT><A%s>%s</A>\n", attrs, html.EscapeString(exportLabel(place)))
+		}
+		buf.WriteString("</DL><p>\n")
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+	return buf.Bytes(), nil
+}
+
+// exportLabel is the title a place is exported with, which formats
+// without an unlabelled bookmark need
+func exportLabel(place Place) string {
+	if place.Label != "" {
+		return place.Label
+	}
+	return defaultLabel(place.Target)
+}
+
+// exportRule is an export_exclude rule: group:NAME for the places added
+// together from a template, scheme:NAME for every place of a URL scheme,
+// or a glob or re: pattern like those of exclude
+type exportRule struct {
+	group   string
+	scheme  string
+	pattern placePattern
+}
+
+// parseExportRule parses an export_exclude rule
+func parseExportRule(s string) (exportRule, error) {
+	if group, ok := strings.CutPrefix(s, "group:"); ok {
+		if group == "" {
+			return exportRule{}, fmt.Errorf("invalid rule %q: no group name", s)
+		}
+		return exportRule{group: group}, nil
+	}
+	if scheme, ok := strings.CutPrefix(s, "scheme:"); ok {
+		if scheme == "" {
+			return exportRule{}, fmt.Errorf("invalid rule %q: no scheme", s)
+		}
+		return exportRule{scheme: strings.ToLower(strings.TrimSuffix(scheme, "://"))}, nil
+	}
+	pattern, err := parsePlacePattern(s)
+	if err != nil {
+		return exportRule{}, err
+	}
+	return exportRule{pattern: pattern}, nil
+}
+
+// exportedPlaces returns the places that may leave the machine, in exports
+// and remote pushes: those no export_exclude rule matches and, with
+// private_volumes, that aren't in a vault or on an encrypted or removable
+// volume. Backends on the machine still get every place.
+func exportedPlaces(places []Place) ([]Place, error) {
+	places, err := withoutPrivatePlaces(places)
+	if err != nil || len(config.ExportExclude) == 0 {
+		return places, err
+	}
+
+	var rules []exportRule
+	for _, s := range config.ExportExclude {
+		rule, err := parseExportRule(s)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	grouped := map[string]bool{}
+	state, err := LoadState()
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if rule.group == "" {
+			continue
+		}
+		for _, place := range state.Groups[rule.group].Places {
+			grouped[normalizeTarget(place.Target)] = true
+		}
+	}
+
+	var kept []Place
+	for _, place := range places {
+		if !slices.ContainsFunc(rules, func(rule exportRule) bool { return rule.excludes(place, grouped) }) {
+			kept = append(kept, place)
+		}
+	}
+	return kept, nil
+}
+
+// excludes reports whether the rule keeps place from being exported.
+// grouped are the targets of the groups the rules name.
+func (r exportRule) excludes(place Place, grouped map[string]bool) bool {
+	switch {
+	case r.group != "":
+		return grouped[normalizeTarget(place.Target)]
+	case r.scheme != "":
+		scheme, _, _ := strings.Cut(place.Target, ":")
+		return strings.EqualFold(scheme, r.scheme)
+	}
+	return r.pattern.matches(filterCandidates(place))
+}
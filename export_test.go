@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportPlaces(t *testing.T) {
+	exported := places("Code", "file:///srv/code", "", "sftp://host/srv")
+	added := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	meta := map[string]exportMeta{
+		normalizeTarget("file:///srv/code"): {Added: added, Tags: []string{"work"}, App: "code --new-window"},
+	}
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"json", []string{`"label": "Code"`, `"added": "2026-01-02T03:04:05Z"`, `"tags": [`, `"app": "code --new-window"`}},
+		{"csv", []string{"label,target,added,modified,tags,app\n", "Code,file:///srv/code,2026-01-02T03:04:05Z,,work,code --new-window\n", ",sftp://host/srv,,,,\n"}},
+		{"xbel", []string{
+			`xmlns:bookmark="http://www.freedesktop.org/standards/desktop-bookmarks"`,
+			`<bookmark href="file:///srv/code" added="2026-01-02T03:04:05Z">`,
+			`<bookmark:application name="code" exec="code --new-window"></bookmark:application>`,
+			`<title>host/srv</title>`,
+		}},
+		{"html", []string{`<A HREF="file:///srv/code" ADD_DATE="1767323045" TAGS="work">Code</A>`}},
+	}
+	for _, test := range tests {
+		data, err := exportPlaces(exported, meta, test.format)
+		if err != nil {
+			t.Fatalf("%s: %v", test.format, err)
+		}
+		for _, want := range test.want {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s export lacks %s:\n%s", test.format, want, data)
+			}
+		}
+	}
+	if _, err := exportPlaces(exported, meta, "yaml"); err == nil {
+		t.Error("unknown format exported")
+	}
+}
+
+// Regression test: exports dropped the commands set-app chose
+func TestExportMetadataApps(t *testing.T) {
+	home := testHome(t)
+	writeFile(t, home, ".config/bookmarksync/open-with", "file:///srv/code/\tcode\n")
+
+	meta, err := exportMetadata(places("Code", "file:///srv/code"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app := meta[normalizeTarget("file:///srv/code")].App; app != "code" {
+		t.Errorf("app = %q, want code", app)
+	}
+}
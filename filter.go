@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// PlaceFilter decides which places are propagated between backends. A
+// place is propagated when it matches no exclude pattern and, if there are
+// include patterns, at least one of them. Places it rejects stay in the
+// backends that have them but are never copied to others. A nil filter
+// propagates everything.
+type PlaceFilter struct {
+	include []placePattern
+	exclude []placePattern
+}
+
+// placePattern is a glob, or a regular expression when written re:EXPR
+type placePattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+// parsePlacePattern parses a glob or re: pattern
+func parsePlacePattern(s string) (placePattern, error) {
+	if expr, ok := strings.CutPrefix(s, "re:"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return placePattern{}, fmt.Errorf("invalid pattern %q: %v", s, err)
+		}
+		return placePattern{re: re}, nil
+	}
+	if _, err := path.Match(s, ""); err != nil {
+		return placePattern{}, fmt.Errorf("invalid pattern %q: %v", s, err)
+	}
+	return placePattern{glob: s}, nil
+}
+
+// NewPlaceFilter parses include and exclude patterns, returning nil when
+// there are none
+func NewPlaceFilter(include, exclude []string) (*PlaceFilter, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, nil
+	}
+	f := &PlaceFilter{}
+	for _, s := range include {
+		p, err := parsePlacePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, p)
+	}
+	for _, s := range exclude {
+		p, err := parsePlacePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, p)
+	}
+	return f, nil
+}
+
+// Allows reports whether place is propagated
+func (f *PlaceFilter) Allows(place Place) bool {
+	if f == nil {
+		return true
+	}
+	candidates := filterCandidates(place)
+	for _, p := range f.exclude {
+		if p.matches(candidates) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		if p.matches(candidates) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns the places that are propagated
+func (f *PlaceFilter) Apply(places []Place) []Place {
+	if f == nil {
+		return places
+	}
+	kept := make([]Place, 0, len(places))
+	for _, place := range places {
+		if f.Allows(place) {
+			kept = append(kept, place)
+		}
+	}
+	return kept
+}
+
+// Held returns places followed by the places of current the filter
+// rejects, which a destination keeps when it is replaced
+func (f *PlaceFilter) Held(current, places []Place) []Place {
+	if f == nil {
+		return places
+	}
+	seen := make(map[string]bool, len(places))
+	for _, place := range places {
+		seen[normalizeTarget(place.Target)] = true
+	}
+	held := slices.Clip(places)
+	for _, place := range current {
+		if !f.Allows(place) && !seen[normalizeTarget(place.Target)] {
+			held = append(held, place)
+		}
+	}
+	return held
+}
+
+// filterCandidates are the strings a pattern may match for place: the
+// label, the target and its parents and, for local places, the folder and
+// its parents, so smb://* covers every share and /mnt/scratch everything
+// under it
+func filterCandidates(place Place) []string {
+	candidates := []string{place.Label}
+	for target := strings.TrimSuffix(place.Target, "/"); ; {
+		candidates = append(candidates, target)
+		i := strings.LastIndex(target, "/")
+		if i < 0 || strings.HasSuffix(target[:i], ":/") {
+			break
+		}
+		target = target[:i]
+	}
+	if dir, err := localPath(place.Target); err == nil && dir != "" {
+		for ; dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+			candidates = append(candidates, dir)
+		}
+	}
+	return candidates
+}
+
+// matches reports whether the pattern matches any of the candidates.
+// Regular expressions match anywhere unless anchored.
+func (p placePattern) matches(candidates []string) bool {
+	for _, candidate := range candidates {
+		if p.re != nil {
+			if p.re.MatchString(candidate) {
+				return true
+			}
+		} else if ok, _ := path.Match(p.glob, candidate); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// patternFlag collects a repeatable pattern flag. Unlike stringListFlag it
+// doesn't split on commas, which regular expressions use.
+type patternFlag []string
+
+func (f *patternFlag) String() string {
+	return strings.Join(*f, " ")
+}
+
+func (f *patternFlag) Set(value string) error {
+	if _, err := parsePlacePattern(value); err != nil {
+		return err
+	}
+	*f = append(*f, value)
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlaceFilter(t *testing.T) {
+	tests := []struct {
+		name             string
+		include, exclude []string
+		place            Place
+		want             bool
+	}{
+		{"no patterns", nil, nil, Place{Label: "a", Target: "file:///a"}, true},
+		{"excluded scheme", nil, []string{"smb://*"}, Place{Label: "share", Target: "smb://nas/share"}, false},
+		{"excluded folder covers its subfolders", nil, []string{"/mnt/scratch"}, Place{Label: "tmp", Target: "file:///mnt/scratch/tmp"}, false},
+		{"excluded label", nil, []string{"Work *"}, Place{Label: "Work stuff", Target: "file:///w"}, false},
+		{"other places pass the exclude", nil, []string{"smb://*"}, Place{Label: "a", Target: "file:///a"}, true},
+		{"regular expression anywhere", nil, []string{"re:secret"}, Place{Label: "x", Target: "file:///home/jo/secret/x"}, false},
+		{"anchored regular expression", nil, []string{"re:^secret"}, Place{Label: "x", Target: "file:///home/jo/secret/x"}, true},
+		{"included", []string{"/home/*"}, nil, Place{Label: "docs", Target: "file:///home/jo"}, true},
+		{"not included", []string{"/home/*"}, nil, Place{Label: "srv", Target: "file:///srv"}, false},
+		{"exclude wins over include", []string{"/home/*"}, []string{"re:jo"}, Place{Label: "docs", Target: "file:///home/jo"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := NewPlaceFilter(test.include, test.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Allows(test.place); got != test.want {
+				t.Errorf("Allows(%v) = %v, want %v", test.place, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPlaceFilterHeld(t *testing.T) {
+	f, err := NewPlaceFilter(nil, []string{"smb://*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := places("a", "file:///a", "share", "smb://nas/share")
+	incoming := places("b", "file:///b", "other", "smb://nas/other")
+	if got, want := f.Apply(incoming), places("b", "file:///b"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Apply() = %v, want %v", got, want)
+	}
+	// A destination keeps its own excluded places when it is replaced
+	if got, want := f.Held(current, f.Apply(incoming)), places("b", "file:///b", "share", "smb://nas/share"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Held() = %v, want %v", got, want)
+	}
+}
+
+func TestInvalidPatterns(t *testing.T) {
+	for _, pattern := range []string{"re:(", "[a-"} {
+		if _, err := NewPlaceFilter(nil, []string{pattern}); err == nil {
+			t.Errorf("pattern %q accepted", pattern)
+		}
+		var flag patternFlag
+		if err := flag.Set(pattern); err == nil {
+			t.Errorf("--exclude %q accepted", pattern)
+		}
+	}
+	if f, err := NewPlaceFilter(nil, nil); f != nil || err != nil {
+		t.Errorf("NewPlaceFilter() without patterns = %v, %v, want nil", f, err)
+	}
+}
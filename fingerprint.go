@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Fingerprint identifies the contents of a backend's files as bookmarksync
+// last wrote them
+type Fingerprint struct {
+	ModTime time.Time `json:"mtime"`
+	Hash    string    `json:"hash"`
+}
+
+// fingerprint hashes a backend's files along with their paths, so that a
+// file appearing or disappearing changes the fingerprint too
+func fingerprint(backend BookmarkSyncBackend) (Fingerprint, error) {
+	files, err := backend.Files()
+	if err != nil {
+		return Fingerprint{}, err
+	}
+
+	var fp Fingerprint
+	hash := sha256.New()
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return Fingerprint{}, err
+		}
+		info, err := f.Stat()
+		if err == nil {
+			if info.ModTime().After(fp.ModTime) {
+				fp.ModTime = info.ModTime()
+			}
+			fmt.Fprintf(hash, "%s\x00%d\x00", file, info.Size())
+			_, err = io.Copy(hash, f)
+		}
+		f.Close()
+		if err != nil {
+			return Fingerprint{}, err
+		}
+	}
+	fp.Hash = hex.EncodeToString(hash.Sum(nil))
+	return fp, nil
+}
+
+// fingerprints records the current fingerprint of every backend
+func (bs *BookmarkSync) fingerprints() map[string]Fingerprint {
+	fps := make(map[string]Fingerprint, len(bs.order))
+	for _, backend := range bs.Backends() {
+		fp, err := fingerprint(backend)
+		if err != nil {
+			continue
+		}
+		fps[backend.Name()] = fp
+	}
+	return fps
+}
+
+// ChangedBackends returns the backends modified outside bookmarksync since
+// the last sync, newest first. A backend counts as changed when its
+// contents differ from the fingerprint recorded after that sync; touching
+// a file without changing it does not count, and neither does a backend
+// whose files are gone. Backends without a recorded fingerprint fall back
+// to comparing modification times.
+func (bs *BookmarkSync) ChangedBackends(state *State) ([]string, error) {
+	var changed []string
+	mtimes := make(map[string]time.Time)
+	for _, backend := range bs.Backends() {
+		name := backend.Name()
+		fp, err := fingerprint(backend)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s: %v", name, err)
+		}
+		if fp.ModTime.IsZero() {
+			continue
+		}
+		recorded, ok := state.Fingerprints[name]
+		if ok && recorded.Hash == fp.Hash {
+			continue
+		}
+		if !ok && !fp.ModTime.After(state.Synced) {
+			continue
+		}
+		mtimes[name] = fp.ModTime
+		changed = append(changed, name)
+	}
+	sort.SliceStable(changed, func(i, j int) bool {
+		return mtimes[changed[i]].After(mtimes[changed[j]])
+	})
+	return changed, nil
+}
+
+// checkUnchanged returns an error naming any of backends that was modified
+// outside bookmarksync since the last sync
+func (bs *BookmarkSync) checkUnchanged(backends []BookmarkSyncBackend) error {
+	state, err := LoadState()
+	if err != nil {
+		return fmt.Errorf("failed to load state: %v", err)
+	}
+	changed, err := bs.ChangedBackends(state)
+	if err != nil {
+		return err
+	}
+
+	var modified []string
+	for _, backend := range backends {
+		for _, name := range changed {
+			if backend.Name() == name {
+				modified = append(modified, name)
+			}
+		}
+	}
+	if len(modified) > 0 {
+		return fmt.Errorf("refusing to overwrite %s, modified since the last sync (use --merge, or sync from it)", strings.Join(modified, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	backend := &GTKBackend{Path: filepath.Join(dir, "bookmarks")}
+	missing, err := fingerprint(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !missing.ModTime.IsZero() {
+		t.Errorf("missing file modified at %v", missing.ModTime)
+	}
+
+	path := writeFile(t, dir, "bookmarks", "file:///a a\n")
+	written, err := fingerprint(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written.Hash == missing.Hash {
+		t.Error("fingerprint unchanged by the file appearing")
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	touched, err := fingerprint(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if touched.Hash != written.Hash {
+		t.Error("fingerprint changed by touching the file")
+	}
+	if !touched.ModTime.After(written.ModTime) {
+		t.Errorf("modified at %v, want after %v", touched.ModTime, written.ModTime)
+	}
+
+	writeFile(t, dir, "bookmarks", "file:///b a\n")
+	edited, err := fingerprint(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Hash == written.Hash {
+		t.Error("fingerprint unchanged by an edit of the same size")
+	}
+
+	// The same contents in another file aren't the same backend
+	other, err := fingerprint(&GTKBackend{Path: writeFile(t, dir, "other", "file:///b a\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Hash == edited.Hash {
+		t.Error("fingerprint doesn't include the path")
+	}
+}
+
+func TestChangedBackends(t *testing.T) {
+	home := testHome(t)
+	testWithoutGTK2(t)
+	gtk := writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\n")
+	bs := NewBookmarkSync()
+	if err := bs.SyncFrom("gtk"); err != nil {
+		t.Fatal(err)
+	}
+	state, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Fingerprints) == 0 || state.Synced.IsZero() {
+		t.Fatalf("sync not recorded: %+v", state)
+	}
+	changed, err := bs.ChangedBackends(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("changed %v right after the sync", changed)
+	}
+	if status := quickStatus(state, bs.backends["gtk"]); status != "in sync" {
+		t.Errorf("gtk is %s, want in sync", status)
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(gtk, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := bs.ChangedBackends(state); len(changed) != 0 {
+		t.Errorf("changed %v by touching a file", changed)
+	}
+	if status := quickStatus(state, bs.backends["gtk"]); status != "in sync" {
+		t.Errorf("touched gtk is %s, want in sync", status)
+	}
+
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\nfile:///b b\n")
+	changed, err = bs.ChangedBackends(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changed, []string{"gtk"}) {
+		t.Errorf("changed %v, want [gtk]", changed)
+	}
+	if status := quickStatus(state, bs.backends["gtk"]); status != "changed since last sync" {
+		t.Errorf("edited gtk is %s", status)
+	}
+	if err := bs.checkUnchanged([]BookmarkSyncBackend{bs.backends["gtk"]}); err == nil {
+		t.Error("overwriting the edited gtk wasn't refused")
+	}
+	if err := bs.checkUnchanged([]BookmarkSyncBackend{bs.backends["kde"]}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	testHome(t)
+	state, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(state, &State{}) {
+		t.Errorf("state without a file is %+v", state)
+	}
+	synced := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	state = &State{
+		Baseline:     places("a", "file:///a"),
+		Synced:       synced,
+		Fingerprints: map[string]Fingerprint{"gtk": {ModTime: synced, Hash: "abc"}},
+	}
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, state) {
+		t.Errorf("loaded %+v, want %+v", loaded, state)
+	}
+}
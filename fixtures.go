@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fixtureSet is a named list of places the fixtures are generated from
+type fixtureSet struct {
+	Name        string
+	Description string
+	Places      []Place
+}
+
+// fixtureSets are the corpora of "bookmarksync fixtures generate". They
+// are fixed, so the files generated from them only change when a backend
+// writes differently.
+func fixtureSets() []fixtureSet {
+	var large []Place
+	for i := 1; i <= 1000; i++ {
+		path := fmt.Sprintf("/home/user/Projects/area-%02d/project-%04d", i%50, i)
+		large = append(large, Place{Label: fmt.Sprintf("Project %d", i), Target: fileURI(path)})
+	}
+
+	return []fixtureSet{
+		{"basic", "A few local folders and a server, as most users have", []Place{
+			{Label: "Documents", Target: fileURI("/home/user/Documents")},
+			{Label: "Music", Target: fileURI("/home/user/Music")},
+			{Label: "Projects", Target: fileURI("/home/user/Projects")},
+			{Label: "Server", Target: "sftp://user@example.com/srv/www"},
+		}},
+		{"encodings", "Non-ASCII labels and percent-encoded paths in several scripts", []Place{
+			{Label: "Übungen", Target: fileURI("/home/user/Übungen")},
+			{Label: "Фотографии", Target: fileURI("/home/user/Фотографии")},
+			{Label: "写真", Target: fileURI("/home/user/写真")},
+			{Label: "מסמכים", Target: fileURI("/home/user/מסמכים")},
+			{Label: "Café ☕", Target: fileURI("/home/user/Café ☕")},
+			{Label: "Naïve résumé", Target: fileURI("/home/user/Naïve résumé")},
+		}},
+		{"special-characters", "Characters that need quoting in URIs, XML or INI files", []Place{
+			{Label: "With spaces", Target: fileURI("/home/user/With spaces")},
+			{Label: "C++", Target: fileURI("/home/user/C++")},
+			{Label: "100% done", Target: fileURI("/home/user/100% done")},
+			{Label: "Issue #42", Target: fileURI("/home/user/Issue #42")},
+			{Label: "Tom & Jerry", Target: fileURI("/home/user/Tom & Jerry")},
+			{Label: "<angle> \"quoted\" 'single'", Target: fileURI("/home/user/angle quoted")},
+			{Label: "semi;colon, comma=equals", Target: fileURI("/home/user/semi;colon, comma=equals")},
+			{Label: "back\\slash", Target: fileURI("/home/user/back\\slash")},
+		}},
+		{"remote", "Network places of every common scheme", []Place{
+			{Label: "SFTP", Target: "sftp://user@example.com/home/user"},
+			{Label: "SFTP port", Target: "sftp://user@example.com:2222/data"},
+			{Label: "SMB share", Target: "smb://fileserver/share"},
+			{Label: "WebDAV", Target: "davs://cloud.example.com/remote.php/webdav"},
+			{Label: "FTP", Target: "ftp://ftp.example.com/pub"},
+			{Label: "NFS", Target: "nfs://nas.example.com/export/home"},
+		}},
+		{"edge-cases", "Unlabelled places, long labels, deep paths and duplicates written differently", []Place{
+			{Label: "", Target: fileURI("/home/user/Unlabelled")},
+			{Label: strings.Repeat("Long label ", 23), Target: fileURI("/home/user/Long")},
+			{Label: "Deep", Target: fileURI("/home/user" + strings.Repeat("/level", 40))},
+			{Label: "Root", Target: fileURI("/")},
+			{Label: "Same label", Target: fileURI("/home/user/First")},
+			{Label: "Same label", Target: fileURI("/home/user/Second")},
+			{Label: "Trailing slash", Target: fileURI("/home/user/Trailing/")},
+			{Label: "Localhost", Target: "file://localhost/home/user/Localhost"},
+		}},
+		{"large", "A thousand places, for performance and limits", large},
+	}
+}
+
+// fixtureBackends returns the backends whose files fixtures are generated
+// for, each writing below dir
+func fixtureBackends(dir string) []BookmarkSyncBackend {
+	return []BookmarkSyncBackend{
+		&GTKBackend{Path: filepath.Join(dir, "gtk", "bookmarks")},
+		&KDEBackend{Path: filepath.Join(dir, "kde", "user-places.xbel")},
+		&QtBackend{Path: filepath.Join(dir, "qt", "QtProject.conf")},
+		&LibreOfficeBackend{Profile: filepath.Join(dir, "libreoffice")},
+	}
+}
+
+// writeFixtures writes set to dir/NAME: places.json with the places it is
+// made of, and for every backend the file it writes for them next to
+// places.json with what the backend reads back from that file, which
+// differs where the backend can't store everything
+func writeFixtures(dir string, set fixtureSet) error {
+	dir = filepath.Join(dir, set.Name)
+	if err := writeFixtureJSON(filepath.Join(dir, "places.json"), set.Places); err != nil {
+		return err
+	}
+	for _, backend := range fixtureBackends(dir) {
+		backendDir := filepath.Join(dir, backend.Name())
+		if err := os.MkdirAll(backendDir, 0755); err != nil {
+			return err
+		}
+		if err := backend.Replace(set.Places); err != nil {
+			return fmt.Errorf("%s: %s: %v", set.Name, backend.Name(), err)
+		}
+		read, err := backend.GetPlaces()
+		if err != nil {
+			return fmt.Errorf("%s: %s: %v", set.Name, backend.Name(), err)
+		}
+		if err := writeFixtureJSON(filepath.Join(backendDir, "places.json"), read); err != nil {
+			return err
+		}
+	}
+
+	// The same GTK file with Windows line endings, as editors and file
+	// syncing between systems leave it; it reads back the same places
+	data, err := os.ReadFile(filepath.Join(dir, "gtk", "bookmarks"))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "gtk", "bookmarks.crlf"), bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n")), 0644)
+}
+
+// writeFixtureJSON writes places as indented JSON, an empty list rather
+// than null when there are none
+func writeFixtureJSON(path string, places []Place) error {
+	if places == nil {
+		places = []Place{}
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(places); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// runFixtures implements "bookmarksync fixtures list|generate", which
+// writes sample backend files from fixed sets of places, so that new
+// backends and changes to existing ones can be checked against the same
+// corpus: reading a generated file must give its places.json back.
+func runFixtures(args []string) error {
+	usage := usageError("bookmarksync-go fixtures list | fixtures generate [--set NAME] DIR")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "list":
+		for _, set := range fixtureSets() {
+			fmt.Printf("%s\t%d places\t%s\n", set.Name, len(set.Places), set.Description)
+		}
+		return nil
+	case "generate":
+		fs := flag.NewFlagSet("fixtures generate", flag.ExitOnError)
+		only := fs.String("set", "", "Only generate this set")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return usage
+		}
+		dir := fs.Arg(0)
+
+		generated := 0
+		for _, set := range fixtureSets() {
+			if *only != "" && set.Name != *only {
+				continue
+			}
+			if err := writeFixtures(dir, set); err != nil {
+				return err
+			}
+			generated++
+		}
+		if generated == 0 {
+			return fmt.Errorf("no fixture set named %s", *only)
+		}
+		fmt.Print(tr("Generated %d fixture sets in %s\n", generated, dir))
+		return nil
+	}
+	return usage
+}
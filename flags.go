@@ -0,0 +1,20 @@
+package main
+
+import "strings"
+
+// stringListFlag collects a flag that may be repeated or given a
+// comma-separated list
+type stringListFlag []string
+
+func (f *stringListFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringListFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*f = append(*f, item)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// matchPlaces returns the places matching a user-supplied name, trying
+// progressively looser rules and stopping at the first that matches:
+// exact label or target, case-insensitive label, label prefix, label or
+// target substring, and finally the name's letters appearing in order.
+// loose reports whether the matches came from the rules past the
+// case-insensitive label, which guess at what the name means.
+func matchPlaces(places []Place, name string) (matches []Place, loose bool) {
+	query := strings.ToLower(name)
+	rules := []func(label, target string) bool{
+		func(label, target string) bool { return label == name || target == name },
+		func(label, target string) bool { return strings.ToLower(label) == query },
+		func(label, target string) bool { return strings.HasPrefix(strings.ToLower(label), query) },
+		func(label, target string) bool {
+			return strings.Contains(strings.ToLower(label), query) || strings.Contains(strings.ToLower(target), query)
+		},
+		func(label, target string) bool { return isSubsequence(query, strings.ToLower(label)) },
+	}
+
+	for i, rule := range rules {
+		for _, place := range places {
+			if rule(place.Label, place.Target) {
+				matches = append(matches, place)
+			}
+		}
+		if len(matches) > 0 {
+			return matches, i > 1
+		}
+	}
+	return nil, false
+}
+
+// isSubsequence reports whether the runes of needle appear in haystack in order
+func isSubsequence(needle, haystack string) bool {
+	rest := []rune(needle)
+	for _, r := range haystack {
+		if len(rest) == 0 {
+			break
+		}
+		if r == rest[0] {
+			rest = rest[1:]
+		}
+	}
+	return len(rest) == 0
+}
+
+// choosePlace asks the user to pick one of several matching places. The
+// menu goes to stderr so commands whose output is captured by the shell
+// (like path) can still prompt.
+func choosePlace(name string, matches []Place) (Place, error) {
+	if !stdinIsTerminal() {
+		var labels []string
+		for _, place := range matches {
+			labels = append(labels, fmt.Sprintf("%q", place.Label))
+		}
+		return Place{}, errors.New(tr("%q is ambiguous: %s", name, strings.Join(labels, ", ")))
+	}
+
+	fmt.Fprint(os.Stderr, tr("%q matches several bookmarks:\n", name))
+	for i, place := range matches {
+		fmt.Fprintf(os.Stderr, "  %d) %s  %s\n", i+1, place.Label, place.Target)
+	}
+	fmt.Fprint(os.Stderr, tr("Choose one: "))
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return Place{}, err
+	}
+	choice, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || choice < 1 || choice > len(matches) {
+		return Place{}, errors.New(tr("invalid choice %q", strings.TrimSpace(line)))
+	}
+	return matches[choice-1], nil
+}
+
+// confirmPlace asks the user whether they meant the places name matched
+// loosely, for commands that change the place they're given rather than
+// guess at it. Without a terminal to ask on, name has to be exact.
+func confirmPlace(name string, matches []Place) (Place, error) {
+	if !stdinIsTerminal() {
+		var labels []string
+		for _, place := range matches {
+			labels = append(labels, fmt.Sprintf("%q", place.Label))
+		}
+		return Place{}, errors.New(tr("no bookmark is called %q; did you mean %s?", name, strings.Join(labels, ", ")))
+	}
+	if len(matches) > 1 {
+		return choosePlace(name, matches)
+	}
+
+	fmt.Fprint(os.Stderr, tr("No bookmark is called %q; did you mean %s (%s)? [y/N] ", name, matches[0].Label, matches[0].Target))
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return Place{}, err
+	}
+	if answer := strings.ToLower(strings.TrimSpace(line)); answer != "y" && answer != "yes" && answer != tr("y") && answer != tr("yes") {
+		return Place{}, errors.New(tr("nothing was changed"))
+	}
+	return matches[0], nil
+}
+
+// stdinIsTerminal reports whether the user can be asked on stdin
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// testStdin makes data what the test reads from stdin, which isn't a
+// terminal
+func testStdin(t *testing.T, data string) {
+	t.Helper()
+	saved := os.Stdin
+	file, err := os.Open(writeFile(t, t.TempDir(), "stdin", data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdin = file
+	t.Cleanup(func() {
+		os.Stdin = saved
+		file.Close()
+	})
+}
+
+func TestMatchPlaces(t *testing.T) {
+	bookmarks := places("Documents", "file:///home/jo/Documents", "Downloads", "file:///home/jo/Downloads")
+	tests := []struct {
+		name   string
+		labels string
+		loose  bool
+	}{
+		{"Documents", "Documents", false},
+		{"downloads", "Downloads", false},
+		{"do", "Documents Downloads", true},
+		{"dcs", "Documents", true},
+		{"music", "", false},
+	}
+	for _, test := range tests {
+		matches, loose := matchPlaces(bookmarks, test.name)
+		var labels []string
+		for _, place := range matches {
+			labels = append(labels, place.Label)
+		}
+		if strings.Join(labels, " ") != test.labels || loose != test.loose {
+			t.Errorf("matchPlaces(%q) = %v, %v, want %s, %v", test.name, labels, loose, test.labels, test.loose)
+		}
+	}
+}
+
+func TestResolvePlaceNeedsExactLabel(t *testing.T) {
+	testStdin(t, "")
+	bookmarks := places("Documents", "file:///home/jo/Documents", "Downloads", "file:///home/jo/Downloads")
+
+	for _, name := range []string{"Documents", "documents", "/home/jo/Documents"} {
+		if place, err := resolvePlace(bookmarks, name); err != nil || place.Label != "Documents" {
+			t.Errorf("resolvePlace(%q) = %v, %v, want Documents", name, place, err)
+		}
+	}
+	if _, err := resolvePlace(bookmarks, "docs"); err == nil || !strings.Contains(err.Error(), `did you mean "Documents"`) {
+		t.Errorf("resolvePlace(\"docs\") error %v, want it to ask for the exact label", err)
+	}
+}
+
+func TestRemoveNeedsExactLabel(t *testing.T) {
+	home := testHome(t)
+	testStdin(t, "")
+	testWithoutGTK2(t)
+	gtk := writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///home/jo/Documents Documents\n")
+
+	if err := runRemove([]string{"doc"}); err == nil {
+		t.Error("removed the bookmark a loose match guessed at")
+	}
+	if data, _ := os.ReadFile(gtk); string(data) != "file:///home/jo/Documents Documents\n" {
+		t.Errorf("gtk holds %q", data)
+	}
+	if err := runRemove([]string{"documents"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(gtk); string(data) != "" {
+		t.Errorf("gtk still holds %q", data)
+	}
+}
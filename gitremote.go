@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitPlacesFile is the file GitBackend keeps the places in, laid out like
+// the canonical places file
+const gitPlacesFile = "places.toml"
+
+// GitBackend implements BookmarkSyncBackend for a git repository several
+// machines share: the places are pulled from it before every read, and
+// committed and pushed after every write, so each machine's syncs pick up
+// the others'. Places the machines changed before either pushed are merged
+// like the edits of a two-way sync.
+type GitBackend struct {
+	// Remote is the URL of the repository
+	Remote string
+	// Branch is the branch the places are on, main when empty
+	Branch string
+}
+
+func (g *GitBackend) Name() string {
+	return "git"
+}
+
+// dir returns the clone of the repository bookmarksync keeps
+func (g *GitBackend) dir() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "git"), nil
+}
+
+func (g *GitBackend) branch() string {
+	if g.Branch != "" {
+		return g.Branch
+	}
+	return "main"
+}
+
+func (g *GitBackend) Files() ([]string, error) {
+	dir, err := g.dir()
+	if err != nil {
+		return nil, err
+	}
+	return []string{filepath.Join(dir, gitPlacesFile)}, nil
+}
+
+// file is the places file of the clone
+func (g *GitBackend) file(dir string) *CanonicalBackend {
+	return &CanonicalBackend{Path: filepath.Join(dir, gitPlacesFile)}
+}
+
+// overSSH reports whether the remote is reached with ssh, whose keys may
+// be the keyring's
+func (g *GitBackend) overSSH() bool {
+	if scheme, _, ok := strings.Cut(g.Remote, "://"); ok {
+		return scheme == "ssh" || scheme == "git+ssh"
+	}
+	// Or scp-like, [USER@]HOST:PATH
+	host, _, ok := strings.Cut(g.Remote, ":")
+	return ok && host != "" && !strings.Contains(host, "/")
+}
+
+// runGit runs git in dir, returning its output. Its errors carry what git
+// printed about them.
+func runGit(dir string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	// Credentials have to come from an agent or helper: there is nobody
+	// to ask in the daemon
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return "", fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// clone sets the clone up on first use, and points it at Remote
+func (g *GitBackend) clone() (string, error) {
+	dir, err := g.dir()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		if url, _ := runGit(dir, "remote", "get-url", "origin"); url != g.Remote {
+			if _, err := runGit(dir, "remote", "set-url", "origin", g.Remote); err != nil {
+				return "", err
+			}
+		}
+		return dir, nil
+	}
+
+	// An empty repository and one without the branch yet can't be
+	// cloned with it checked out, so the clone starts out empty and
+	// pull fills it in
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"remote", "add", "origin", g.Remote},
+		{"symbolic-ref", "HEAD", "refs/heads/" + g.branch()},
+	} {
+		if _, err := runGit(dir, args...); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// pull brings the clone up to date with the remote. Places committed here
+// and there since they last agreed are merged in a merge commit.
+func (g *GitBackend) pull(dir string) error {
+	if err := checkKeyring(g.overSSH()); err != nil {
+		return err
+	}
+	if _, err := runGit(dir, "fetch", "--quiet", "origin"); err != nil {
+		return err
+	}
+	upstream := "origin/" + g.branch()
+	if _, err := runGit(dir, "rev-parse", "--verify", "--quiet", upstream); err != nil {
+		// Nothing was pushed yet: the first push creates the branch
+		return nil
+	}
+	head, err := runGit(dir, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil {
+		_, err := runGit(dir, "reset", "--quiet", "--hard", upstream)
+		return err
+	}
+	isAncestor := func(a, b string) bool {
+		_, err := runGit(dir, "merge-base", "--is-ancestor", a, b)
+		return err == nil
+	}
+	switch {
+	case isAncestor(upstream, head):
+		return nil
+	case isAncestor(head, upstream):
+		_, err := runGit(dir, "merge", "--quiet", "--ff-only", upstream)
+		return err
+	}
+	return g.mergeDiverged(dir, upstream)
+}
+
+// placesAt returns the places of the file as committed in rev, none if it
+// has no places file, and its entries with their times
+func (g *GitBackend) placesAt(dir, rev string) ([]Place, map[string]canonicalPlace, error) {
+	if _, err := runGit(dir, "cat-file", "-e", rev+":"+gitPlacesFile); err != nil {
+		return []Place{}, nil, nil
+	}
+	data, err := runGit(dir, "show", rev+":"+gitPlacesFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	plain, err := openPlaces(rev+":"+gitPlacesFile, []byte(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	places, err := parseCanonical(plain)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s in %s: %v", gitPlacesFile, rev, err)
+	}
+	return places, canonicalTimes(rev+":"+gitPlacesFile, plain), nil
+}
+
+// mergeDiverged merges the places committed upstream with those committed
+// here since the clone and the remote last agreed, and pushes the merge.
+// Where both changed the same place, the newest change wins.
+func (g *GitBackend) mergeDiverged(dir, upstream string) error {
+	base, err := runGit(dir, "merge-base", "HEAD", upstream)
+	if err != nil {
+		return err
+	}
+	baseline, _, err := g.placesAt(dir, base)
+	if err != nil {
+		return err
+	}
+	ours, ourEntries, err := g.placesAt(dir, "HEAD")
+	if err != nil {
+		return err
+	}
+	theirs, theirEntries, err := g.placesAt(dir, upstream)
+	if err != nil {
+		return err
+	}
+
+	caps := Capabilities{Labels: true, Remote: true}
+	edits := []placeEdits{
+		diffPlaces("local", caps, baseline, ours),
+		diffPlaces(g.Remote, caps, baseline, theirs),
+	}
+	edits[0].modified = modifiedTimes(ourEntries)
+	edits[1].modified = modifiedTimes(theirEntries)
+	merged, conflicts := threeWayMerge(baseline, edits)
+	for _, conflict := range conflicts {
+		log.Print(tr("Conflict: %s", conflict))
+	}
+
+	// Record the merge with both parents, then put the merged places in
+	// the tree it commits
+	if _, err := runGit(dir, "merge", "--quiet", "--no-commit", "-s", "ours", upstream); err != nil {
+		return err
+	}
+	if err := g.file(dir).Replace(merged); err != nil {
+		return err
+	}
+	if err := g.commit(dir, "bookmarksync: merge places from "+g.Remote); err != nil {
+		return err
+	}
+	// Push the merge right away: a sync that finds nothing else to write
+	// wouldn't, and the other machines should see it. If another one
+	// pushed in the meantime, the next pull merges again.
+	runGit(dir, "push", "--quiet", "origin", "HEAD:refs/heads/"+g.branch())
+	return nil
+}
+
+// commit commits the places file if it changed
+func (g *GitBackend) commit(dir, message string) error {
+	if _, err := runGit(dir, "add", "--", gitPlacesFile); err != nil {
+		return err
+	}
+	if _, err := runGit(dir, "diff", "--cached", "--quiet"); err == nil {
+		if _, err := runGit(dir, "rev-parse", "--verify", "--quiet", "MERGE_HEAD"); err != nil {
+			return nil
+		}
+	}
+	_, err := runGit(dir, "commit", "--quiet", "-m", message)
+	return err
+}
+
+// push pushes what was committed here. When another machine pushed first,
+// its places are merged in and the push is tried again.
+func (g *GitBackend) push(dir string) error {
+	if err := checkKeyring(g.overSSH()); err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		_, err := runGit(dir, "push", "--quiet", "origin", "HEAD:refs/heads/"+g.branch())
+		if err == nil || attempt == 3 {
+			return err
+		}
+		if g.pull(dir) != nil {
+			return err
+		}
+	}
+}
+
+func (g *GitBackend) GetPlaces() ([]Place, error) {
+	dir, err := g.clone()
+	if err != nil {
+		return nil, err
+	}
+	// Offline, the places last pulled are still the best there are
+	if err := g.pull(dir); err != nil {
+		log.Print(tr("Warning: failed to pull the places from %s: %v", g.Remote, err))
+	}
+	return g.file(dir).GetPlaces()
+}
+
+func (g *GitBackend) Merge(places []Place) error {
+	return mergeInto(g, places)
+}
+
+func (g *GitBackend) Replace(places []Place) error {
+	places, err := exportedPlaces(places)
+	if err != nil {
+		return err
+	}
+	dir, err := g.clone()
+	if err != nil {
+		return err
+	}
+	if err := g.pull(dir); err != nil {
+		log.Print(tr("Warning: failed to pull the places from %s: %v", g.Remote, err))
+	}
+	file := g.file(dir)
+	old, err := file.GetPlaces()
+	if err != nil {
+		old = nil
+	}
+	if err := file.Replace(places); err != nil {
+		return err
+	}
+	if err := g.commit(dir, canonicalCommitMessage(old, places)); err != nil {
+		return err
+	}
+	if err := g.push(dir); err != nil {
+		return fmt.Errorf("failed to push the places to %s: %w", g.Remote, err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// runGroup implements "bookmarksync group list|enable|disable". Disabling a
+// group removes its bookmarks from every backend while keeping them in the
+// state file, so enabling it puts them back.
+func runGroup(args []string) error {
+	if len(args) == 0 {
+		return usageError("bookmarksync-go group list|enable NAME|disable NAME")
+	}
+
+	state, err := LoadState()
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "list" {
+		var names []string
+		for name := range state.Groups {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			group := state.Groups[name]
+			status := tr("enabled")
+			if group.Disabled {
+				status = tr("disabled")
+			}
+			fmt.Print(tr("%s\t%s\t%d bookmarks\t%s\n", name, status, len(group.Places), group.Root))
+		}
+		return nil
+	}
+
+	if len(args) != 2 || (args[0] != "enable" && args[0] != "disable") {
+		return usageError("bookmarksync-go group list|enable NAME|disable NAME")
+	}
+	name := args[1]
+	group, exists := state.Groups[name]
+	if !exists {
+		return errors.New(tr("no group named %s", name))
+	}
+
+	disable := args[0] == "disable"
+	if group.Disabled == disable {
+		if disable {
+			fmt.Print(tr("Group %s is already disabled\n", name))
+		} else {
+			fmt.Print(tr("Group %s is already enabled\n", name))
+		}
+		return nil
+	}
+
+	err = NewBookmarkSync().UpdateAll(func(current []Place) []Place {
+		if disable {
+			return removeTargets(current, group.Places)
+		}
+		return appendMissingPlaces(current, group.Places)
+	})
+	if err != nil {
+		return err
+	}
+
+	// UpdateAll saved what it wrote; reload so it isn't overwritten
+	state, err = LoadState()
+	if err != nil {
+		return err
+	}
+	group.Disabled = disable
+	state.Groups[name] = group
+	if disable {
+		fmt.Print(tr("Group %s disabled (%d bookmarks)\n", name, len(group.Places)))
+	} else {
+		fmt.Print(tr("Group %s enabled (%d bookmarks)\n", name, len(group.Places)))
+	}
+	return state.Save()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Regression test: the state loaded before UpdateAll was saved over what
+// it recorded, so every backend looked changed since the last sync
+func TestGroupKeepsSyncState(t *testing.T) {
+	home := testHome(t)
+	dir := filepath.Join(home, "project")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", fileURI(dir)+" project\n")
+	state := &State{Groups: map[string]Group{
+		"project": {Root: dir, Places: []Place{{Label: "project", Target: fileURI(dir)}}},
+	}}
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runGroup([]string{"disable", "project"}); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Groups["project"].Disabled {
+		t.Error("group not disabled")
+	}
+	assertInSync(t, state)
+}
+
+// assertInSync fails the test unless state recorded a sync and every
+// backend as it is now
+func assertInSync(t *testing.T, state *State) {
+	t.Helper()
+	if state.Synced.IsZero() {
+		t.Error("sync time not recorded")
+	}
+	for _, backend := range NewBookmarkSync().Backends() {
+		fp, err := fingerprint(backend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if recorded := state.Fingerprints[backend.Name()]; recorded.Hash != fp.Hash {
+			t.Errorf("%s changed since the last sync", backend.Name())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// gtk2Libraries are where GTK 2 is found when it is installed, for
+// deciding whether to start a ~/.gtk-bookmarks file
+var gtk2Libraries = []string{
+	"/usr/lib/libgtk-x11-2.0.so.0",
+	"/usr/lib64/libgtk-x11-2.0.so.0",
+	"/usr/lib/*/libgtk-x11-2.0.so.0",
+}
+
+// GTK2Backend implements BookmarkSyncBackend for ~/.gtk-bookmarks, which
+// the file chooser of GTK 2 applications like GIMP 2.x reads instead of
+// the gtk-3.0 file. The format is the same.
+type GTK2Backend struct {
+	// Path overrides the default ~/.gtk-bookmarks location
+	Path string
+}
+
+func (g *GTK2Backend) Name() string {
+	return "gtk2"
+}
+
+// file returns the GTK backend of the bookmarks file
+func (g *GTK2Backend) file() (*GTKBackend, error) {
+	path := g.Path
+	if path == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(homeDir, ".gtk-bookmarks")
+	}
+	return &GTKBackend{BackendName: g.Name(), Path: path}, nil
+}
+
+// Installed reports whether the bookmarks file exists or GTK 2 is
+// installed; the backend isn't synced for users without it
+func (g *GTK2Backend) Installed() bool {
+	file, err := g.file()
+	if err != nil {
+		return false
+	}
+	if _, err := os.Stat(file.Path); err == nil {
+		return true
+	}
+	for _, pattern := range gtk2Libraries {
+		if matches, _ := filepath.Glob(pattern); len(matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *GTK2Backend) Files() ([]string, error) {
+	file, err := g.file()
+	if err != nil {
+		return nil, err
+	}
+	return file.Files()
+}
+
+func (g *GTK2Backend) GetPlaces() ([]Place, error) {
+	file, err := g.file()
+	if err != nil {
+		return nil, err
+	}
+	return file.GetPlaces()
+}
+
+func (g *GTK2Backend) Merge(places []Place) error {
+	return mergeInto(g, places)
+}
+
+func (g *GTK2Backend) Replace(places []Place) error {
+	file, err := g.file()
+	if err != nil {
+		return err
+	}
+	return file.Replace(places)
+}
+
+func (g *GTK2Backend) Render(places []Place) ([]byte, error) {
+	file, err := g.file()
+	if err != nil {
+		return nil, err
+	}
+	return file.Render(places)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// testWithoutGTK2 makes GTK 2 look uninstalled for the test
+func testWithoutGTK2(t *testing.T) {
+	saved := gtk2Libraries
+	gtk2Libraries = []string{filepath.Join(t.TempDir(), "libgtk-x11-2.0.so.0")}
+	t.Cleanup(func() { gtk2Libraries = saved })
+}
+
+// Regression test: without GTK 2, dry runs showed gtk2 changing and syncs
+// recorded it as synced, though Replace left it alone
+func TestGTK2WithoutGTK2(t *testing.T) {
+	home := testHome(t)
+	testWithoutGTK2(t)
+	if _, ok := NewBookmarkSync().backends["gtk2"]; ok {
+		t.Error("gtk2 registered without GTK 2")
+	}
+
+	writeFile(t, home, ".gtk-bookmarks", "file:///a a\n")
+	if _, ok := NewBookmarkSync().backends["gtk2"]; !ok {
+		t.Error("gtk2 not registered with a bookmarks file")
+	}
+}
+
+func TestGTK2Path(t *testing.T) {
+	home := testHome(t)
+	testWithoutGTK2(t)
+	path := writeFile(t, home, "dotfiles/gtk-bookmarks", "file:///a a\n")
+	config.Paths = map[string]string{"gtk2": path}
+
+	backend, ok := NewBookmarkSync().backends["gtk2"]
+	if !ok {
+		t.Fatal("gtk2 not registered with a configured file")
+	}
+	if err := backend.Replace(places("b", "file:///b")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := (&GTKBackend{Path: path}).GetPlaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Target != "file:///b" {
+		t.Errorf("%s holds %v", path, got)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gtkAppFlag collects --gtk-app NAME=PATH values. Each one becomes an extra
+// GTK-format backend named gtk:NAME, for applications that keep their own
+// bookmarks list instead of the shared gtk-3.0 one.
+type gtkAppFlag []*GTKBackend
+
+func (f *gtkAppFlag) String() string {
+	var apps []string
+	for _, app := range *f {
+		apps = append(apps, app.Name()+"="+app.Path)
+	}
+	return strings.Join(apps, ",")
+}
+
+func (f *gtkAppFlag) Set(value string) error {
+	name, path, ok := strings.Cut(value, "=")
+	name = strings.ToLower(strings.TrimSpace(name))
+	path = strings.TrimSpace(path)
+	if !ok || name == "" || path == "" {
+		return fmt.Errorf("expected NAME=PATH, got %q", value)
+	}
+
+	path, err := expandHome(path)
+	if err != nil {
+		return err
+	}
+
+	*f = append(*f, &GTKBackend{BackendName: "gtk:" + name, Path: path})
+	return nil
+}
+
+// expandHome replaces a leading ~/ with the user's home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, strings.TrimPrefix(path, "~")), nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gtkLine is one parsed line of a GTK bookmarks file
+type gtkLine struct {
+	Number int
+	Raw    string
+	Place  Place
+	// Problem describes what is wrong with the line, and Usable whether
+	// Place holds the line as read or repaired
+	Problem string
+	Usable  bool
+}
+
+// parseGTKLine parses "TARGET LABEL". GTK percent-encodes targets, but
+// hand-edited files often contain file:// targets or bare paths with raw
+// spaces, where the first space does not end the target. For those the
+// longest prefix naming an existing folder is taken as the target.
+func parseGTKLine(line string) (Place, string, bool) {
+	problem := ""
+	bare := strings.HasPrefix(line, "/")
+	if bare {
+		problem = "bare path instead of a file:// URI"
+	}
+
+	target, label, _ := strings.Cut(line, " ")
+	if bare || strings.HasPrefix(line, "file://") {
+		if t, l, ok := longestExistingTarget(line, bare); ok && t != target {
+			target, label = t, l
+			if problem == "" {
+				problem = "unencoded space in target"
+			}
+		}
+	}
+	if bare {
+		target = fileURI(target)
+	} else if strings.Contains(target, " ") {
+		target = strings.ReplaceAll(target, " ", "%20")
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" {
+		return Place{}, "target is not a valid URI", false
+	}
+	if label == "" {
+		label = filepath.Base(u.Path)
+	}
+	return Place{Label: strings.TrimSpace(label), Target: target}, problem, true
+}
+
+// longestExistingTarget tries every space in line as the end of the target
+// and returns the longest split whose target is an existing folder
+func longestExistingTarget(line string, bare bool) (string, string, bool) {
+	for end := len(line); end > 0; end = strings.LastIndex(line[:end], " ") {
+		candidate := line[:end]
+		path := candidate
+		if !bare {
+			u, err := url.Parse(strings.ReplaceAll(candidate, " ", "%20"))
+			if err != nil {
+				continue
+			}
+			path = u.Path
+		}
+		if isDir(path) {
+			return candidate, strings.TrimSpace(line[end:]), true
+		}
+	}
+	return "", "", false
+}
+
+// formatGTKLine renders a place as a GTK bookmarks line, encoding the
+// spaces that would otherwise end the target early
+func formatGTKLine(place Place) string {
+	target := strings.ReplaceAll(place.Target, " ", "%20")
+	if place.Label != "" {
+		return target + " " + place.Label
+	}
+	return target
+}
+
+// readGTKLines parses every non-empty line of a GTK bookmarks file
+func readGTKLines(path string) ([]gtkLine, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []gtkLine
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+		place, problem, ok := parseGTKLine(raw)
+		lines = append(lines, gtkLine{Number: number, Raw: raw, Place: place, Problem: problem, Usable: ok})
+	}
+	return lines, scanner.Err()
+}
+
+// runAuditGTK implements "bookmarksync audit-gtk [--fix] [FILE...]", which
+// reports lines of GTK bookmarks files that don't round-trip, and with
+// --fix rewrites the files with the repaired lines
+func runAuditGTK(args []string) error {
+	fs := flag.NewFlagSet("audit-gtk", flag.ExitOnError)
+	fix := fs.Bool("fix", false, "Rewrite the files with malformed lines repaired")
+	if config.Paths == nil {
+		config.Paths = map[string]string{}
+	}
+	fs.Var(backendPathFlag(config.Paths), "backend-path", optionHelp("backend-path"))
+	fs.Parse(args)
+	if err := checkLockedFlags(fs); err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		path, err := (&GTKBackend{Path: config.Paths["gtk"]}).bookmarksPath()
+		if err != nil {
+			return err
+		}
+		files = []string{path}
+	}
+
+	problems := 0
+	for _, path := range files {
+		lines, err := readGTKLines(path)
+		if err != nil {
+			return err
+		}
+
+		var fixed []string
+		changed := false
+		for _, line := range lines {
+			if line.Problem == "" && line.Usable {
+				fixed = append(fixed, line.Raw)
+				continue
+			}
+			problems++
+			if !line.Usable {
+				fmt.Print(tr("%s:%d: %s, dropping: %s\n", path, line.Number, line.Problem, line.Raw))
+				changed = true
+				continue
+			}
+			repaired := formatGTKLine(line.Place)
+			fmt.Print(tr("%s:%d: %s\n  %s\n  -> %s\n", path, line.Number, line.Problem, line.Raw, repaired))
+			fixed = append(fixed, repaired)
+			changed = changed || repaired != line.Raw
+		}
+
+		if *fix && changed {
+			content := strings.Join(fixed, "\n") + "\n"
+			if err := replaceFile(path, []byte(content)); err != nil {
+				return err
+			}
+			fmt.Print(tr("Repaired %s\n", path))
+		}
+	}
+
+	if problems > 0 && !*fix {
+		return fmt.Errorf("%d malformed lines, run with --fix to repair them", problems)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseGTKLine(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "My Files")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line    string
+		want    Place
+		problem bool
+		usable  bool
+	}{
+		{"file:///tmp Temp", Place{Label: "Temp", Target: "file:///tmp"}, false, true},
+		{"file:///tmp", Place{Label: "tmp", Target: "file:///tmp"}, false, true},
+		{"sftp://host/srv Server", Place{Label: "Server", Target: "sftp://host/srv"}, false, true},
+		{dir + " Mine", Place{Label: "Mine", Target: fileURI(dir)}, true, true},
+		{"file://" + dir + " Mine", Place{Label: "Mine", Target: "file://" + filepath.Dir(dir) + "/My%20Files"}, true, true},
+		{"no-scheme Label", Place{}, true, false},
+	}
+	for _, test := range tests {
+		place, problem, usable := parseGTKLine(test.line)
+		if place != test.want || (problem != "") != test.problem || usable != test.usable {
+			t.Errorf("parseGTKLine(%q) = %v, %q, %v, want %v, problem %v, usable %v",
+				test.line, place, problem, usable, test.want, test.problem, test.usable)
+		}
+	}
+}
+
+// Regression test: --fix replaced a symlinked bookmarks file with a copy
+func TestAuditGTKFixKeepsSymlink(t *testing.T) {
+	home := testHome(t)
+	real := writeFile(t, home, "dotfiles/bookmarks", home+" Home\n")
+	link := filepath.Join(home, "gtk-bookmarks")
+	if err := os.Symlink(real, link); err != nil {
+		t.Fatal(err)
+	}
+	config.Paths = map[string]string{"gtk": link}
+
+	if err := runAuditGTK([]string{"--fix"}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("%s is no longer a symlink", link)
+	}
+	data, err := os.ReadFile(real)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fileURI(home) + " Home\n"; string(data) != want {
+		t.Errorf("repaired to %q, want %q", data, want)
+	}
+}
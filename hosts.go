@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// runHosts implements "bookmarksync hosts [--all] PATH|LABEL [HOST...]",
+// which shows or sets the hosts a place of the canonical places file is
+// for. Other machines keep it in their copies of the file, but don't write
+// it to their backends.
+func runHosts(args []string) error {
+	fs := flag.NewFlagSet("hosts", flag.ExitOnError)
+	all := fs.Bool("all", false, "Make the place for every host again")
+	fs.Parse(args)
+	if fs.NArg() < 1 || (*all && fs.NArg() != 1) {
+		return usageError("bookmarksync-go hosts [--all] PATH|LABEL [HOST...]")
+	}
+	hosts := fs.Args()[1:]
+	for _, host := range hosts {
+		if _, err := path.Match(host, ""); err != nil || host == "" {
+			return fmt.Errorf("invalid host %q", host)
+		}
+	}
+
+	backend, places, entry, err := canonicalEntry("hosts", fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	place := Place{Label: entry.Label, Target: entry.Target}
+
+	if len(hosts) == 0 && !*all {
+		if len(entry.Hosts) == 0 {
+			fmt.Print(tr("%s is for every host\n", place.Label))
+		} else {
+			fmt.Print(tr("%s is for %s\n", place.Label, strings.Join(entry.Hosts, ", ")))
+		}
+		return nil
+	}
+
+	entry.Hosts = hosts
+	if err := updateEntry(backend, places, entry); err != nil {
+		return err
+	}
+	if len(hosts) == 0 {
+		fmt.Print(tr("%s is for every host now\n", place.Label))
+	} else {
+		fmt.Print(tr("%s is only for %s now\n", place.Label, strings.Join(hosts, ", ")))
+	}
+	return nil
+}
+
+// canonicalEntry returns the canonical backend, the places of its file,
+// those for other hosts too, and the entry of the one arg names, for
+// command
+func canonicalEntry(command, arg string) (BookmarkSyncBackend, []Place, canonicalPlace, error) {
+	backend, ok := NewBookmarkSync().backends["canonical"]
+	if !ok {
+		return nil, nil, canonicalPlace{}, errors.New(tr("%s needs the canonical backend: set canonical = true in the configuration", command))
+	}
+	files, err := backend.Files()
+	if err != nil {
+		return nil, nil, canonicalPlace{}, err
+	}
+	// Every entry, those for other hosts too
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		return nil, nil, canonicalPlace{}, err
+	}
+	if data, err = openPlaces(files[0], data); err != nil {
+		return nil, nil, canonicalPlace{}, err
+	}
+	entries, err := decodeCanonical(data)
+	if err != nil {
+		return nil, nil, canonicalPlace{}, &corruptError{File: files[0], Err: err}
+	}
+	places := make([]Place, len(entries))
+	for i, entry := range entries {
+		places[i] = Place{Label: entry.Label, Target: entry.Target}
+	}
+	place, err := resolvePlace(places, arg)
+	if err != nil {
+		return nil, nil, canonicalPlace{}, err
+	}
+	var entry canonicalPlace
+	for _, e := range entries {
+		if normalizeTarget(e.Target) == normalizeTarget(place.Target) {
+			entry = e
+		}
+	}
+	return backend, places, entry, nil
+}
+
+// updateEntry writes the canonical places file with entry, one of places
+// edited by a command, as modified now
+func updateEntry(backend BookmarkSyncBackend, places []Place, entry canonicalPlace) error {
+	// Scoping and locking are edits like relabelling, which the newest
+	// copy wins. Times are in seconds: one changed within the second it
+	// was written still has to be newer.
+	now := time.Now().UTC().Truncate(time.Second)
+	if !now.After(entry.Modified) {
+		now = entry.Modified.Add(time.Second)
+	}
+	entry.Modified = now
+	noteEntries([]canonicalPlace{entry})
+	return backend.Replace(places)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testHostname makes name the test's host, with no canonical entries seen
+// yet
+func testHostname(t *testing.T, name string) {
+	t.Helper()
+	saved := hostname
+	hostname = func() string { return name }
+	seenEntries.Lock()
+	seenEntries.entries = make(map[string]canonicalPlace)
+	seenEntries.Unlock()
+	t.Cleanup(func() { hostname = saved })
+}
+
+// canonicalEntries returns the entries of the canonical places file at path
+func canonicalEntries(t *testing.T, path string) map[string][]string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := decodeCanonical(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := make(map[string][]string)
+	for _, entry := range entries {
+		hosts[entry.Label] = entry.Hosts
+	}
+	return hosts
+}
+
+func TestOnThisHost(t *testing.T) {
+	testHostname(t, "laptop.example.com")
+	tests := []struct {
+		hosts []string
+		want  bool
+	}{
+		{nil, true},
+		{[]string{"laptop"}, true},
+		{[]string{"LAPTOP.example.com"}, true},
+		{[]string{"desktop", "lap*"}, true},
+		{[]string{"*.example.com"}, true},
+		{[]string{"desktop"}, false},
+		{[]string{"laptop.example.org"}, false},
+	}
+	for _, test := range tests {
+		if got := onThisHost(test.hosts); got != test.want {
+			t.Errorf("onThisHost(%v) = %v, want %v", test.hosts, got, test.want)
+		}
+	}
+}
+
+func TestCanonicalPlacesOfOtherHosts(t *testing.T) {
+	home := testHome(t)
+	testHostname(t, "laptop")
+	path := writeFile(t, home, "places.toml", `[[place]]
+label = "a"
+target = "file:///a"
+
+[[place]]
+label = "work"
+target = "file:///work"
+hosts = ["desktop"]
+`)
+	canonical := &CanonicalBackend{Path: path}
+	got, err := canonical.GetPlaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := places("a", "file:///a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("laptop sees %v, want %v", got, want)
+	}
+
+	// Writing the laptop's places keeps the desktop's in the file
+	if err := canonical.Replace(places("a", "file:///a", "b", "file:///b")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := canonicalEntries(t, path), map[string][]string{"a": nil, "b": nil, "work": {"desktop"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("file holds %v, want %v", got, want)
+	}
+
+	testHostname(t, "desktop")
+	got, err = canonical.GetPlaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, place := range got {
+		labels = append(labels, place.Label)
+	}
+	if strings.Join(labels, " ") != "a work b" {
+		t.Errorf("desktop sees %v, want a, work and b", labels)
+	}
+}
+
+func TestRunHosts(t *testing.T) {
+	home := testHome(t)
+	testHostname(t, "laptop")
+	testStdin(t, "")
+	config.Canonical = true
+	path := writeFile(t, home, ".config/bookmarksync/places.toml", "[[place]]\nlabel = \"work\"\ntarget = \"file:///work\"\n")
+
+	if err := runHosts([]string{"work", "desktop", "*.office"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := canonicalEntries(t, path)["work"], []string{"desktop", "*.office"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("work is for %v, want %v", got, want)
+	}
+	if err := runHosts([]string{"--all", "work"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := canonicalEntries(t, path)["work"]; got != nil {
+		t.Errorf("work is for %v, want every host", got)
+	}
+
+	if err := runHosts([]string{"work", "[desktop"}); err == nil || !strings.Contains(err.Error(), "invalid host") {
+		t.Errorf("with a broken glob: error %v", err)
+	}
+	config.Canonical = false
+	if err := runHosts([]string{"work"}); err == nil || !strings.Contains(err.Error(), "needs the canonical backend") {
+		t.Errorf("without the canonical backend: error %v", err)
+	}
+}
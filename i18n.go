@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// User-facing messages are looked up in golang.org/x/text's default catalog
+// by their English format string. A translation is a file named
+// i18n_<language>.go whose init function registers every message with
+// message.SetString; English needs no catalog. Messages without a
+// translation fall back to English.
+
+var (
+	printer     *message.Printer
+	printerOnce sync.Once
+)
+
+// localePrinter returns the printer for the user's message locale, chosen
+// on first use so every translation has been registered by then
+func localePrinter() *message.Printer {
+	printerOnce.Do(func() {
+		printer = message.NewPrinter(userLanguage())
+	})
+	return printer
+}
+
+// userLanguage picks the best available translation for the locale in
+// $LC_ALL, $LC_MESSAGES or $LANG, in that order of precedence
+func userLanguage() language.Tag {
+	var locale string
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale = os.Getenv(name); locale != "" {
+			break
+		}
+	}
+
+	// de_DE.UTF-8@euro -> de-DE
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return language.English
+	}
+	tag, err := language.Parse(strings.ReplaceAll(locale, "_", "-"))
+	if err != nil {
+		return language.English
+	}
+
+	supported := append([]language.Tag{language.English}, message.DefaultCatalog.Languages()...)
+	_, index, confidence := language.NewMatcher(supported).Match(tag)
+	if confidence == language.No {
+		return language.English
+	}
+	return supported[index]
+}
+
+// tr formats a user-facing message in the user's language
+func tr(format string, args ...interface{}) string {
+	return localePrinter().Sprintf(format, args...)
+}
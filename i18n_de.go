@@ -0,0 +1,252 @@
+package main
+
+import (
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// German messages
+func init() {
+	for key, msg := range map[string]string{
+		"BookmarkSync - A utility to sync bookmarks between GTK+, KDE, and Qt file dialogs": "BookmarkSync - Gleicht Lesezeichen zwischen den Dateidialogen von GTK+, KDE und Qt ab",
+		"Version: %s\n\n": "Version: %s\n\n",
+		"Usage:":          "Aufruf:",
+		"\nCommands:":     "\nBefehle:",
+		"\nSync options:": "\nAbgleichsoptionen:",
+		"Sync from a particular backend (canonical, gtk, gtk2, kde, qt, libreoffice, blender, git, remote, webdav, gtk:NAME)":       "Von einem bestimmten Backend abgleichen (canonical, gtk, gtk2, kde, qt, libreoffice, blender, git, remote, webdav, gtk:NAME)",
+		"Merge into destinations instead of replacing them":                                                                         "In die Ziele einfügen, statt sie zu ersetzen",
+		"Refuse to overwrite backends modified since the last sync":                                                                 "Seit dem letzten Abgleich geänderte Backends nicht überschreiben",
+		"Show what would change in each backend without writing":                                                                    "Änderungen je Backend anzeigen, ohne zu schreiben",
+		"Sync from the most recently modified backend (default)":                                                                    "Vom zuletzt geänderten Backend abgleichen (Standard)",
+		"Propagate changes made in any backend since the last run":                                                                  "Änderungen aus allen Backends seit dem letzten Lauf übernehmen",
+		"Keep running and sync whenever a backend's bookmarks change":                                                               "Weiterlaufen und bei jeder Änderung eines Backends abgleichen",
+		"Wait for writes to settle before syncing (default 500ms)":                                                                  "Vor dem Abgleich warten, bis Schreibvorgänge enden (Standard 500ms)",
+		"Add detected cloud drive folders (macOS, Windows)":                                                                         "Erkannte Cloud-Ordner hinzufügen (macOS, Windows)",
+		"Also sync an app-specific GTK bookmarks file (repeatable)":                                                                 "Auch die GTK-Lesezeichendatei einer Anwendung abgleichen (mehrfach)",
+		"Write places as shortcuts into a Wine/Proton prefix (repeatable)":                                                          "Orte als Verknüpfungen in ein Wine/Proton-Prefix schreiben (mehrfach)",
+		"Also sync AppImages with a portable home/config directory":                                                                 "Auch AppImages mit portablem Home- oder Konfigurationsordner abgleichen",
+		"Directory to scan for AppImages (repeatable)":                                                                              "Ordner, der nach AppImages durchsucht wird (mehrfach)",
+		"Add sftp:// places for ~/.ssh/config hosts (* for all)":                                                                    "sftp://-Orte für Hosts aus ~/.ssh/config hinzufügen (* für alle)",
+		"Print the result of every sync as a line of JSON (also accepted before any command)":                                       "Das Ergebnis jedes Abgleichs als JSON-Zeile ausgeben (auch vor einem Befehl möglich)",
+		"Read and write the gtk, gtk2, kde, qt or canonical backend at FILE (repeatable)":                                           "Das Backend gtk, gtk2, kde, qt oder canonical in DATEI lesen und schreiben (mehrfach)",
+		"Only write these backends (repeatable)":                                                                                    "Nur in diese Backends schreiben (mehrfach)",
+		"--sync-to cannot be combined with --two-way":                                                                               "--sync-to kann nicht mit --two-way kombiniert werden",
+		"Also write a Bookmarks menu of scripts for Nautilus and Nemo":                                                              "Auch ein Lesezeichen-Menü aus Skripten für Nautilus und Nemo schreiben",
+		"Failed to find file managers: %v":                                                                                          "Dateimanager konnten nicht gesucht werden: %v",
+		"Also write a .desktop launcher for every bookmark (see gen-launchers)":                                                     "Auch einen .desktop-Starter für jedes Lesezeichen schreiben (siehe gen-launchers)",
+		"Write a .desktop launcher for every bookmark and remove those of deleted ones":                                             "Einen .desktop-Starter für jedes Lesezeichen schreiben und die gelöschter entfernen",
+		"Wrote %d launchers to %s\n":                                                                                                "%d Starter in %s geschrieben\n",
+		"Warning: can't resolve documents portal path %s: %v":                                                                       "Warnung: Pfad %s des Dokumentenportals kann nicht aufgelöst werden: %v",
+		"Never copy places whose label, target or folder matches PATTERN, a glob or re:REGEXP (repeatable)":                         "Orte, deren Name, Ziel oder Ordner auf MUSTER passt, nie kopieren; MUSTER ist ein Glob oder re:REGEXP (mehrfach)",
+		"Only copy places matching PATTERN (repeatable)":                                                                            "Nur Orte kopieren, die auf MUSTER passen (mehrfach)",
+		"Keep a bookmark in a backend even when syncs from others don't have it":                                                    "Ein Lesezeichen in einem Backend behalten, auch wenn Synchronisierungen von anderen es nicht haben",
+		"Stop keeping a pinned bookmark":                                                                                            "Ein angeheftetes Lesezeichen nicht mehr behalten",
+		"%s is already pinned in %s\n":                                                                                              "%s ist in %s bereits angeheftet\n",
+		"Pinned %s (%s) in %s\n":                                                                                                    "%s (%s) in %s angeheftet\n",
+		"Unpinned %s (%s) in %s\n":                                                                                                  "%s (%s) in %s nicht mehr angeheftet\n",
+		"Copy places with control characters or javascript:/data: targets instead of ignoring them":                                 "Orte mit Steuerzeichen oder javascript:/data:-Zielen kopieren, statt sie zu ignorieren",
+		"it contains control characters":                                                                                            "es enthält Steuerzeichen",
+		"%s: targets aren't places":                                                                                                 "%s:-Ziele sind keine Orte",
+		"Warning: ignoring %q from %s: %s (use --allow-unsafe to copy it anyway)":                                                   "Warnung: %q aus %s wird ignoriert: %s (mit --allow-unsafe trotzdem kopieren)",
+		"Print the file a backend would be written with, without writing it":                                                        "Die Datei ausgeben, mit der ein Backend geschrieben würde, ohne sie zu schreiben",
+		"Write places in this order: preserve-source (default), alphabetical-by-label, alphabetical-by-path or custom":              "Orte in dieser Reihenfolge schreiben: preserve-source (Standard), alphabetical-by-label, alphabetical-by-path oder custom",
+		"Drop places whose folder no longer exists before writing":                                                                  "Orte, deren Ordner nicht mehr existiert, vor dem Schreiben verwerfen",
+		"Remove the bookmarks of folders that no longer exist from every backend":                                                   "Lesezeichen nicht mehr existierender Ordner aus allen Backends entfernen",
+		"Dropping %s (%s): the folder no longer exists\n":                                                                           "%s (%s) wird verworfen: der Ordner existiert nicht mehr\n",
+		"Every bookmarked folder exists\n":                                                                                          "Alle Ordner mit Lesezeichen existieren\n",
+		"How a backend stores what it can't: links gives Qt labels through symlinks named after them, drop loses them (repeatable)": "Wie ein Backend speichert, was es nicht kann: links gibt Qt Beschriftungen über danach benannte Symlinks, drop verwirft sie (wiederholbar)",
+		"Report local bookmarks whose folder is missing, without changing anything":                                                 "Lokale Lesezeichen melden, deren Ordner fehlt, ohne etwas zu ändern",
+		"the folder doesn't exist":                                                                                                  "der Ordner existiert nicht",
+		"not a folder":                                                                                                              "kein Ordner",
+		"%d bookmarks are unreachable":                                                                                              "%d Lesezeichen sind nicht erreichbar",
+		"%s can't be written to (read-only filesystem or no permission)":                                                            "%s ist nicht beschreibbar (schreibgeschütztes Dateisystem oder keine Berechtigung)",
+		"Nothing was written as %v. A sync would change:\n":                                                                         "Nichts wurde geschrieben: %v. Ein Abgleich würde ändern:\n",
+		"Generated %d fixture sets in %s\n":                                                                                         "%d Testdatensätze in %s erzeugt\n",
+		"nothing to undo: no backups are kept (backups = 0)":                                                                        "nichts rückgängig zu machen: es werden keine Sicherungen aufbewahrt (backups = 0)",
+		"nothing to undo: no sync has been recorded yet":                                                                            "nichts rückgängig zu machen: es wurde noch kein Abgleich aufgezeichnet",
+		"nothing to undo: the last sync didn't write %s":                                                                            "nichts rückgängig zu machen: der letzte Abgleich hat %s nicht geschrieben",
+		"can't undo %s: its backup %s is gone":                                                                                      "%s kann nicht rückgängig gemacht werden: die Sicherung %s fehlt",
+		"Restored %s as it was at %s\n":                                                                                             "%s auf den Stand von %s zurückgesetzt\n",
+		"Removed the files of %s, which it didn't have before\n":                                                                    "Dateien von %s entfernt, die es vorher nicht gab\n",
+		"no snapshot named %s":                                                                                                      "kein Schnappschuss namens %s",
+		"snapshot %s already exists (use --force to overwrite it)":                                                                  "Schnappschuss %s existiert bereits (mit --force überschreiben)",
+		"Saved snapshot %s (%d backends, %d places)\n":                                                                              "Schnappschuss %s gespeichert (%d Backends, %d Orte)\n",
+		"Deleted snapshot %s\n":                                                                                                     "Schnappschuss %s gelöscht\n",
+		"snapshot %s has no places of %s":                                                                                           "Schnappschuss %s enthält keine Orte von %s",
+		"Warning: skipping %s, which isn't a backend here":                                                                          "Warnung: %s wird übersprungen, es ist hier kein Backend",
+		"Restored snapshot %s from %s\n":                                                                                            "Schnappschuss %s vom %s wiederhergestellt\n",
+		"Warning: %v":                                                                                                               "Warnung: %v",
+		"\nDaemon: running since %s (%s), %d syncs":                                                                                 "\nDienst: läuft seit %s (%s), %d Abgleiche",
+		"  Last sync: %s":       "  Letzter Abgleich: %s",
+		"  %s last written: %s": "  %s zuletzt geschrieben: %s",
+		"  Last error (%s): %s": "  Letzter Fehler (%s): %s",
+		"Sync at the latest this long after the first write, even while writes keep coming": "Spätestens so lange nach dem ersten Schreiben abgleichen, auch wenn weiter geschrieben wird",
+		"%d of %d backends failed:":                                     "%d von %d Backends fehlgeschlagen:",
+		"Creating the canonical places file from gtk\n":                 "Die kanonische Orte-Datei wird aus gtk erstellt\n",
+		"Warning: failed to commit %s: %v":                              "Warnung: %s konnte nicht committet werden: %v",
+		"%s differ from %s":                                             "%s weichen von %s ab",
+		"Every backend matches %s\n":                                    "Alle Backends stimmen mit %s überein\n",
+		"Restored from backup %s":                                       "Aus Sicherung %s wiederhergestellt",
+		"Warning: %v; kept a copy in %s and restored %s from backup %s": "Warnung: %v; Kopie in %s aufbewahrt und %s aus Sicherung %s wiederhergestellt",
+		"Rewritten with the places it had after the last sync":          "Mit den Orten nach dem letzten Abgleich neu geschrieben",
+		"Warning: %v; kept a copy in %s and rewrote %s with the places it had after the last sync": "Warnung: %v; Kopie in %s aufbewahrt und %s mit den Orten nach dem letzten Abgleich neu geschrieben",
+		"Not recovered: no backup parses and no sync recorded its places":                          "Nicht wiederhergestellt: keine Sicherung ist lesbar und kein Abgleich hat die Orte festgehalten",
+		"%s: ok\n":               "%s: in Ordnung\n",
+		"\nQuarantined files:\n": "\nIn Quarantäne verschobene Dateien:\n",
+		"%s can't be read":       "%s kann nicht gelesen werden",
+		"Warning: failed to pull the places from %s: %v":                                                "Warnung: Orte konnten nicht von %s geholt werden: %v",
+		"Not recovered: no backup parses, and the places of the last sync can't be written without one": "Nicht wiederhergestellt: keine Sicherung ist lesbar, und die Orte des letzten Abgleichs können ohne sie nicht geschrieben werden",
+		"\nAliases:": "\nAliase:",
+		"Warning: failed to read the places from %s, using those read last: %v": "Warnung: Orte konnten nicht von %s gelesen werden, die zuletzt gelesenen werden verwendet: %v",
+		"Show each backend's file, place count and whether it changed since the last sync, and what a running daemon did; --prompt prints one character for shell prompts, --waybar a Waybar module": "Datei, Anzahl der Orte und Änderungen seit dem letzten Abgleich je Backend sowie die Arbeit eines laufenden Daemons anzeigen; --prompt gibt ein Zeichen für Shell-Prompts aus, --waybar ein Waybar-Modul",
+		"Warning: skipping %s: %v":            "Warnung: %s wird übersprungen: %v",
+		"Merged the sync conflict %s into %s": "Synchronisationskonflikt %s in %s zusammengeführt",
+		"Last sync failed: %s":                "Letzter Abgleich fehlgeschlagen: %s",
+		"error":                               "Fehler",
+		"Dropping %s (%s): unchanged for longer than %s\n":                          "%s (%s) wird verworfen: seit mehr als %s unverändert\n",
+		"Show or set the hosts a place of the canonical places file is written on":  "Die Rechner anzeigen oder setzen, auf denen ein Ort der kanonischen Orte-Datei geschrieben wird",
+		"%s needs the canonical backend: set canonical = true in the configuration": "%s braucht das canonical-Backend: canonical = true in der Konfiguration setzen",
+		"%s is for every host\n":                        "%s gilt für jeden Rechner\n",
+		"%s is for %s\n":                                "%s gilt für %s\n",
+		"%s is for every host now\n":                    "%s gilt jetzt für jeden Rechner\n",
+		"%s is only for %s now\n":                       "%s gilt jetzt nur für %s\n",
+		"Let the sync remove and relabel locked places": "Die Synchronisierung gesperrte Orte entfernen und umbenennen lassen",
+		"Keep a place of the canonical places file from being removed or relabelled without --force": "Einen Ort der kanonischen Orte-Datei davor schützen, ohne --force entfernt oder umbenannt zu werden",
+		"Let syncs and commands remove and relabel a locked place again":                             "Synchronisierungen und Befehle einen gesperrten Ort wieder entfernen und umbenennen lassen",
+		"%s is already locked\n": "%s ist bereits gesperrt\n",
+		"%s isn't locked\n":      "%s ist nicht gesperrt\n",
+		"Locked %s (%s)\n":       "%s (%s) gesperrt\n",
+		"Unlocked %s (%s)\n":     "%s (%s) entsperrt\n",
+		"%s is locked: remove it with --force, or unlock it first": "%s ist gesperrt: mit --force entfernen oder zuerst entsperren",
+		"%s is locked: rename it with --force, or unlock it first": "%s ist gesperrt: mit --force umbenennen oder zuerst entsperren",
+		"%s is locked: edit with --force to remove or relabel it":  "%s ist gesperrt: zum Entfernen oder Umbenennen mit --force bearbeiten",
+		"Warning: ignoring %s in the configuration: %s sets it":    "Warnung: %s in der Konfiguration wird ignoriert: %s legt es fest",
+		"%s is required by %s":                                                                                                            "%s wird von %s vorgeschrieben",
+		"%s: places are forbidden by %s":                                                                                                  "%s: Orte sind durch %s verboten",
+		"Warning: ignoring %q from %s: %s: places are forbidden by %s":                                                                    "Warnung: %q aus %s wird ignoriert: %s: Orte sind durch %s verboten",
+		"Sync bookmarks between backends (the default command)":                                                                           "Lesezeichen zwischen Backends abgleichen (der Standardbefehl)",
+		"Write sample backend files from fixed sets of places, for testing backends":                                                      "Beispieldateien der Backends aus festen Sätzen von Orten schreiben, zum Testen von Backends",
+		"Put the backends' files back as they were before the last sync":                                                                  "Die Dateien der Backends auf den Stand vor dem letzten Abgleich zurücksetzen",
+		"Save every backend's places under a name and put them back later":                                                                "Die Orte aller Backends unter einem Namen speichern und später wiederherstellen",
+		"Waiting for another bookmarksync to finish":                                                                                      "Warten, bis ein anderes bookmarksync fertig ist",
+		"Print a backend's places as JSON, CSV, XBEL or Netscape bookmark HTML":                                                           "Die Orte eines Backends als JSON, CSV, XBEL oder Netscape-Lesezeichen-HTML ausgeben",
+		"Fail with status 3 when any backend differs from the source, without writing anything":                                           "Mit Status 3 fehlschlagen, wenn ein Backend von der Quelle abweicht, ohne etwas zu schreiben",
+		"Check that every backend can be read and show the corrupt files that were quarantined":                                           "Prüfen, ob sich jedes Backend lesen lässt, und die beschädigten Dateien in Quarantäne anzeigen",
+		"How two-way syncs settle conflicting edits: auto keeps the newest, or the first backend's (default); edit opens them in $EDITOR": "Wie Zwei-Wege-Abgleiche widersprüchliche Änderungen auflösen: auto behält die neueste oder die des ersten Backends (Standard); edit öffnet sie in $EDITOR",
+		"unknown conflict mode %s, expected auto or edit":                                                                                 "Unbekannter Konfliktmodus %s, erwartet auto oder edit",
+		"--conflict edit needs --two-way, without --watch":                                                                                "--conflict edit erfordert --two-way, ohne --watch",
+		"conflicts not resolved; nothing was written":                                                                                     "Konflikte nicht aufgelöst; nichts wurde geschrieben",
+		"Leaving %s until the keyring is unlocked\n":                                                                                      "%s bleibt, bis der Schlüsselbund entsperrt ist\n",
+		"Waiting for the keyring to be unlocked to sync %s\n":                                                                             "Warte auf das Entsperren des Schlüsselbunds, um %s abzugleichen\n",
+		"the keyring stayed locked: %s not synced":                                                                                        "Der Schlüsselbund blieb gesperrt: %s nicht abgeglichen",
+		"Warning: failed to push %s: %v":                                                                                                  "Warnung: %s konnte nicht gepusht werden: %v",
+		"--%s can't be used: %s locks %s":                                                                                                 "--%s ist nicht möglich: %s sperrt %s",
+		"usage: %s":                                                                                                                       "Aufruf: %s",
+		"enabled":                                                                                                                         "aktiviert",
+		"disabled":                                                                                                                        "deaktiviert",
+		"%s\t%s\t%d bookmarks\t%s\n":                                                                                                      "%s\t%s\t%d Lesezeichen\t%s\n",
+		"no group named %s":                                                                                                               "keine Gruppe namens %s",
+		"Group %s is already disabled\n":                                                                                                  "Gruppe %s ist bereits deaktiviert\n",
+		"Group %s is already enabled\n":                                                                                                   "Gruppe %s ist bereits aktiviert\n",
+		"Group %s disabled (%d bookmarks)\n":                                                                                              "Gruppe %s deaktiviert (%d Lesezeichen)\n",
+		"Group %s enabled (%d bookmarks)\n":                                                                                               "Gruppe %s aktiviert (%d Lesezeichen)\n",
+		"Removed expired bookmark %s (%s)\n":                                                                                              "Abgelaufenes Lesezeichen %s (%s) entfernt\n",
+		"%s\t%s\texpires %s\n":                                                                                                            "%s\t%s\tläuft ab %s\n",
+		"Added %s until %s\n":                                                                                                             "%s bis %s hinzugefügt\n",
+		"%s is not a directory":                                                                                                           "%s ist kein Ordner",
+		"%s (%s): shares the host home, already in sync\n":                                                                                "%s (%s): teilt das Home des Hosts, bereits abgeglichen\n",
+		"%s (%s): separate home %s\n":                                                                                                     "%s (%s): eigenes Home %s\n",
+		"%s (%s): syncing into %s\n":                                                                                                      "%s (%s): Abgleich nach %s\n",
+		"Wrote %s\n":                                                                                                                      "%s geschrieben\n",
+		"Enable with: systemctl --user enable --now %s\n":                                                                                 "Aktivieren mit: systemctl --user enable --now %s\n",
+		"No changes":                      "Keine Änderungen",
+		"%q is ambiguous: %s":             "%q ist mehrdeutig: %s",
+		"%q matches several bookmarks:\n": "%q passt auf mehrere Lesezeichen:\n",
+		"Choose one: ":                    "Auswahl: ",
+		"invalid choice %q":               "ungültige Auswahl %q",
+		"%s: %v\n":                        "%s: %v\n",
+		"no bookmark is called %q; did you mean %s?":             "kein Lesezeichen heißt %q; war %s gemeint?",
+		"No bookmark is called %q; did you mean %s (%s)? [y/N] ": "Kein Lesezeichen heißt %q; war %s (%s) gemeint? [j/N] ",
+		"y":                   "j",
+		"yes":                 "ja",
+		"nothing was changed": "es wurde nichts geändert",
+		"Try operations failing with io, stale, busy or timeout errors N times (repeatable)": "Bei io-, stale-, busy- oder timeout-Fehlern N Versuche unternehmen (mehrfach)",
+		"Warning: failed to read %s (%v), retrying in %s":                                    "Warnung: Lesen von %s fehlgeschlagen (%v), neuer Versuch in %s",
+		"Warning: failed to write %s (%v), retrying in %s":                                   "Warnung: Schreiben von %s fehlgeschlagen (%v), neuer Versuch in %s",
+		"Show version information":                                                           "Versionsinformationen anzeigen",
+		"Show this help message":                                                             "Diese Hilfe anzeigen",
+
+		"Keep syncing whenever a backend's bookmarks change (same as sync --watch)":    "Bei jeder Änderung eines Backends abgleichen (wie sync --watch)",
+		"Add a bookmark to every backend":                                              "Ein Lesezeichen zu allen Backends hinzufügen",
+		"Remove a bookmark from every backend":                                         "Ein Lesezeichen aus allen Backends entfernen",
+		"Rename a bookmark in every backend":                                           "Ein Lesezeichen in allen Backends umbenennen",
+		"Bookmark a project's folders using a template":                                "Ordner eines Projekts anhand einer Vorlage als Lesezeichen anlegen",
+		"Report GTK bookmarks lines that don't round-trip, and repair them with --fix": "GTK-Lesezeichenzeilen melden, die sich nicht verlustfrei lesen lassen, und sie mit --fix reparieren",
+		"List running containers or sync bookmarks into them":                          "Laufende Container auflisten oder Lesezeichen in sie abgleichen",
+		"Show how the second backend's places differ from the first's":                 "Zeigen, wie sich die Orte des zweiten Backends von denen des ersten unterscheiden",
+		"Edit bookmarks in $EDITOR and write the result to every backend":              "Lesezeichen in $EDITOR bearbeiten und in alle Backends schreiben",
+		"Print the man page":                             "Die Manpage ausgeben",
+		"Print the command reference as markdown":        "Die Befehlsreferenz als Markdown ausgeben",
+		"List bookmark groups or switch them on and off": "Lesezeichengruppen auflisten oder ein- und ausschalten",
+		"Install and enable systemd user units that sync on login, or start the daemon on demand with --dbus": "systemd-Benutzereinheiten für den Abgleich bei der Anmeldung einrichten, oder den Dienst mit --dbus bei Bedarf starten",
+		"With --watch, exit after DURATION without syncs or D-Bus calls":                                      "Mit --watch nach DAUER ohne Abgleich oder D-Bus-Aufruf beenden",
+		"No activity for %s, exiting":                                 "Seit %s keine Aktivität, beende",
+		"Print a backend's places, with -l also where each came from": "Die Orte eines Backends ausgeben, mit -l auch ihre Herkunft",
+		"Open a bookmark with its configured application":             "Ein Lesezeichen mit der eingestellten Anwendung öffnen",
+		"Print the local directory of a bookmark":                     "Den lokalen Ordner eines Lesezeichens ausgeben",
+		"Remove the bookmarks added for a project":                    "Die Lesezeichen eines Projekts entfernen",
+		"Set the application a bookmark opens with":                   "Die Anwendung festlegen, mit der ein Lesezeichen geöffnet wird",
+		"Print a shell function that cd's into bookmarks":             "Eine Shell-Funktion ausgeben, die in Lesezeichen wechselt",
+		"Add a bookmark that expires, or list them":                   "Ein ablaufendes Lesezeichen anlegen oder diese auflisten",
+
+		"Running sync from %s backend\n":                                     "Abgleich vom Backend %s\n",
+		"Running sync from %s backend (most recently modified)\n":            "Abgleich vom Backend %s (zuletzt geändert)\n",
+		"Running two-way sync\n":                                             "Zwei-Wege-Abgleich\n",
+		"No backend changed since the last sync\n":                           "Seit dem letzten Abgleich hat sich kein Backend geändert\n",
+		"Watching for bookmark changes\n":                                    "Warte auf Änderungen an Lesezeichen\n",
+		"Conflict: %s":                                                       "Konflikt: %s",
+		"Unknown command: %s":                                                "Unbekannter Befehl: %s",
+		"unexpected argument: %s":                                            "unerwartetes Argument: %s",
+		"Warning: -f and --sync-from are deprecated, use \"sync --from %s\"": "Warnung: -f und --sync-from sind veraltet, bitte \"sync --from %s\" verwenden",
+		"Unknown backend: %s":                                                "Unbekanntes Backend: %s",
+		"Sync failed":                                                        "Abgleich fehlgeschlagen",
+		"Watch failed: %v":                                                   "Überwachung fehlgeschlagen: %v",
+		"Failed to load configuration: %v":                                   "Konfiguration konnte nicht geladen werden: %v",
+		"Failed to recover interrupted sync: %v":                             "Unterbrochener Abgleich konnte nicht zurückgesetzt werden: %v",
+		"Failed to scan for AppImages: %v":                                   "Suche nach AppImages fehlgeschlagen: %v",
+		"Rolled back an interrupted sync from %s (%d files restored)":        "Unterbrochener Abgleich vom %s zurückgesetzt (%d Dateien wiederhergestellt)",
+		"--dry-run cannot be combined with --two-way or --watch":             "--dry-run kann nicht mit --two-way oder --watch kombiniert werden",
+		"Warning: %s were all modified since the last sync; changes outside %s may be lost": "Warnung: %s wurden seit dem letzten Abgleich geändert; Änderungen außerhalb von %s können verloren gehen",
+		"Warning: D-Bus interface unavailable: %v":                                          "Warnung: D-Bus-Schnittstelle nicht verfügbar: %v",
+		"Warning: failed to expire temporary bookmarks: %v":                                 "Warnung: abgelaufene temporäre Lesezeichen konnten nicht entfernt werden: %v",
+		"Warning: failed to save state: %v":                                                 "Warnung: Zustand konnte nicht gespeichert werden: %v",
+		"Warning: not watching %s: %v":                                                      "Warnung: %s wird nicht überwacht: %v",
+		"Warning: sync failed: %v":                                                          "Warnung: Abgleich fehlgeschlagen: %v",
+		"Warning: watch error: %v":                                                          "Warnung: Fehler bei der Überwachung: %v",
+
+		"BACKEND\tFILE\tPLACES\tMODIFIED\tSTATUS": "BACKEND\tDATEI\tORTE\tGEÄNDERT\tSTATUS",
+		"LABEL\tTARGET":                      "NAME\tZIEL",
+		"LABEL\tTARGET\tORIGIN\tHOST\tADDED": "NAME\tZIEL\tHERKUNFT\tRECHNER\tHINZUGEFÜGT",
+		"error: %v":                          "Fehler: %v",
+		"missing":                            "fehlt",
+		"unreadable":                         "nicht lesbar",
+		"never synced":                       "nie abgeglichen",
+		"in sync":                            "abgeglichen",
+		"changed since last sync":            "seit dem letzten Abgleich geändert",
+		"\nLast sync: %s":                    "\nLetzter Abgleich: %s",
+
+		"%s: no changes\n":                     "%s: keine Änderungen\n",
+		"%s: reordered\n":                      "%s: neu sortiert\n",
+		"%s: cannot read current places: %v\n": "%s: aktuelle Orte nicht lesbar: %v\n",
+		"Added %s (%s)\n":                      "%s (%s) hinzugefügt\n",
+		"%s:%d: %s, dropping: %s\n":            "%s:%d: %s, wird verworfen: %s\n",
+		"%s:%d: %s\n  %s\n  -> %s\n":           "%s:%d: %s\n  %s\n  -> %s\n",
+		"Repaired %s\n":                        "%s repariert\n",
+		"Removed %s (%s)\n":                    "%s (%s) entfernt\n",
+		"Renamed %s to %s\n":                   "%s in %s umbenannt\n",
+		"Removed disabled group %s\n":          "Deaktivierte Gruppe %s entfernt\n",
+		"Removed %d bookmarks of %s\n":         "%d Lesezeichen von %s entfernt\n",
+	} {
+		message.SetString(language.German, key, msg)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// idleTimer ends the daemon once it has gone a while without syncing or
+// serving a D-Bus call, so that D-Bus activation or a systemd unit can
+// start it again on demand. A nil idleTimer never expires.
+type idleTimer struct {
+	timeout time.Duration
+	timer   *time.Timer
+	mu      sync.Mutex
+	// busy counts the activities in progress, which hold off expiry
+	busy int
+}
+
+// newIdleTimer returns a timer calling expire after timeout without
+// activity
+func newIdleTimer(timeout time.Duration, expire func()) *idleTimer {
+	return &idleTimer{timeout: timeout, timer: time.AfterFunc(timeout, expire)}
+}
+
+// Begin marks the start of an activity; the timer restarts when the
+// returned function is called at its end
+func (t *idleTimer) Begin() func() {
+	if t == nil {
+		return func() {}
+	}
+	t.mu.Lock()
+	t.busy++
+	t.timer.Stop()
+	t.mu.Unlock()
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.busy--; t.busy == 0 {
+			t.timer.Reset(t.timeout)
+		}
+	}
+}
+
+// Wrap returns run counted as an activity
+func (t *idleTimer) Wrap(run func() error) func() error {
+	if t == nil {
+		return run
+	}
+	return func() error {
+		defer t.Begin()()
+		return run()
+	}
+}
+
+// Stop disarms the timer
+func (t *idleTimer) Stop() {
+	if t != nil {
+		t.timer.Stop()
+	}
+}
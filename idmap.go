@@ -0,0 +1,88 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// nativeIDBackend is a backend whose records carry identifiers of their
+// own, like the IDs of KDE's places. They are mapped to the places' IDs in
+// the state, so that a rewrite can update the record a place had instead
+// of dropping it and creating a new one.
+type nativeIDBackend interface {
+	// NativeIDs returns the identifier of each record, by normalized
+	// target
+	NativeIDs() (map[string]string, error)
+}
+
+// newPlaceID returns a random (version 4) UUID
+func newPlaceID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// recordIDs gives every place of state.Seen an ID if it has none yet,
+// forgets those of places no backend holds any more, and maps the IDs to
+// the records of the backends that have identifiers of their own
+func (bs *BookmarkSync) recordIDs(state *State) {
+	held := make(map[string]bool)
+	for _, places := range state.Seen {
+		for _, place := range places {
+			held[normalizeTarget(place.Target)] = true
+		}
+	}
+	if state.PlaceIDs == nil {
+		state.PlaceIDs = make(map[string]string)
+	}
+	for key := range state.PlaceIDs {
+		if !held[key] {
+			delete(state.PlaceIDs, key)
+		}
+	}
+	for key := range held {
+		if _, ok := state.PlaceIDs[key]; !ok {
+			state.PlaceIDs[key] = newPlaceID()
+		}
+	}
+
+	for _, backend := range bs.Backends() {
+		b, ok := unwrapped[nativeIDBackend](backend)
+		if !ok {
+			continue
+		}
+		records, err := b.NativeIDs()
+		if err != nil {
+			continue
+		}
+		mapped := make(map[string]string, len(records))
+		for key, native := range records {
+			if id, ok := state.PlaceIDs[key]; ok {
+				mapped[id] = native
+			}
+		}
+		if state.NativeIDs == nil {
+			state.NativeIDs = make(map[string]map[string]string)
+		}
+		state.NativeIDs[backend.Name()] = mapped
+	}
+}
+
+// mappedNativeIDs returns the identifiers the records of the backend
+// called name had at the last sync, by the normalized target of the place
+// that now has the record's ID
+func mappedNativeIDs(name string) map[string]string {
+	state, err := LoadState()
+	if err != nil {
+		return nil
+	}
+	natives := make(map[string]string)
+	for key, id := range state.PlaceIDs {
+		if native, ok := state.NativeIDs[name][id]; ok {
+			natives[key] = native
+		}
+	}
+	return natives
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Journal is an undo log for a write that touches several backend files.
+// Before anything is written, every file is copied aside and the journal is
+// saved; removing the journal commits the transaction. If bookmarksync dies
+// half way, the next run finds the journal and restores every file, so the
+// backends never stay half synced.
+type Journal struct {
+	Started time.Time      `json:"started"`
+	Entries []journalEntry `json:"entries"`
+
+	dir string
+}
+
+type journalEntry struct {
+	Path    string      `json:"path"`
+	Backup  string      `json:"backup,omitempty"`
+	Existed bool        `json:"existed"`
+	Mode    os.FileMode `json:"mode,omitempty"`
+}
+
+// journalDir returns where the journal and its file copies are kept
+func journalDir() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "journal"), nil
+}
+
+// BeginTransaction saves a copy of every file of the given backends and
+// records them in the journal
+func BeginTransaction(backends []BookmarkSyncBackend) (*Journal, error) {
+	dir, err := journalDir()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, "journal.json")); err == nil {
+		return nil, fmt.Errorf("an unfinished transaction is pending in %s", dir)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	journal := &Journal{Started: time.Now(), dir: dir}
+	for _, backend := range backends {
+		files, err := backend.Files()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve files of %s: %v", backend.Name(), err)
+		}
+		for _, path := range files {
+			entry := journalEntry{Path: path}
+			info, err := os.Stat(path)
+			switch {
+			case os.IsNotExist(err):
+			case err != nil:
+				return nil, err
+			default:
+				entry.Existed = true
+				entry.Mode = info.Mode().Perm()
+				entry.Backup = strconv.Itoa(len(journal.Entries))
+				if err := copyFileSynced(path, filepath.Join(dir, entry.Backup)); err != nil {
+					return nil, fmt.Errorf("failed to back up %s: %v", path, err)
+				}
+			}
+			journal.Entries = append(journal.Entries, entry)
+		}
+	}
+
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileSynced(filepath.Join(dir, "journal.json"), data, 0600); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// Commit ends the transaction, keeping everything that was written
+func (j *Journal) Commit() error {
+	if err := os.Remove(filepath.Join(j.dir, "journal.json")); err != nil {
+		return err
+	}
+	return os.RemoveAll(j.dir)
+}
+
+// RecoverJournal rolls back a transaction left behind by a crashed run
+func RecoverJournal() error {
+	dir, err := journalDir()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dir, "journal.json")); os.IsNotExist(err) {
+		return nil
+	}
+	// The journal may be another process's, still writing; once it lets
+	// go of the run lock, the journal is gone or really left behind
+	release, err := acquireRunLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+	data, err := os.ReadFile(filepath.Join(dir, "journal.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var journal Journal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return fmt.Errorf("corrupt journal %s: %v", dir, err)
+	}
+
+	journal.dir = dir
+	if err := journal.restore(); err != nil {
+		return err
+	}
+	log.Print(tr("Rolled back an interrupted sync from %s (%d files restored)",
+		journal.Started.Format(time.DateTime), len(journal.Entries)))
+	return journal.Commit()
+}
+
+// restore puts every file of the journal back as it was when the
+// transaction began, removing those that didn't exist
+func (j *Journal) restore() error {
+	for _, entry := range j.Entries {
+		if !entry.Existed {
+			if err := os.Remove(entry.Path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		// Through replaceFile, so a symlinked file stays one
+		backup, err := os.ReadFile(filepath.Join(j.dir, entry.Backup))
+		if err == nil {
+			err = replaceFile(entry.Path, backup)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore %s: %v", entry.Path, err)
+		}
+		if err := os.Chmod(entry.Path, entry.Mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFileSynced copies src to dst and flushes it to disk
+func copyFileSynced(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// writeFileSynced writes data to a temporary file, flushes it and renames
+// it into place, so readers see either the old or the new content
+func writeFileSynced(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// replaceFile atomically replaces the contents of a backend file with data,
+// keeping the mode of the file it replaces, or 0644 for a new one. A
+// symlinked file, as dotfile managers leave them, is replaced at the
+// link's target, so the link stays.
+func replaceFile(path string, data []byte) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	return writeFileSynced(path, data, perm)
+}
+
+// inTransaction runs write with every file of the given backends journaled.
+// When write fails, the files are restored from the journal, so a write
+// that can't finish leaves nothing half written; one that can't be
+// restored leaves the journal for the next run to roll back.
+func inTransaction(backends []BookmarkSyncBackend, write func() error) error {
+	release, err := acquireRunLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+	journal, err := BeginTransaction(backends)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	writeErr := write()
+	if writeErr != nil {
+		if err := journal.restore(); err != nil {
+			return fmt.Errorf("%v, and failed to roll back: %v", writeErr, err)
+		}
+	}
+	if err := journal.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return writeErr
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Regression test: recovery renamed the saved copy over a symlinked file
+func TestRecoverJournalKeepsSymlinks(t *testing.T) {
+	home := testHome(t)
+	real := writeFile(t, home, "dotfiles/bookmarks", "file:///a a\n")
+	link := filepath.Join(home, "bookmarks")
+	if err := os.Symlink(real, link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BeginTransaction([]BookmarkSyncBackend{&GTKBackend{Path: link}}); err != nil {
+		t.Fatal(err)
+	}
+	// The run dies half way through writing
+	writeFile(t, home, "dotfiles/bookmarks", "file:///b")
+
+	if err := RecoverJournal(); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("%s is no longer a symlink", link)
+	}
+	if data, _ := os.ReadFile(real); string(data) != "file:///a a\n" {
+		t.Errorf("restored %q", data)
+	}
+}
+
+func TestRecoverJournal(t *testing.T) {
+	home := testHome(t)
+	gtk := writeFile(t, home, "bookmarks", "file:///a a\n")
+	if err := os.Chmod(gtk, 0600); err != nil {
+		t.Fatal(err)
+	}
+	kde := filepath.Join(home, "user-places.xbel")
+	backends := []BookmarkSyncBackend{&GTKBackend{Path: gtk}, &KDEBackend{Path: kde}}
+	if _, err := BeginTransaction(backends); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BeginTransaction(backends); err == nil {
+		t.Error("began a transaction with one pending")
+	}
+	// The run dies after writing one file and creating the other
+	writeFile(t, home, "bookmarks", "file:///b b\n")
+	if err := os.Chmod(gtk, 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, home, "user-places.xbel", "<xbel")
+
+	if err := RecoverJournal(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(gtk); string(data) != "file:///a a\n" {
+		t.Errorf("restored %q", data)
+	}
+	if info, err := os.Stat(gtk); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("restored with mode %v", info.Mode())
+	}
+	if _, err := os.Stat(kde); !os.IsNotExist(err) {
+		t.Errorf("%s created by the run is still there", kde)
+	}
+	dir, err := journalDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("journal left behind")
+	}
+}
+
+func TestJournalCommit(t *testing.T) {
+	home := testHome(t)
+	gtk := writeFile(t, home, "bookmarks", "file:///a a\n")
+	journal, err := BeginTransaction([]BookmarkSyncBackend{&GTKBackend{Path: gtk}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, home, "bookmarks", "file:///b b\n")
+	if err := journal.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecoverJournal(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(gtk); string(data) != "file:///b b\n" {
+		t.Errorf("committed write undone: %q", data)
+	}
+}
+
+func TestInTransactionRollsBack(t *testing.T) {
+	home := testHome(t)
+	gtk := writeFile(t, home, "bookmarks", "file:///a a\n")
+	kde := filepath.Join(home, "user-places.xbel")
+	backends := []BookmarkSyncBackend{&GTKBackend{Path: gtk}, &KDEBackend{Path: kde}}
+
+	// The second write fails after the first went through
+	failed := errors.New("disk full")
+	err := inTransaction(backends, func() error {
+		writeFile(t, home, "bookmarks", "file:///b b\n")
+		writeFile(t, home, "user-places.xbel", "<xbel")
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("inTransaction() = %v, want the write's error", err)
+	}
+	if data, _ := os.ReadFile(gtk); string(data) != "file:///a a\n" {
+		t.Errorf("failed write left %q", data)
+	}
+	if _, err := os.Stat(kde); !os.IsNotExist(err) {
+		t.Errorf("%s created by the failed write is still there", kde)
+	}
+	dir, err := journalDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("journal left behind")
+	}
+}
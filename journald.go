@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// journalSocket is where journald takes entries in its native protocol
+const journalSocket = "/run/systemd/journal/socket"
+
+// Priorities of journal entries, as in syslog
+const (
+	journalErr     = 3
+	journalWarning = 4
+	journalInfo    = 6
+)
+
+// journald sends log entries to the systemd journal when bookmarksync runs
+// under systemd with its output going there, nil otherwise
+var journald *journalWriter
+
+// journalWriter writes entries with priorities and fields of their own to
+// the journal, so "journalctl --user -u bookmarksync -p warning" or
+// "journalctl --user BACKEND=kde OP=failed" pick them out
+type journalWriter struct {
+	conn net.Conn
+}
+
+// setupJournald sends the log to the journal if stderr is connected to it
+func setupJournald() {
+	if !journalStreamed() {
+		return
+	}
+	conn, err := net.Dial("unixgram", journalSocket)
+	if err != nil {
+		return
+	}
+	journald = &journalWriter{conn: conn}
+	// The journal has timestamps of its own
+	log.SetFlags(0)
+	log.SetOutput(journald)
+}
+
+// Write sends a log line; everything bookmarksync logs is a warning, and
+// errors it exits on go through fatal
+func (j *journalWriter) Write(p []byte) (int, error) {
+	if err := j.Send(journalWarning, strings.TrimSuffix(string(p), "\n"), nil); err != nil {
+		return os.Stderr.Write(p)
+	}
+	return len(p), nil
+}
+
+// Send writes an entry with the given priority, message and fields, whose
+// names must be upper case
+func (j *journalWriter) Send(priority int, message string, fields map[string]string) error {
+	var entry bytes.Buffer
+	writeJournalField(&entry, "MESSAGE", message)
+	writeJournalField(&entry, "PRIORITY", strconv.Itoa(priority))
+	writeJournalField(&entry, "SYSLOG_IDENTIFIER", "bookmarksync")
+	for name, value := range fields {
+		writeJournalField(&entry, name, value)
+	}
+	_, err := j.conn.Write(entry.Bytes())
+	return err
+}
+
+// writeJournalField encodes a field: NAME=value, or for values spanning
+// lines the name, the value's length as a little-endian uint64 and the
+// value
+func writeJournalField(entry *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		entry.WriteString(name + "=" + value + "\n")
+		return
+	}
+	entry.WriteString(name + "\n")
+	binary.Write(entry, binary.LittleEndian, uint64(len(value)))
+	entry.WriteString(value + "\n")
+}
+
+// fatal logs message as an error and exits
+func fatal(message string) {
+	exitWith(1, message)
+}
+
+// exitWith logs message as an error and exits with status
+func exitWith(status int, message string) {
+	if journald == nil || journald.Send(journalErr, message, nil) != nil {
+		log.Print(message)
+	}
+	os.Exit(status)
+}
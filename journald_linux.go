@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// journalStreamed reports whether stderr is connected to the journal:
+// systemd sets $JOURNAL_STREAM to its device and inode then
+func journalStreamed() bool {
+	stream := os.Getenv("JOURNAL_STREAM")
+	if stream == "" {
+		return false
+	}
+	var st syscall.Stat_t
+	if err := syscall.Fstat(int(os.Stderr.Fd()), &st); err != nil {
+		return false
+	}
+	return stream == fmt.Sprintf("%d:%d", st.Dev, st.Ino)
+}
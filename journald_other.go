@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// journalStreamed reports whether stderr is connected to the journal,
+// which only exists on Linux
+func journalStreamed() bool {
+	return false
+}
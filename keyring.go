@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// errKeyringLocked is a backend's credentials being in a keyring that is
+// still locked, like early at login. Syncs leave such a backend for later
+// rather than failing.
+var errKeyringLocked = errors.New("the keyring is locked")
+
+// keyringWait is how long a sync that had to leave backends for the
+// keyring waits for it to be unlocked, to sync them too
+const keyringWait = 15 * time.Minute
+
+// keyringPoll is how often the keyring is checked while waiting for it
+var keyringPoll = 5 * time.Second
+
+// keyringLocked reports whether the user's keyring is locked: the default
+// collection of the Secret Service (GNOME Keyring, KeePassXC, KWallet 6)
+// or KWallet's network wallet. Without either running there is nothing to
+// wait for.
+var keyringLocked = func() bool {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return false
+	}
+	// Not started for asking
+	running := func(name string) bool {
+		var has bool
+		return conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, name).Store(&has) == nil && has
+	}
+	if running("org.freedesktop.secrets") {
+		locked, err := conn.Object("org.freedesktop.secrets", "/org/freedesktop/secrets/aliases/default").
+			GetProperty("org.freedesktop.Secret.Collection.Locked")
+		if err == nil {
+			return locked.Value() == true
+		}
+	}
+	for _, version := range []string{"6", "5"} {
+		name := "org.kde.kwalletd" + version
+		if !running(name) {
+			continue
+		}
+		wallet := conn.Object(name, dbus.ObjectPath("/modules/kwalletd"+version))
+		var networkWallet string
+		var open bool
+		if wallet.Call("org.kde.KWallet.networkWallet", 0).Store(&networkWallet) != nil ||
+			wallet.Call("org.kde.KWallet.isOpen", 0, networkWallet).Store(&open) != nil {
+			continue
+		}
+		return !open
+	}
+	return false
+}
+
+// sshKeysInKeyring reports whether ssh gets its keys from the agent of the
+// keyring, GNOME Keyring's, which only has them once it is unlocked
+func sshKeysInKeyring() bool {
+	socket, runtime := os.Getenv("SSH_AUTH_SOCK"), os.Getenv("XDG_RUNTIME_DIR")
+	return socket != "" && runtime != "" && strings.HasPrefix(socket, filepath.Join(runtime, "keyring")+"/")
+}
+
+// checkKeyring returns errKeyringLocked when credentials come from the
+// keyring and it is locked
+func checkKeyring(fromKeyring bool) error {
+	if fromKeyring && keyringLocked() {
+		return errKeyringLocked
+	}
+	return nil
+}
+
+// waitForKeyring waits until the keyring is unlocked, at most timeout, and
+// reports whether it was
+func waitForKeyring(ctx context.Context, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(keyringPoll)
+	defer ticker.Stop()
+	for keyringLocked() {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
+
+// setDeferred records the backends a sync left for the keyring
+func (bs *BookmarkSync) setDeferred(names []string) {
+	bs.deferred.Lock()
+	defer bs.deferred.Unlock()
+	bs.deferred.names = names
+}
+
+// Deferred returns the backends the last sync left until the keyring is
+// unlocked
+func (bs *BookmarkSync) Deferred() []string {
+	bs.deferred.Lock()
+	defer bs.deferred.Unlock()
+	return bs.deferred.names
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testKeyring makes the keyring locked or not for the test, and returns a
+// function changing that
+func testKeyring(t *testing.T, locked bool) func(bool) {
+	t.Helper()
+	saved, savedPoll := keyringLocked, keyringPoll
+	keyringPoll = time.Millisecond
+	var mu sync.Mutex
+	keyringLocked = func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return locked
+	}
+	t.Cleanup(func() { keyringLocked, keyringPoll = saved, savedPoll })
+	return func(value bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		locked = value
+	}
+}
+
+func TestWebDAVKeyringLocked(t *testing.T) {
+	testHome(t)
+	unlock := testKeyring(t, true)
+	server := newWebDAVServer(t)
+	backend := &WebDAVBackend{URL: server.URL + "/places.toml", User: "me", PasswordCommand: "echo secret"}
+	if err := backend.Replace(places("a", "file:///a")); !errors.Is(err, errKeyringLocked) {
+		t.Fatalf("write with the keyring locked: %v", err)
+	}
+	if server.requests != 0 {
+		t.Error("the server was asked without the password")
+	}
+
+	unlock(false)
+	if err := backend.Replace(places("a", "file:///a")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(server.data), `target = "file:///a"`) {
+		t.Errorf("server holds %q", server.data)
+	}
+}
+
+// A sync at login leaves the backends whose credentials are in the locked
+// keyring, and syncs them once it is unlocked
+func TestSyncDefersLockedBackends(t *testing.T) {
+	home := testHome(t)
+	testWithoutGTK2(t)
+	unlock := testKeyring(t, true)
+	server := newWebDAVServer(t)
+	config.WebDAVURL = server.URL + "/places.toml"
+	config.WebDAVUser = "me"
+	config.WebDAVPasswordCommand = "echo secret"
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\n")
+
+	bs := NewBookmarkSync()
+	if err := bs.SyncFrom("gtk"); err != nil {
+		t.Fatalf("sync failed for the locked keyring: %v", err)
+	}
+	if deferred := bs.Deferred(); !reflect.DeepEqual(deferred, []string{"webdav"}) {
+		t.Errorf("deferred %v, want [webdav]", deferred)
+	}
+	if got, _ := bs.backends["kde"].GetPlaces(); !reflect.DeepEqual(got, places("a", "file:///a")) {
+		t.Errorf("kde holds %v", got)
+	}
+	if server.data != nil {
+		t.Error("webdav written with the keyring locked")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		unlock(false)
+	}()
+	if !waitForKeyring(context.Background(), time.Minute) {
+		t.Fatal("unlocking the keyring wasn't noticed")
+	}
+	if err := bs.SyncFrom("gtk"); err != nil {
+		t.Fatal(err)
+	}
+	if len(bs.Deferred()) != 0 || server.data == nil {
+		t.Errorf("webdav still deferred after unlocking")
+	}
+}
+
+func TestWaitForKeyringTimeout(t *testing.T) {
+	testKeyring(t, true)
+	if waitForKeyring(context.Background(), 20*time.Millisecond) {
+		t.Error("a keyring that stays locked was waited for")
+	}
+}
+
+func TestSSHKeysInKeyring(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	for socket, want := range map[string]bool{
+		"/run/user/1000/keyring/ssh": true,
+		"/run/user/1000/ssh-agent":   false,
+		"/tmp/ssh-XXXX/agent.123":    false,
+		"":                           false,
+	} {
+		t.Setenv("SSH_AUTH_SOCK", socket)
+		if got := sshKeysInKeyring(); got != want {
+			t.Errorf("%q is the keyring's: %v, want %v", socket, got, want)
+		}
+	}
+}
+
+func TestGitOverSSH(t *testing.T) {
+	for remote, want := range map[string]bool{
+		"git@example.com:me/places.git":     true,
+		"ssh://git@example.com/places.git":  true,
+		"https://example.com/me/places.git": false,
+		"/srv/git/places.git":               false,
+		"file:///srv/git/places.git":        false,
+		"../places.git":                     false,
+	} {
+		if got := (&GitBackend{Remote: remote}).overSSH(); got != want {
+			t.Errorf("%s over ssh: %v, want %v", remote, got, want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LaunchersBackend implements BookmarkSyncBackend for application launchers:
+// every place becomes a .desktop file in $XDG_DATA_HOME/applications, so
+// bookmarks show up in app launchers and docks. Launchers are write-only,
+// and only the ones listed in the manifest are ever removed.
+type LaunchersBackend struct{}
+
+// launcherPrefix starts the name of every .desktop file bookmarksync writes
+const launcherPrefix = "bookmarksync-"
+
+func (l *LaunchersBackend) Name() string {
+	return "launchers"
+}
+
+// dir returns the folder the launchers are written to
+func (l *LaunchersBackend) dir() (string, error) {
+	dataHome, err := xdgDataHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataHome, "applications"), nil
+}
+
+func (l *LaunchersBackend) Files() ([]string, error) {
+	dir, err := l.dir()
+	if err != nil {
+		return nil, err
+	}
+	return shortcutFiles(dir), nil
+}
+
+func (l *LaunchersBackend) GetPlaces() ([]Place, error) {
+	dir, err := l.dir()
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := readShortcutManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	places := []Place{}
+	for _, entry := range manifest {
+		places = append(places, entry.Place)
+	}
+	return places, nil
+}
+
+func (l *LaunchersBackend) Merge(places []Place) error {
+	return mergeInto(l, places)
+}
+
+func (l *LaunchersBackend) Replace(places []Place) error {
+	dir, err := l.dir()
+	if err != nil {
+		return err
+	}
+
+	previous, err := readShortcutManifest(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range previous {
+		if err := os.Remove(filepath.Join(dir, entry.File)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// Launchers open their place with the command set-app chose for it
+	hints, err := readOpenWith()
+	if err != nil {
+		return err
+	}
+
+	var manifest bytes.Buffer
+	used := make(map[string]bool)
+	for _, place := range places {
+		label := place.Label
+		if label == "" {
+			label = filepath.Base(place.Target)
+		}
+		base := launcherPrefix + launcherSlug(label)
+		name := base + ".desktop"
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s-%d.desktop", base, i)
+		}
+		used[name] = true
+
+		icon := "folder"
+		if !strings.HasPrefix(place.Target, "file://") {
+			icon = "folder-remote"
+		}
+		var entry strings.Builder
+		entry.WriteString("[Desktop Entry]\n")
+		entry.WriteString("Type=Application\n")
+		fmt.Fprintf(&entry, "Name=%s\n", desktopEscape(label))
+		fmt.Fprintf(&entry, "Comment=%s\n", desktopEscape(place.Target))
+		fmt.Fprintf(&entry, "Icon=%s\n", icon)
+		argv := openCommand(hints[place.Target], place.Target)
+		for i, arg := range argv {
+			argv[i] = desktopExecQuote(arg)
+		}
+		fmt.Fprintf(&entry, "Exec=%s\n", desktopEscape(strings.Join(argv, " ")))
+		entry.WriteString("Terminal=false\n")
+		entry.WriteString("Categories=Utility;\n")
+		if err := replaceFile(filepath.Join(dir, name), []byte(entry.String())); err != nil {
+			return err
+		}
+		fmt.Fprintf(&manifest, "%s\t%s\t%s\n", name, place.Target, place.Label)
+	}
+
+	return replaceFile(filepath.Join(dir, shortcutManifest), manifest.Bytes())
+}
+
+// launcherSlug turns a label into the lower-case ASCII part of a desktop
+// file name
+func launcherSlug(label string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(label) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			slug.WriteRune(r)
+			dash = false
+		} else if !dash && slug.Len() > 0 {
+			slug.WriteByte('-')
+			dash = true
+		}
+	}
+	if s := strings.TrimSuffix(slug.String(), "-"); s != "" {
+		return s
+	}
+	return "bookmark"
+}
+
+// desktopEscape escapes a desktop entry string value
+func desktopEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(s)
+}
+
+// desktopExecQuote quotes an Exec argument: reserved characters need
+// double quotes, inside which ", `, $ and \ are escaped, and % is doubled
+// everywhere
+func desktopExecQuote(arg string) string {
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	if !strings.ContainsAny(arg, " \t\n\"'\\><~|&;$*?#()`") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`"`, `\"`, "`", "\\`", "$", `\$`, `\`, `\\`).Replace(arg) + `"`
+}
+
+// runGenLaunchers implements "bookmarksync gen-launchers", which writes a
+// .desktop launcher for every bookmark of a backend and removes the ones
+// of bookmarks that are gone. sync --launchers keeps them up to date.
+func runGenLaunchers(args []string) error {
+	fs := flag.NewFlagSet("gen-launchers", flag.ExitOnError)
+	places, err := sourcePlaces(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usageError("bookmarksync-go gen-launchers [-f BACKEND]")
+	}
+
+	launchers := &LaunchersBackend{}
+	if err := launchers.Replace(places); err != nil {
+		return err
+	}
+	dir, err := launchers.dir()
+	if err != nil {
+		return err
+	}
+	fmt.Print(tr("Wrote %d launchers to %s\n", len(places), dir))
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// execLine returns the Exec line of the launcher called name
+func execLine(t *testing.T, home, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(home, ".local/share/applications", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if exec, ok := strings.CutPrefix(line, "Exec="); ok {
+			return exec
+		}
+	}
+	t.Fatalf("%s has no Exec line", name)
+	return ""
+}
+
+func TestLaunchersOpenWith(t *testing.T) {
+	home := testHome(t)
+	writeFile(t, home, ".config/bookmarksync/open-with", "file:///srv/code\tcode --new-window\nsftp://host/srv\tfilezilla %u\n")
+	launchers := &LaunchersBackend{}
+
+	if err := launchers.Replace(places("Code", "file:///srv/code", "Server", "sftp://host/srv", "My Docs", "file:///srv/my%20docs")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ name, exec string }{
+		{"bookmarksync-code.desktop", "code --new-window /srv/code"},
+		{"bookmarksync-server.desktop", "filezilla sftp://host/srv"},
+		{"bookmarksync-my-docs.desktop", `xdg-open file:///srv/my%%20docs`},
+	}
+	for _, test := range tests {
+		if exec := execLine(t, home, test.name); exec != test.exec {
+			t.Errorf("%s: Exec=%s, want Exec=%s", test.name, exec, test.exec)
+		}
+	}
+
+	files, err := launchers.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(home, ".local/share/applications")
+	want := []string{filepath.Join(dir, ".bookmarksync")}
+	for _, test := range tests {
+		want = append(want, filepath.Join(dir, test.name))
+	}
+	if !slices.Equal(files, want) {
+		t.Errorf("Files() = %v, want %v", files, want)
+	}
+}
+
+func TestSetAppUpdatesLauncher(t *testing.T) {
+	home := testHome(t)
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///srv/code Code\n")
+	if err := (&LaunchersBackend{}).Replace(places("Code", "file:///srv/code")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runSetApp([]string{"Code", "code"}); err != nil {
+		t.Fatal(err)
+	}
+	if exec := execLine(t, home, "bookmarksync-code.desktop"); exec != "code /srv/code" {
+		t.Errorf("Exec=%s, want Exec=code /srv/code", exec)
+	}
+}
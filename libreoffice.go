@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// LibreOfficeBackend implements BookmarkSyncBackend for the places shown in
+// LibreOffice's own file dialogs. They are stored as two parallel string
+// lists in the user profile's registrymodifications.xcu.
+type LibreOfficeBackend struct {
+	// Profile overrides the user profile directory
+	Profile string
+}
+
+const libreOfficeMiscPath = "/org.openoffice.Office.Common/Misc"
+
+// placesItemRe matches the registry items holding the places lists, which
+// LibreOffice writes one per line
+var placesItemRe = regexp.MustCompile(`(?s)[ \t]*<item oor:path="` + regexp.QuoteMeta(libreOfficeMiscPath) + `"><prop oor:name="FilePickerPlaces(?:Urls|Names)".*?</item>\r?\n?`)
+
+type xcuItems struct {
+	Items []xcuItem `xml:"item"`
+}
+
+type xcuItem struct {
+	Path  string    `xml:"path,attr"`
+	Props []xcuProp `xml:"prop"`
+}
+
+type xcuProp struct {
+	Name   string   `xml:"name,attr"`
+	Values []string `xml:"value>it"`
+}
+
+func (l *LibreOfficeBackend) Name() string {
+	return "libreoffice"
+}
+
+// profileDir returns the LibreOffice user profile directory
+func (l *LibreOfficeBackend) profileDir() (string, error) {
+	if l.Profile != "" {
+		return l.Profile, nil
+	}
+	configHome, err := xdgConfigHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configHome, "libreoffice", "4", "user"), nil
+}
+
+// Installed reports whether LibreOffice has a user profile, which Replace
+// only writes into
+func (l *LibreOfficeBackend) Installed() bool {
+	profileDir, err := l.profileDir()
+	return err == nil && isDir(profileDir)
+}
+
+func (l *LibreOfficeBackend) Files() ([]string, error) {
+	profileDir, err := l.profileDir()
+	if err != nil {
+		return nil, err
+	}
+	return []string{filepath.Join(profileDir, "registrymodifications.xcu")}, nil
+}
+
+func (l *LibreOfficeBackend) GetPlaces() ([]Place, error) {
+	profileDir, err := l.profileDir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(profileDir, "registrymodifications.xcu"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Place{}, nil
+		}
+		return nil, err
+	}
+
+	var items xcuItems
+	if err := xml.Unmarshal(data, &items); err != nil {
+		return nil, &corruptError{File: filepath.Join(profileDir, "registrymodifications.xcu"), Err: err}
+	}
+
+	var urls, names []string
+	for _, item := range items.Items {
+		if item.Path != libreOfficeMiscPath {
+			continue
+		}
+		for _, prop := range item.Props {
+			switch prop.Name {
+			case "FilePickerPlacesUrls":
+				urls = prop.Values
+			case "FilePickerPlacesNames":
+				names = prop.Values
+			}
+		}
+	}
+
+	var places []Place
+	for i, target := range urls {
+		label := ""
+		if i < len(names) {
+			label = names[i]
+		}
+		places = append(places, Place{Label: label, Target: target})
+	}
+
+	return places, nil
+}
+
+func (l *LibreOfficeBackend) Merge(places []Place) error {
+	return mergeInto(l, places)
+}
+
+func (l *LibreOfficeBackend) Replace(places []Place) error {
+	profileDir, err := l.profileDir()
+	if err != nil {
+		return err
+	}
+
+	// Don't create a profile for users who never ran LibreOffice; it would
+	// be treated as an existing (and nearly empty) configuration
+	if _, err := os.Stat(profileDir); os.IsNotExist(err) {
+		return nil
+	}
+
+	xcuPath := filepath.Join(profileDir, "registrymodifications.xcu")
+	data, err := l.Render(places)
+	if err != nil {
+		return err
+	}
+	return replaceFile(xcuPath, data)
+}
+
+func (l *LibreOfficeBackend) Render(places []Place) ([]byte, error) {
+	profileDir, err := l.profileDir()
+	if err != nil {
+		return nil, err
+	}
+	xcuPath := filepath.Join(profileDir, "registrymodifications.xcu")
+	data, err := os.ReadFile(xcuPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		data = []byte(`<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+			`<oor:items xmlns:oor="http://openoffice.org/2001/registry" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` + "\n" +
+			`</oor:items>` + "\n")
+	}
+
+	// The registry is edited as text to keep the rest of it as it is, but
+	// one that doesn't parse would lose what comes after the break
+	var parsed xcuItems
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		return nil, &corruptError{File: xcuPath, Err: err}
+	}
+
+	var urls, names []string
+	for _, place := range places {
+		urls = append(urls, place.Target)
+		names = append(names, place.Label)
+	}
+
+	content := placesItemRe.ReplaceAllString(string(data), "")
+	end := strings.LastIndex(content, "</oor:items>")
+	if end < 0 {
+		return nil, &corruptError{File: xcuPath, Err: errors.New("missing </oor:items>")}
+	}
+
+	var items strings.Builder
+	items.WriteString(xcuStringList("FilePickerPlacesNames", names))
+	items.WriteString(xcuStringList("FilePickerPlacesUrls", urls))
+	content = content[:end] + items.String() + content[end:]
+	return []byte(content), nil
+}
+
+// xcuStringList renders a registry item setting a string list property
+func xcuStringList(name string, values []string) string {
+	var buf bytes.Buffer
+	buf.WriteString(`<item oor:path="` + libreOfficeMiscPath + `"><prop oor:name="` + name + `" oor:op="fuse">`)
+	if len(values) == 0 {
+		buf.WriteString(`<value/>`)
+	} else {
+		buf.WriteString(`<value>`)
+		for _, value := range values {
+			buf.WriteString(`<it>`)
+			xml.EscapeText(&buf, []byte(value))
+			buf.WriteString(`</it>`)
+		}
+		buf.WriteString(`</value>`)
+	}
+	buf.WriteString("</prop></item>\n")
+	return buf.String()
+}
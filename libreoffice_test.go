@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Regression test: every sync rewrote LibreOffice's settings, for users
+// who never asked for it
+func TestLibreOfficeOptIn(t *testing.T) {
+	home := testHome(t)
+	registered := func() bool {
+		_, ok := NewBookmarkSync().backends["libreoffice"]
+		return ok
+	}
+
+	if registered() {
+		t.Error("registered without apps")
+	}
+	config.Apps = []string{"LibreOffice"}
+	if registered() {
+		t.Error("registered without a profile")
+	}
+	if err := os.MkdirAll(filepath.Join(home, ".config/libreoffice/4/user"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if !registered() {
+		t.Error("not registered with apps and a profile")
+	}
+}
+
+func TestLoadConfigApps(t *testing.T) {
+	home := testHome(t)
+	writeFile(t, home, ".config/bookmarksync/config.toml", `apps = ["libreoffice"]`)
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"libreoffice"}; !reflect.DeepEqual(cfg.Apps, want) {
+		t.Errorf("apps = %v, want %v", cfg.Apps, want)
+	}
+
+	writeFile(t, home, ".config/bookmarksync/config.toml", `apps = ["word"]`)
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "unknown app word") {
+		t.Errorf("LoadConfig() error %v, want an unknown app", err)
+	}
+}
+
+func TestLibreOfficeRoundTrip(t *testing.T) {
+	backend := &LibreOfficeBackend{Profile: t.TempDir()}
+	want := places("Projects", "file:///home/me/Projects", "Server", "sftp://host/srv", "Fünf & <sechs>", "file:///tmp/f%C3%BCnf")
+	if err := backend.Replace(want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := backend.GetPlaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("read back %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runList implements "bookmarksync list [BACKEND]", printing a backend's
+// places as a table, JSON, CSV with a header row, or header-less TSV for
+// cut and fzf
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	format := fs.String("format", "table", "Output format: table, json, csv or tsv")
+	long := fs.Bool("long", false, "Also show where each bookmark came from and when")
+	fs.BoolVar(long, "l", false, "Also show where each bookmark came from and when")
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		return usageError("bookmarksync-go list [--format table|json|csv|tsv] [-l] [BACKEND]")
+	}
+
+	name := "gtk"
+	if fs.NArg() == 1 {
+		name = strings.ToLower(fs.Arg(0))
+	}
+	backend, exists := NewBookmarkSync().backends[name]
+	if !exists {
+		return fmt.Errorf("unknown backend: %s", name)
+	}
+	places, err := backend.GetPlaces()
+	if err != nil {
+		return fmt.Errorf("failed to get places from %s: %v", backend.Name(), err)
+	}
+
+	if jsonOutput && *format == "table" {
+		*format = "json"
+	}
+
+	var provenance map[string]Provenance
+	if *long {
+		state, err := LoadState()
+		if err != nil {
+			return fmt.Errorf("failed to load state: %v", err)
+		}
+		provenance = state.Provenance
+	}
+	// origin returns a place's provenance columns: backend and command,
+	// host, and when it was added
+	origin := func(place Place) []string {
+		p, ok := provenance[normalizeTarget(place.Target)]
+		if !ok {
+			return []string{"-", "-", "-"}
+		}
+		from := p.Command
+		if p.Backend != "" {
+			from = p.Backend + " (" + p.Command + ")"
+		}
+		return []string{from, p.Host, p.Added.Format(time.DateTime)}
+	}
+
+	switch *format {
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		if *long {
+			fmt.Fprintln(w, tr("LABEL\tTARGET\tORIGIN\tHOST\tADDED"))
+		} else {
+			fmt.Fprintln(w, tr("LABEL\tTARGET"))
+		}
+		for _, place := range places {
+			row := []string{place.Label, place.Target}
+			if *long {
+				row = append(row, origin(place)...)
+			}
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return w.Flush()
+	case "json":
+		type listedPlace struct {
+			Place
+			Provenance *Provenance `json:"provenance,omitempty"`
+		}
+		listed := []listedPlace{}
+		for _, place := range places {
+			entry := listedPlace{Place: place}
+			if p, ok := provenance[normalizeTarget(place.Target)]; ok {
+				entry.Provenance = &p
+			}
+			listed = append(listed, entry)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(listed)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		header := []string{"label", "target"}
+		if *long {
+			header = append(header, "origin", "host", "added")
+		}
+		w.Write(header)
+		for _, place := range places {
+			row := []string{place.Label, place.Target}
+			if *long {
+				row = append(row, origin(place)...)
+			}
+			w.Write(row)
+		}
+		w.Flush()
+		return w.Error()
+	case "tsv":
+		for _, place := range places {
+			// Tabs and newlines would break the columns
+			label := strings.NewReplacer("\t", " ", "\n", " ").Replace(place.Label)
+			row := []string{label, place.Target}
+			if *long {
+				row = append(row, origin(place)...)
+			}
+			fmt.Println(strings.Join(row, "\t"))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown format: %s", *format)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// fileLock is an advisory lock on a lock file, which keeps two bookmarksync
+// processes from interleaving their reads and writes. Applications don't
+// take these locks; they only order bookmarksync against itself.
+type fileLock struct {
+	file *os.File
+}
+
+// lockDir returns where lock files are kept: the runtime directory, which
+// is emptied at logout, or the state directory without one
+func lockDir() (string, error) {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "bookmarksync"), nil
+	}
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "locks"), nil
+}
+
+// acquireLock takes the lock file called name, exclusively or shared,
+// waiting for other processes holding it. waiting is called first when it
+// has to wait. Where no lock file can be created, as on a read-only home,
+// nothing is locked.
+func acquireLock(name string, exclusive bool, waiting func()) (*fileLock, error) {
+	dir, err := lockDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil && !unlockable(err) {
+		return nil, err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		if unlockable(err) {
+			return &fileLock{}, nil
+		}
+		return nil, err
+	}
+	if err := flock(file, exclusive, false); errors.Is(err, errLocked) {
+		if waiting != nil {
+			waiting()
+		}
+		err = flock(file, exclusive, true)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileLock{file: file}, nil
+}
+
+// unlockable reports whether err means lock files can't be created at all
+func unlockable(err error) bool {
+	return errors.Is(err, syscall.EROFS) || errors.Is(err, os.ErrPermission)
+}
+
+// Release gives the lock up
+func (l *fileLock) Release() {
+	if l.file != nil {
+		l.file.Close()
+	}
+}
+
+// runLock is the lock a process holds while it syncs or writes backends,
+// counted so that a write inside a sync doesn't wait for the sync itself
+var runLock struct {
+	mu    sync.Mutex
+	held  int
+	taken *fileLock
+}
+
+// acquireRunLock takes the run lock, saying so when another bookmarksync,
+// such as the daemon, has to finish first. The returned function releases
+// it.
+func acquireRunLock() (func(), error) {
+	runLock.mu.Lock()
+	defer runLock.mu.Unlock()
+	if runLock.held == 0 {
+		lock, err := acquireLock("run.lock", true, func() {
+			log.Print(tr("Waiting for another bookmarksync to finish"))
+		})
+		if err != nil {
+			return nil, err
+		}
+		runLock.taken = lock
+	}
+	runLock.held++
+	return func() {
+		runLock.mu.Lock()
+		defer runLock.mu.Unlock()
+		if runLock.held--; runLock.held == 0 {
+			runLock.taken.Release()
+			runLock.taken = nil
+		}
+	}, nil
+}
+
+// withRunLock returns run holding the run lock
+func withRunLock(run func() error) func() error {
+	return func() error {
+		release, err := acquireRunLock()
+		if err != nil {
+			return err
+		}
+		defer release()
+		return run()
+	}
+}
+
+// lockedBackend holds a lock on each of a backend's files while it reads
+// them, shared, or writes them, exclusively. The locks are kept apart from
+// the files, which are replaced by renaming.
+type lockedBackend struct {
+	BookmarkSyncBackend
+}
+
+func (l *lockedBackend) Capabilities() Capabilities {
+	return capabilitiesOf(l.BookmarkSyncBackend)
+}
+
+func (l *lockedBackend) Unwrap() BookmarkSyncBackend {
+	return l.BookmarkSyncBackend
+}
+
+// lock locks the backend's files, returning the function unlocking them
+func (l *lockedBackend) lock(exclusive bool) (func(), error) {
+	files, err := l.Files()
+	if err != nil {
+		return nil, err
+	}
+	var locks []*fileLock
+	unlock := func() {
+		for _, lock := range locks {
+			lock.Release()
+		}
+	}
+	for _, file := range files {
+		sum := sha256.Sum256([]byte(file))
+		lock, err := acquireLock("file-"+hex.EncodeToString(sum[:8])+".lock", exclusive, nil)
+		if err != nil {
+			unlock()
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return unlock, nil
+}
+
+func (l *lockedBackend) GetPlaces() ([]Place, error) {
+	unlock, err := l.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return l.BookmarkSyncBackend.GetPlaces()
+}
+
+func (l *lockedBackend) Replace(places []Place) error {
+	unlock, err := l.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return l.BookmarkSyncBackend.Replace(places)
+}
+
+func (l *lockedBackend) Merge(places []Place) error {
+	unlock, err := l.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return l.BookmarkSyncBackend.Merge(places)
+}
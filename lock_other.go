@@ -0,0 +1,17 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// errLocked is returned by flock when it doesn't wait and another process
+// holds the lock
+var errLocked = errors.New("locked by another process")
+
+// flock does nothing where flock(2) doesn't exist
+func flock(file *os.File, exclusive, wait bool) error {
+	return nil
+}
@@ -0,0 +1,126 @@
+//go:build unix
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// blocked reports whether done stays open for a moment, what a lock that
+// is waited for looks like
+func blocked(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return false
+	case <-time.After(100 * time.Millisecond):
+		return true
+	}
+}
+
+// finishes reports whether done is closed within a few seconds
+func finishes(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	case <-time.After(5 * time.Second):
+		return false
+	}
+}
+
+func TestAcquireLock(t *testing.T) {
+	testHome(t)
+	first, err := acquireLock("test.lock", true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waited := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		second, err := acquireLock("test.lock", true, func() { close(waited) })
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		second.Release()
+	}()
+	if !blocked(done) {
+		t.Fatal("took a lock another holds exclusively")
+	}
+	select {
+	case <-waited:
+	default:
+		t.Error("waited without saying so")
+	}
+	first.Release()
+	if !finishes(done) {
+		t.Fatal("still waiting after the lock was released")
+	}
+}
+
+func TestSharedLocks(t *testing.T) {
+	testHome(t)
+	first, err := acquireLock("test.lock", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Release()
+	second, err := acquireLock("test.lock", false, func() { t.Error("readers waited for each other") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Release()
+}
+
+func TestRunLockIsReentrant(t *testing.T) {
+	testHome(t)
+	release, err := acquireRunLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A write inside the sync holding it
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		inner, err := acquireRunLock()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		inner()
+	}()
+	if !finishes(done) {
+		t.Fatal("the run lock waited for itself")
+	}
+	release()
+	if runLock.held != 0 || runLock.taken != nil {
+		t.Errorf("run lock still held %d times", runLock.held)
+	}
+}
+
+func TestLockedBackendWaitsForWriter(t *testing.T) {
+	home := testHome(t)
+	gtk := writeFile(t, home, "bookmarks", "file:///a a\n")
+	writer := &lockedBackend{&GTKBackend{Path: gtk}}
+	unlock, err := writer.lock(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := (&lockedBackend{&GTKBackend{Path: gtk}}).GetPlaces(); err != nil {
+			t.Error(err)
+		}
+	}()
+	if !blocked(done) {
+		t.Fatal("read the file while it was being written")
+	}
+	unlock()
+	if !finishes(done) {
+		t.Fatal("still waiting after the write")
+	}
+}
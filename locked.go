@@ -0,0 +1,173 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// keepLocked has the locked places of the canonical places file put back
+// into every backend written without them, with their label. --force
+// turns it off for the commands that take it.
+var keepLocked = true
+
+// lockedPlaces returns the bookmarks of the policy file, and the places of
+// the canonical places file that are locked and for this host unless
+// --force is given
+func lockedPlaces() []Place {
+	locked := slices.Clip(config.Mandated)
+	if !keepLocked || (!config.Canonical && config.Paths["canonical"] == "") || !config.enabled("canonical") {
+		return locked
+	}
+	path, err := (&CanonicalBackend{Path: config.Paths["canonical"]}).path()
+	if err != nil {
+		return locked
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return locked
+	}
+	// One that can't be read is the quarantine's to put right
+	if data, err = openPlaces(path, data); err != nil {
+		return locked
+	}
+	current, err := decodeCanonical(data)
+	if err != nil {
+		return locked
+	}
+	byKey := make(map[string]canonicalPlace, len(current))
+	for _, entry := range current {
+		byKey[normalizeTarget(entry.Target)] = entry
+	}
+	for _, entry := range current {
+		// Another copy may have unlocked it since
+		if newest, _ := newestEntry(byKey, normalizeTarget(entry.Target)); newest.Locked && onThisHost(newest.Hosts) {
+			locked = append(locked, Place{Label: newest.Label, Target: newest.Target})
+		}
+	}
+	return locked
+}
+
+// withLocked returns places with the locked places they lack added at the
+// end, and those they relabelled given their label back
+func withLocked(places []Place) []Place {
+	locked := lockedPlaces()
+	if len(locked) == 0 {
+		return places
+	}
+	// The policy's labels come first
+	labels := make(map[string]string, len(locked))
+	for _, place := range locked {
+		if _, ok := labels[normalizeTarget(place.Target)]; !ok {
+			labels[normalizeTarget(place.Target)] = place.Label
+		}
+	}
+	kept := make([]Place, 0, len(places)+len(locked))
+	for _, place := range places {
+		key := normalizeTarget(place.Target)
+		if label, ok := labels[key]; ok {
+			place.Label = label
+			delete(labels, key)
+		}
+		kept = append(kept, place)
+	}
+	for _, place := range locked {
+		key := normalizeTarget(place.Target)
+		if label, missing := labels[key]; missing {
+			kept = append(kept, Place{Label: label, Target: place.Target})
+			delete(labels, key)
+		}
+	}
+	return kept
+}
+
+// withoutLocked returns places without the locked ones, for the commands
+// that would drop them
+func withoutLocked(places []Place) []Place {
+	return removeTargets(places, lockedPlaces())
+}
+
+// isLocked reports whether place is a locked place
+func isLocked(place Place) bool {
+	key := normalizeTarget(place.Target)
+	return slices.ContainsFunc(lockedPlaces(), func(locked Place) bool {
+		return normalizeTarget(locked.Target) == key
+	})
+}
+
+// changedLocked returns the problems of edited, an edit of every place,
+// that removes or relabels a locked place, for "bookmarksync edit"
+func changedLocked(edited []Place) []string {
+	labels := make(map[string]string, len(edited))
+	for _, place := range edited {
+		labels[normalizeTarget(place.Target)] = place.Label
+	}
+	var problems []string
+	for _, place := range lockedPlaces() {
+		label, ok := labels[normalizeTarget(place.Target)]
+		switch {
+		case ok && label == place.Label:
+		case isMandated(place):
+			problems = append(problems, tr("%s is required by %s", place.Label, policyFile))
+		default:
+			problems = append(problems, tr("%s is locked: edit with --force to remove or relabel it", place.Label))
+		}
+	}
+	return problems
+}
+
+// runLockPlace implements "bookmarksync lock PATH|LABEL", which keeps a place
+// of the canonical places file from being removed or relabelled by syncs
+// and commands without --force, and "bookmarksync lock list"
+func runLockPlace(args []string) error {
+	if len(args) == 1 && args[0] == "list" {
+		for _, place := range lockedPlaces() {
+			fmt.Printf("%s\t%s\n", place.Label, place.Target)
+		}
+		return nil
+	}
+	fs := flag.NewFlagSet("lock", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return usageError("bookmarksync-go lock PATH|LABEL | lock list")
+	}
+	return setLocked("lock", fs.Arg(0), true)
+}
+
+// runUnlockPlace implements "bookmarksync unlock PATH|LABEL"
+func runUnlockPlace(args []string) error {
+	fs := flag.NewFlagSet("unlock", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return usageError("bookmarksync-go unlock PATH|LABEL")
+	}
+	return setLocked("unlock", fs.Arg(0), false)
+}
+
+// setLocked locks or unlocks the place of the canonical places file arg
+// names
+func setLocked(command, arg string, locked bool) error {
+	backend, places, entry, err := canonicalEntry(command, arg)
+	if err != nil {
+		return err
+	}
+	switch {
+	case entry.Locked == locked && locked:
+		fmt.Print(tr("%s is already locked\n", entry.Label))
+		return nil
+	case entry.Locked == locked:
+		fmt.Print(tr("%s isn't locked\n", entry.Label))
+		return nil
+	}
+	entry.Locked = locked
+	if err := updateEntry(backend, places, entry); err != nil {
+		return err
+	}
+	if locked {
+		fmt.Print(tr("Locked %s (%s)\n", entry.Label, entry.Target))
+	} else {
+		fmt.Print(tr("Unlocked %s (%s)\n", entry.Label, entry.Target))
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testLocked makes the canonical places file hold a locked place, an
+// unlocked one and one locked on another host
+func testLocked(t *testing.T) string {
+	t.Helper()
+	home := testHome(t)
+	config.Canonical = true
+	writeFile(t, home, ".config/bookmarksync/places.toml", `
+[[place]]
+label = "Handbook"
+target = "file:///srv/locked-handbook"
+locked = true
+
+[[place]]
+label = "Scratch"
+target = "file:///srv/locked-scratch"
+
+[[place]]
+label = "Elsewhere"
+target = "file:///srv/locked-elsewhere"
+hosts = ["not-this-host-*"]
+locked = true
+`)
+	return home
+}
+
+func TestLockedPlaces(t *testing.T) {
+	testLocked(t)
+	handbook := places("Handbook", "file:///srv/locked-handbook")
+	if locked := lockedPlaces(); !reflect.DeepEqual(locked, handbook) {
+		t.Errorf("locked %v, want %v", locked, handbook)
+	}
+
+	without := places("Scratch", "file:///srv/locked-scratch")
+	if got, want := withLocked(without), append(without, handbook...); !reflect.DeepEqual(got, want) {
+		t.Errorf("with locked %v, want %v", got, want)
+	}
+	relabelled := places("Manual", "file:///srv/locked-handbook/", "Scratch", "file:///srv/locked-scratch")
+	if got, want := withLocked(relabelled), places("Handbook", "file:///srv/locked-handbook/", "Scratch", "file:///srv/locked-scratch"); !reflect.DeepEqual(got, want) {
+		t.Errorf("with locked %v, want %v", got, want)
+	}
+	if got := withoutLocked(relabelled); !reflect.DeepEqual(got, without) {
+		t.Errorf("without locked %v, want %v", got, without)
+	}
+	if !isLocked(Place{Target: "file:///srv/locked-handbook/"}) || isLocked(without[0]) {
+		t.Error("isLocked doesn't tell the locked place")
+	}
+
+	// --force
+	keepLocked = false
+	t.Cleanup(func() { keepLocked = true })
+	if locked := lockedPlaces(); len(locked) != 0 {
+		t.Errorf("locked %v with --force", locked)
+	}
+	if got := withLocked(without); !reflect.DeepEqual(got, without) {
+		t.Errorf("with locked %v with --force", got)
+	}
+}
+
+func TestChangedLocked(t *testing.T) {
+	testLocked(t)
+	config.Mandated = places("Wiki", "https://wiki.example.com")
+	tests := []struct {
+		name   string
+		edited []Place
+		want   []string
+	}{
+		{
+			name:   "unchanged",
+			edited: places("Handbook", "file:///srv/locked-handbook", "Wiki", "https://wiki.example.com"),
+		},
+		{
+			name:   "removed",
+			edited: places("Handbook", "file:///srv/locked-handbook"),
+			want:   []string{"Wiki is required by " + policyFile},
+		},
+		{
+			name:   "relabelled",
+			edited: places("Manual", "file:///srv/locked-handbook", "Wiki", "https://wiki.example.com"),
+			want:   []string{"Handbook is locked"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems := changedLocked(test.edited)
+			if len(problems) != len(test.want) {
+				t.Fatalf("problems %q, want %q", problems, test.want)
+			}
+			for i, problem := range problems {
+				if !strings.HasPrefix(problem, test.want[i]) {
+					t.Errorf("problem %q, want %q", problem, test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSyncKeepsLocked(t *testing.T) {
+	home := testLocked(t)
+	testWithoutGTK2(t)
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///srv/locked-scratch Scratch\n")
+	if err := NewBookmarkSync().SyncFrom("gtk"); err != nil {
+		t.Fatal(err)
+	}
+	// The source is left as it is; the place is only removed there
+	for _, backend := range []BookmarkSyncBackend{&KDEBackend{}, &CanonicalBackend{}} {
+		got, err := backend.GetPlaces()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := places("Scratch", "file:///srv/locked-scratch", "Handbook", "file:///srv/locked-handbook"); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s holds %v, want %v", backend.Name(), got, want)
+		}
+	}
+}
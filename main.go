@@ -2,87 +2,616 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/xml"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"bookmarksync-go/bookmark"
+	"golang.org/x/sync/errgroup"
 	"gopkg.in/ini.v1"
 )
 
 const Version = "0.4.0"
 
 func main() {
+	setupJournald()
+	if err := RecoverJournal(); err != nil {
+		fatal(tr("Failed to recover interrupted sync: %v", err))
+	}
+	var err error
+	if config, err = LoadConfig(); err != nil {
+		fatal(tr("Failed to load configuration: %v", err))
+	}
+
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "--json" {
+		jsonOutput, args = true, args[1:]
+	}
+
+	// Without a command, the arguments are options of sync
+	name := "sync"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := findCommand(name)
+	if _, alias := config.Aliases[name]; !ok && alias {
+		cmd = command{Name: name, Run: func(args []string) error { return runAlias(name, args) }}
+		ok = true
+	}
+	if !ok {
+		printHelp(os.Stderr)
+		fatal(tr("Unknown command: %s", name))
+	}
+	commandName = cmd.Name
+	if err := cmd.Run(args); err != nil {
+		exitWith(exitStatus(err), fmt.Sprintf("%s: %v", name, err))
+	}
+}
+
+// runDaemon implements "bookmarksync daemon", which is sync in watch mode
+func runDaemon(args []string) error {
+	return runSync(append([]string{"--watch"}, args...))
+}
+
+// runSync implements "bookmarksync sync", also run when no command is given
+func runSync(args []string) error {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
 	var syncFrom string
+	var legacyFrom string
 	var showVersion bool
 	var showHelp bool
+	var cloudFolders bool
+	var gtkApps gtkAppFlag
+	var winePrefixes winePrefixFlag
+	var appImages bool
+	var scripts bool
+	var launchers bool
+	var appImageDirs stringListFlag
+	var sshHosts stringListFlag
+	var syncTo stringListFlag
+	var includes, excludes patternFlag
+	var allowUnsafe bool
+	var prune bool
+	var merge bool
+	var safe bool
+	var force bool
+	var dryRun bool
+	var twoWay bool
+	var conflictMode string
+	var auto bool
+	var watch bool
+	var debounce time.Duration
+	var maxDelay time.Duration
+	var idleExit time.Duration
+	if config.Paths == nil {
+		config.Paths = map[string]string{}
+	}
+	if config.Simulate == nil {
+		config.Simulate = map[string]string{}
+	}
+	retries := retryFlag{}
 
-	flag.StringVar(&syncFrom, "sync-from", "", "CLI mode: sync from a particular backend (gtk, kde, qt)")
-	flag.StringVar(&syncFrom, "f", "", "CLI mode: sync from a particular backend (gtk, kde, qt) (shorthand)")
-	flag.BoolVar(&showVersion, "version", false, "Show version information")
-	flag.BoolVar(&showHelp, "help", false, "Show help information")
-	flag.Parse()
+	fs.StringVar(&syncFrom, "from", "", optionHelp("from"))
+	// -f and --sync-from predate the sync command
+	fs.StringVar(&legacyFrom, "sync-from", "", "Deprecated alias for --from")
+	fs.StringVar(&legacyFrom, "f", "", "Deprecated alias for --from")
+	fs.Var(&syncTo, "sync-to", optionHelp("sync-to"))
+	fs.Var(&excludes, "exclude", optionHelp("exclude"))
+	fs.Var(&includes, "include", optionHelp("include"))
+	fs.BoolVar(&allowUnsafe, "allow-unsafe", false, optionHelp("allow-unsafe"))
+	fs.BoolVar(&merge, "merge", config.Merge, optionHelp("merge"))
+	fs.StringVar(&config.Order, "order", config.Order, optionHelp("order"))
+	fs.BoolVar(&prune, "prune", false, optionHelp("prune"))
+	fs.BoolVar(&safe, "safe", false, optionHelp("safe"))
+	fs.BoolVar(&force, "force", false, optionHelp("force"))
+	fs.BoolVar(&dryRun, "dry-run", false, optionHelp("dry-run"))
+	fs.BoolVar(&auto, "auto", false, optionHelp("auto"))
+	fs.BoolVar(&twoWay, "two-way", false, optionHelp("two-way"))
+	fs.StringVar(&conflictMode, "conflict", "auto", optionHelp("conflict"))
+	fs.BoolVar(&watch, "watch", false, optionHelp("watch"))
+	if config.Debounce == 0 {
+		config.Debounce = defaultDebounce
+	}
+	fs.DurationVar(&debounce, "debounce", config.Debounce, optionHelp("debounce"))
+	fs.DurationVar(&maxDelay, "max-delay", config.MaxDelay, optionHelp("max-delay"))
+	fs.DurationVar(&idleExit, "idle-exit", 0, optionHelp("idle-exit"))
+	fs.BoolVar(&cloudFolders, "cloud-folders", false, optionHelp("cloud-folders"))
+	fs.Var(&gtkApps, "gtk-app", optionHelp("gtk-app"))
+	fs.Var(&winePrefixes, "wine-prefix", optionHelp("wine-prefix"))
+	fs.BoolVar(&appImages, "appimages", false, optionHelp("appimages"))
+	fs.BoolVar(&scripts, "file-manager-scripts", false, optionHelp("file-manager-scripts"))
+	fs.BoolVar(&launchers, "launchers", false, optionHelp("launchers"))
+	fs.Var(&appImageDirs, "appimage-dir", optionHelp("appimage-dir"))
+	fs.Var(&sshHosts, "ssh-hosts", optionHelp("ssh-hosts"))
+	fs.Var(backendPathFlag(config.Paths), "backend-path", optionHelp("backend-path"))
+	fs.Var(simulateFlag(config.Simulate), "simulate", optionHelp("simulate"))
+	fs.Var(retries, "retry", optionHelp("retry"))
+	fs.BoolVar(&jsonOutput, "json", jsonOutput, optionHelp("json"))
+	fs.BoolVar(&showVersion, "version", false, optionHelp("version"))
+	fs.BoolVar(&showHelp, "help", false, optionHelp("help"))
+	fs.Usage = func() { printHelp(os.Stderr) }
+	fs.Parse(args)
 
 	if showVersion {
 		fmt.Printf("BookmarkSync %s\n", Version)
-		return
+		return nil
 	}
 
-	if showHelp || syncFrom == "" {
-		fmt.Println("BookmarkSync - A utility to sync bookmarks between GTK+, KDE, and Qt file dialogs")
-		fmt.Printf("Version: %s\n\n", Version)
-		fmt.Println("Usage:")
-		fmt.Println("  bookmarksync-go [OPTIONS]")
-		fmt.Println("\nOptions:")
-		fmt.Println("  -f, --sync-from BACKEND   Sync from a particular backend (gtk, kde, qt)")
-		fmt.Println("  --version                 Show version information")
-		fmt.Println("  --help                    Show this help message")
-		return
+	if showHelp {
+		printHelp(os.Stdout)
+		return nil
 	}
 
-	backend := strings.ToLower(syncFrom)
-	if backend != "gtk" && backend != "kde" && backend != "qt" {
-		log.Fatalf("Unknown backend: %s", backend)
+	if fs.NArg() > 0 {
+		return errors.New(tr("unexpected argument: %s", fs.Arg(0)))
+	}
+	if err := checkLockedFlags(fs); err != nil {
+		return err
+	}
+	if err := checkOrder(config.Order); err != nil {
+		return err
+	}
+	keepLocked = !force
+	if legacyFrom != "" {
+		log.Print(tr("Warning: -f and --sync-from are deprecated, use \"sync --from %s\"", legacyFrom))
+		if syncFrom == "" {
+			syncFrom = legacyFrom
+		}
+	}
+	if syncFrom == "" {
+		syncFrom = config.From
 	}
-
-	fmt.Printf("Running sync from %s backend\n", backend)
 
 	sync := NewBookmarkSync()
-	if err := sync.SyncFrom(backend); err != nil {
-		log.Fatalf("Sync failed: %v", err)
+	if canonical, ok := sync.backends["canonical"]; ok && syncFrom == "" && !auto && !twoWay {
+		syncFrom = "canonical"
+		if !hasFiles(canonical) {
+			// Start the canonical file off with the places there are
+			// rather than emptying every backend
+			syncFrom = "gtk"
+			sync.say(tr("Creating the canonical places file from gtk\n"))
+		}
+	}
+	sync.CloudFolders = cloudFolders
+	sync.SSHHosts = sshHosts
+	sync.AllowUnsafe = allowUnsafe
+	sync.Prune = prune
+	sync.Merge = merge
+	sync.Safe = safe
+	sync.DryRun = dryRun
+	for class, policy := range retries {
+		sync.Retry[class] = policy
+	}
+	if dryRun && (twoWay || watch) {
+		return errors.New(tr("--dry-run cannot be combined with --two-way or --watch"))
 	}
+	if len(syncTo) > 0 && twoWay {
+		return errors.New(tr("--sync-to cannot be combined with --two-way"))
+	}
+	switch {
+	case conflictMode != "auto" && conflictMode != "edit":
+		return errors.New(tr("unknown conflict mode %s, expected auto or edit", conflictMode))
+	case conflictMode == "edit" && (!twoWay || watch):
+		return errors.New(tr("--conflict edit needs --two-way, without --watch"))
+	}
+	sync.EditConflicts = conflictMode == "edit"
+	for _, app := range gtkApps {
+		sync.AddBackend(app)
+	}
+	for _, prefix := range winePrefixes {
+		sync.AddBackend(prefix)
+	}
+	if appImages || len(appImageDirs) > 0 {
+		backends, err := AppImageBackends(appImageDirs)
+		if err != nil {
+			return errors.New(tr("Failed to scan for AppImages: %v", err))
+		}
+		for _, backend := range backends {
+			sync.AddBackend(backend)
+		}
+	}
+	if scripts {
+		backends, err := ScriptsBackends()
+		if err != nil {
+			return errors.New(tr("Failed to find file managers: %v", err))
+		}
+		for _, backend := range backends {
+			sync.AddBackend(backend)
+		}
+	}
+	if launchers {
+		sync.AddBackend(&LaunchersBackend{})
+	}
+	if len(includes)+len(excludes) > 0 {
+		// The flags were checked when parsed
+		sync.Filter, _ = NewPlaceFilter(append(config.Include, includes...), append(config.Exclude, excludes...))
+	}
+	for _, name := range syncTo {
+		name = strings.ToLower(name)
+		if _, ok := sync.backends[name]; !ok {
+			return errors.New(tr("Unknown backend: %s", name))
+		}
+		sync.SyncTo = append(sync.SyncTo, name)
+	}
+
+	mode := "from"
+	run := func() error {
+		sync.say(tr("Running sync from %s backend\n", syncFrom))
+		return sync.SyncFrom(syncFrom)
+	}
+	watched := sync.Backends()
+	switch {
+	case twoWay:
+		mode = "two-way"
+		run = func() error {
+			sync.say(tr("Running two-way sync\n"))
+			conflicts, err := sync.SyncBidirectional()
+			for _, conflict := range conflicts {
+				if sync.Report != nil {
+					sync.Report.Conflicts = append(sync.Report.Conflicts, conflict.String())
+				} else {
+					log.Print(tr("Conflict: %s", conflict))
+				}
+			}
+			return err
+		}
+	case auto || syncFrom == "":
+		mode = "auto"
+		run = sync.SyncAuto
+	default:
+		syncFrom = strings.ToLower(syncFrom)
+		source, ok := sync.backends[syncFrom]
+		if !ok {
+			return errors.New(tr("Unknown backend: %s", syncFrom))
+		}
+		watched = []BookmarkSyncBackend{source}
+	}
+
+	if jsonOutput {
+		log.SetFlags(0)
+		log.SetOutput(&reportLog{bs: sync})
+		inner := run
+		run = func() error {
+			report := &SyncReport{Mode: mode, DryRun: dryRun}
+			if mode == "from" {
+				report.Source = syncFrom
+			}
+			return sync.withReport(report, inner)
+		}
+	}
+	if !dryRun {
+		inner := run
+		run = func() error {
+			err := inner()
+			recordOutcome(err)
+			return err
+		}
+	}
+	// Another sync, like the daemon's, reading between the reads and
+	// writes of this one would undo them
+	run = withRunLock(run)
+
+	if err := sync.ExpireTemporary(); err != nil {
+		log.Print(tr("Warning: failed to expire temporary bookmarks: %v", err))
+	}
+	if err := run(); err != nil {
+		return fmt.Errorf("%s: %w", tr("Sync failed"), err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if !watch {
+		deferred := sync.Deferred()
+		if len(deferred) == 0 {
+			return nil
+		}
+		// Like at login, before the keyring is unlocked: sync them too
+		// once it is
+		sync.say(tr("Waiting for the keyring to be unlocked to sync %s\n", strings.Join(deferred, ", ")))
+		if !waitForKeyring(ctx, keyringWait) {
+			return errors.New(tr("the keyring stayed locked: %s not synced", strings.Join(deferred, ", ")))
+		}
+		if err := run(); err != nil {
+			return fmt.Errorf("%s: %w", tr("Sync failed"), err)
+		}
+		return nil
+	}
+	var idle *idleTimer
+	if idleExit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		idle = newIdleTimer(idleExit, func() {
+			log.Print(tr("No activity for %s, exiting", idleExit))
+			cancel()
+		})
+		defer idle.Stop()
+		run = idle.Wrap(run)
+	}
+	sync.Stats = newDaemonStats()
+	run = sync.Stats.Wrap(run)
+
+	service, err := ExportDBus(sync, run, idle)
+	if err != nil {
+		log.Print(tr("Warning: D-Bus interface unavailable: %v", err))
+	} else {
+		defer service.Close()
+		run = service.Locked(run)
+	}
+
+	sync.say(tr("Watching for bookmark changes\n"))
+	housekeeping := func() {
+		if err := sync.ExpireTemporary(); err != nil {
+			log.Print(tr("Warning: failed to expire temporary bookmarks: %v", err))
+		}
+		// Backends left for the keyring are synced once it is unlocked
+		if len(sync.Deferred()) > 0 && !keyringLocked() {
+			if err := run(); err != nil {
+				log.Print(tr("Warning: sync failed: %v", err))
+			}
+		}
+	}
+	triggers := fileManagerTriggers(ctx)
+	if err := Watch(ctx, watched, triggers, debounce, maxDelay, run, housekeeping, idle); err != nil {
+		return errors.New(tr("Watch failed: %v", err))
+	}
+	return nil
 }
 
 // Place represents a bookmark entry
-type Place struct {
-	Label  string
-	Target string
-}
+type Place = bookmark.Place
 
 // BookmarkSyncBackend defines the interface for bookmark backends
-type BookmarkSyncBackend interface {
-	GetPlaces() ([]Place, error)
-	Replace(places []Place) error
-	Name() string
+type BookmarkSyncBackend = bookmark.Backend
+
+// Capabilities describe what a backend's file format can store
+type Capabilities = bookmark.Capabilities
+
+// capabilityReporter is implemented by backends that can't store
+// everything a Place holds
+type capabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// capabilitiesOf returns the capabilities of a backend, assuming full
+// fidelity for backends that don't report any
+func capabilitiesOf(backend BookmarkSyncBackend) Capabilities {
+	if reporter, ok := backend.(capabilityReporter); ok {
+		return reporter.Capabilities()
+	}
+	return Capabilities{Labels: true, Remote: true}
+}
+
+// unwrapped finds the backend of type T behind the wrappers AddBackend puts
+// around a backend
+func unwrapped[T any](backend BookmarkSyncBackend) (T, bool) {
+	for {
+		if b, ok := backend.(T); ok {
+			return b, true
+		}
+		wrapper, ok := backend.(interface{ Unwrap() BookmarkSyncBackend })
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		backend = wrapper.Unwrap()
+	}
 }
 
 // BookmarkSync manages syncing between backends
 type BookmarkSync struct {
 	backends map[string]BookmarkSyncBackend
+	// order lists backend names in registration order, which is also
+	// their priority when two-way sync has to pick between conflicting edits
+	order []string
+
+	// CloudFolders adds detected cloud drive folders to the synced places
+	CloudFolders bool
+	// SSHHosts are ~/.ssh/config hosts to generate sftp:// places for
+	SSHHosts []string
+	// SyncTo limits the destinations of SyncFrom to these backends, all
+	// others when empty
+	SyncTo []string
+	// Routes are the destinations SyncFrom may write for each source
+	// backend, any when nil
+	Routes map[string][]string
+	// ReadOnly are backends no sync writes to
+	ReadOnly []string
+	// WriteOnly are backends no sync reads places from
+	WriteOnly []string
+	// Filter decides which places are copied between backends
+	Filter *PlaceFilter
+	// Prune drops places whose folder no longer exists
+	Prune bool
+	// AllowUnsafe copies places with suspicious targets instead of
+	// ignoring them
+	AllowUnsafe bool
+	// Merge unions places into destinations instead of replacing them
+	Merge bool
+	// Safe refuses to overwrite backends edited outside bookmarksync since
+	// the last sync
+	Safe bool
+	// DryRun prints what a sync would change instead of writing it
+	DryRun bool
+	// EditConflicts has two-way syncs open their conflicts in the user's
+	// editor instead of resolving them
+	EditConflicts bool
+	// deferred are the backends the last sync left until the keyring is
+	// unlocked
+	deferred struct {
+		sync.Mutex
+		names []string
+	}
+	// Retry holds the retry policy of every transient error class
+	Retry map[string]RetryPolicy
+	// Report collects the outcome of the running sync with --json
+	Report *SyncReport
+	// Stats counts the syncs of a daemon
+	Stats *daemonStats
+
+	// origins are the backends the places of this run were read from, by
+	// normalized target
+	origins map[string]string
+	// vaulted are the places of this run held back in closed vaults
+	vaulted []Place
 }
 
 // NewBookmarkSync creates a new BookmarkSync instance
 func NewBookmarkSync() *BookmarkSync {
-	return &BookmarkSync{
-		backends: map[string]BookmarkSyncBackend{
-			"gtk": &GTKBackend{},
-			"kde": &KDEBackend{},
-			"qt":  &QtBackend{},
-		},
+	bs := &BookmarkSync{
+		backends:  map[string]BookmarkSyncBackend{},
+		Retry:     make(map[string]RetryPolicy, len(defaultRetryPolicies)),
+		Routes:    config.Routes,
+		ReadOnly:  config.ReadOnly,
+		WriteOnly: config.WriteOnly,
+	}
+	for class, policy := range defaultRetryPolicies {
+		bs.Retry[class] = policy
+	}
+	// LoadConfig checked the patterns
+	bs.Filter, _ = NewPlaceFilter(config.Include, config.Exclude)
+	if config.Canonical || config.Paths["canonical"] != "" {
+		// The source of truth comes first, which also makes it win
+		// conflicting edits in two-way syncs
+		bs.AddBackend(&CanonicalBackend{Path: config.Paths["canonical"]})
+	}
+	for _, backend := range []BookmarkSyncBackend{
+		&GTKBackend{Path: config.Paths["gtk"]},
+		&GTK2Backend{Path: config.Paths["gtk2"]},
+		&KDEBackend{Path: config.Paths["kde"]},
+		&QtBackend{Path: config.Paths["qt"]},
+	} {
+		// Without GTK 2 there is no one to read its file
+		if installed(backend) {
+			bs.AddBackend(backend)
+		}
+	}
+	for _, backend := range appBackends() {
+		// An application's places are only synced when asked for, and
+		// where it is installed
+		if slices.ContainsFunc(config.Apps, func(app string) bool { return strings.EqualFold(app, backend.Name()) }) && installed(backend) {
+			bs.AddBackend(backend)
+		}
+	}
+	if config.GitRemote != "" {
+		bs.AddBackend(&GitBackend{Remote: config.GitRemote, Branch: config.GitBranch})
+	}
+	if config.Remote != "" {
+		bs.AddBackend(&RemoteBackend{URL: config.Remote})
+	}
+	if config.WebDAVURL != "" {
+		bs.AddBackend(&WebDAVBackend{URL: config.WebDAVURL, User: config.WebDAVUser, PasswordCommand: config.WebDAVPasswordCommand})
 	}
+	return bs
+}
+
+// appBackends returns the backends of applications, which apps in the
+// configuration adds
+func appBackends() []BookmarkSyncBackend {
+	return []BookmarkSyncBackend{&LibreOfficeBackend{}, &BlenderBackend{}}
+}
+
+// AddBackend registers an additional backend under its name, unless the
+// configuration disables it. Its reads and writes are retried on transient
+// errors according to bs.Retry.
+func (bs *BookmarkSync) AddBackend(backend BookmarkSyncBackend) {
+	name := backend.Name()
+	if !config.enabled(name) {
+		return
+	}
+	backend = &lockedBackend{backend}
+	if !capabilitiesOf(backend).Labels {
+		backend = &linksBackend{backend}
+	}
+	backend = &rewriteBackend{backend}
+	backend = &quarantineBackend{backend}
+	backend = &pinnedBackend{&orderedBackend{&backupBackend{&retryBackend{BookmarkSyncBackend: backend, policies: bs.Retry}}}}
+	if _, exists := bs.backends[name]; !exists {
+		bs.order = append(bs.order, name)
+	}
+	bs.backends[name] = backend
+}
+
+// Backends returns the registered backends in registration order
+func (bs *BookmarkSync) Backends() []BookmarkSyncBackend {
+	backends := make([]BookmarkSyncBackend, 0, len(bs.order))
+	for _, name := range bs.order {
+		backends = append(backends, bs.backends[name])
+	}
+	return backends
+}
+
+// ReplaceAll writes places to every backend
+func (bs *BookmarkSync) ReplaceAll(places []Place) error {
+	if err := bs.checkWritable(bs.Backends()); err != nil {
+		return err
+	}
+	var destinations []BookmarkSyncBackend
+	for _, backend := range bs.Backends() {
+		if bs.writable(backend.Name()) {
+			destinations = append(destinations, backend)
+		}
+	}
+	failures := &backendErrors{}
+	err := inTransaction(bs.Backends(), func() error {
+		bs.writeBackends(destinations, failures, func(backend BookmarkSyncBackend) (string, error) {
+			return "written", backend.Replace(places)
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := bs.recordSync(); err != nil {
+		return err
+	}
+	return failures.err()
+}
+
+// parallelWrites is how many backends are written at the same time
+const parallelWrites = 8
+
+// writeBackends runs write for every backend, parallelWrites at a time,
+// then notes what came of each in failures and the report, in the order of
+// backends. write returns whether it wrote the backend or left it
+// "unchanged".
+func (bs *BookmarkSync) writeBackends(backends []BookmarkSyncBackend, failures *backendErrors, write func(BookmarkSyncBackend) (string, error)) {
+	statuses := make([]string, len(backends))
+	errs := make([]error, len(backends))
+	var group errgroup.Group
+	group.SetLimit(parallelWrites)
+	for i, backend := range backends {
+		group.Go(func() error {
+			statuses[i], errs[i] = write(backend)
+			return nil
+		})
+	}
+	group.Wait()
+	var deferred []string
+	for i, backend := range backends {
+		if errors.Is(errs[i], errKeyringLocked) {
+			// Written once the keyring is unlocked
+			statuses[i], errs[i] = "deferred", nil
+			deferred = append(deferred, backend.Name())
+			bs.say(tr("Leaving %s until the keyring is unlocked\n", backend.Name()))
+		}
+		failures.note(backend.Name(), errs[i])
+		bs.noteBackend(backend.Name(), statuses[i], errs[i])
+	}
+	bs.setDeferred(deferred)
 }
 
 // SyncFrom syncs bookmarks from the specified backend to all others
@@ -91,164 +620,439 @@ func (bs *BookmarkSync) SyncFrom(backendName string) error {
 	if !exists {
 		return fmt.Errorf("unknown backend: %s", backendName)
 	}
+	if !bs.readable(backendName) {
+		return fmt.Errorf("refusing to sync from %s: it is write-only", backendName)
+	}
 
-	places, err := sourceBackend.GetPlaces()
+	places, err := bs.readSource(sourceBackend)
 	if err != nil {
-		return fmt.Errorf("failed to get places from %s: %v", backendName, err)
+		return err
 	}
+	places = bs.pruned(places)
 
-	for name, backend := range bs.backends {
-		if name != backendName {
-			if err := backend.Replace(places); err != nil {
-				log.Printf("Warning: failed to sync to %s: %v", name, err)
+	if bs.Merge {
+		// Merging would copy a place deleted elsewhere back from a
+		// backend that still has it
+		state, err := LoadState()
+		if err != nil {
+			return fmt.Errorf("failed to load state: %v", err)
+		}
+		bs.updateTombstones(state)
+		if err := state.Save(); err != nil {
+			return fmt.Errorf("failed to save state: %v", err)
+		}
+		places = state.withoutBuried(places, nil)
+	}
+
+	if !bs.feeds(backendName) {
+		return fmt.Errorf("the routing table has no routes from %s", backendName)
+	}
+	var destinations []BookmarkSyncBackend
+	for _, backend := range bs.Backends() {
+		name := backend.Name()
+		if name == backendName || !bs.routed(backendName, name) {
+			continue
+		}
+		if len(bs.SyncTo) > 0 && !slices.Contains(bs.SyncTo, name) {
+			continue
+		}
+		if !bs.writable(name) {
+			if len(bs.SyncTo) > 0 {
+				return fmt.Errorf("refusing to sync to %s: it is read-only", name)
 			}
+			continue
 		}
+		destinations = append(destinations, backend)
 	}
 
-	return nil
+	if bs.Safe && !bs.Merge {
+		if err := bs.checkUnchanged(destinations); err != nil {
+			return err
+		}
+	}
+
+	if bs.DryRun {
+		bs.printPlan(places, destinations)
+		return nil
+	}
+	if err := bs.checkWritable(destinations); err != nil {
+		// Say what the sync would have done instead of failing half way
+		bs.say(tr("Nothing was written as %v. A sync would change:\n", err))
+		bs.printPlan(places, destinations)
+		return err
+	}
+
+	failures := &backendErrors{}
+	err = inTransaction(destinations, func() error {
+		bs.writeBackends(destinations, failures, func(backend BookmarkSyncBackend) (string, error) {
+			if bs.Merge {
+				return "written", backend.Merge(places)
+			}
+			return "written", bs.replaceKeeping(backend, places)
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := bs.recordSync(); err != nil {
+		log.Print(tr("Warning: failed to save state: %v", err))
+	}
+	return failures.err()
+}
+
+// readSource returns the places a sync from source copies to other
+// backends
+func (bs *BookmarkSync) readSource(source BookmarkSyncBackend) ([]Place, error) {
+	name := source.Name()
+	places, err := source.GetPlaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get places from %s: %v", name, err)
+	}
+	places = dedupePlaces(withPortalPathsResolved(bs.sanitizePlaces(name, places)))
+	bs.noteOrigins(name, places)
+
+	places, err = bs.withGeneratedPlaces(places)
+	if err != nil {
+		return nil, err
+	}
+	places, err = bs.withVaults(places)
+	if err != nil {
+		return nil, err
+	}
+	return bs.Filter.Apply(places), nil
+}
+
+// replaceKeeping replaces the places of a destination, keeping the ones
+// the filter doesn't let other backends have
+func (bs *BookmarkSync) replaceKeeping(backend BookmarkSyncBackend, places []Place) error {
+	if bs.Filter != nil {
+		current, err := backend.GetPlaces()
+		if err != nil {
+			return err
+		}
+		places = bs.Filter.Held(current, places)
+	}
+	return backend.Replace(places)
+}
+
+// feeds reports whether source may feed any backend: it isn't write-only
+// and the routing table has routes from it
+func (bs *BookmarkSync) feeds(source string) bool {
+	return bs.readable(source) && (bs.Routes == nil || len(bs.Routes[source]) > 0)
+}
+
+// readable reports whether places may be synced from the backend
+func (bs *BookmarkSync) readable(name string) bool {
+	return !slices.ContainsFunc(bs.WriteOnly, func(s string) bool { return strings.EqualFold(s, name) })
+}
+
+// writable reports whether syncs may write to the backend
+func (bs *BookmarkSync) writable(name string) bool {
+	return !slices.ContainsFunc(bs.ReadOnly, func(s string) bool { return strings.EqualFold(s, name) })
+}
+
+// routed reports whether the routing table lets source be synced into
+// destination
+func (bs *BookmarkSync) routed(source, destination string) bool {
+	if bs.Routes == nil {
+		return true
+	}
+	for _, name := range bs.Routes[source] {
+		if strings.EqualFold(name, destination) {
+			return true
+		}
+	}
+	return false
+}
+
+// withGeneratedPlaces adds the places bookmarksync generates itself (cloud
+// folders, ssh hosts) to a synced list
+func (bs *BookmarkSync) withGeneratedPlaces(places []Place) ([]Place, error) {
+	if bs.CloudFolders {
+		places = appendMissingPlaces(places, CloudPlaces())
+	}
+
+	if len(bs.SSHHosts) > 0 {
+		var err error
+		places, err = withSSHPlaces(places, bs.SSHHosts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ssh hosts: %v", err)
+		}
+	}
+
+	return places, nil
 }
 
 // GTKBackend implements BookmarkSyncBackend for GTK bookmarks
-type GTKBackend struct{}
+type GTKBackend struct {
+	// BackendName overrides the name for app-specific bookmark files
+	BackendName string
+	// Path overrides the default $XDG_CONFIG_HOME/gtk-3.0/bookmarks location
+	Path string
+}
 
 func (g *GTKBackend) Name() string {
+	if g.BackendName != "" {
+		return g.BackendName
+	}
 	return "gtk"
 }
 
+// bookmarksPath returns the bookmarks file this backend reads and writes
+func (g *GTKBackend) bookmarksPath() (string, error) {
+	if g.Path != "" {
+		return g.Path, nil
+	}
+	configHome, err := xdgConfigHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configHome, "gtk-3.0", "bookmarks"), nil
+}
+
+func (g *GTKBackend) Files() ([]string, error) {
+	bookmarksPath, err := g.bookmarksPath()
+	if err != nil {
+		return nil, err
+	}
+	return []string{bookmarksPath}, nil
+}
+
 func (g *GTKBackend) GetPlaces() ([]Place, error) {
-	homeDir, err := os.UserHomeDir()
+	bookmarksPath, err := g.bookmarksPath()
 	if err != nil {
 		return nil, err
 	}
 
-	bookmarksPath := filepath.Join(homeDir, ".config", "gtk-3.0", "bookmarks")
-	file, err := os.Open(bookmarksPath)
+	data, err := os.ReadFile(bookmarksPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return []Place{}, nil
 		}
 		return nil, err
 	}
-	defer file.Close()
+	if err := checkText(bookmarksPath, data); err != nil {
+		return nil, err
+	}
 
 	var places []Place
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" {
 			continue
 		}
-
-		parts := strings.SplitN(line, " ", 2)
-		target := parts[0]
-		label := ""
-		if len(parts) > 1 {
-			label = parts[1]
-		} else {
-			// Extract label from URL path
-			if u, err := url.Parse(target); err == nil {
-				label = filepath.Base(u.Path)
-				if decoded, err := url.QueryUnescape(label); err == nil {
-					label = decoded
-				}
-			}
+		// Lines that can't be repaired are left for audit-gtk to report
+		if place, _, ok := parseGTKLine(line); ok {
+			places = append(places, place)
 		}
-		places = append(places, Place{Label: label, Target: target})
 	}
 
 	return places, scanner.Err()
 }
 
+func (g *GTKBackend) Merge(places []Place) error {
+	return mergeInto(g, places)
+}
+
 func (g *GTKBackend) Replace(places []Place) error {
-	homeDir, err := os.UserHomeDir()
+	bookmarksPath, err := g.bookmarksPath()
 	if err != nil {
 		return err
 	}
-
-	configDir := filepath.Join(homeDir, ".config", "gtk-3.0")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	data, err := g.Render(places)
+	if err != nil {
 		return err
 	}
 
-	bookmarksPath := filepath.Join(configDir, "bookmarks")
-	file, err := os.Create(bookmarksPath)
-	if err != nil {
+	if err := os.MkdirAll(filepath.Dir(bookmarksPath), 0755); err != nil {
 		return err
 	}
-	defer file.Close()
+	return replaceFile(bookmarksPath, data)
+}
 
+func (g *GTKBackend) Render(places []Place) ([]byte, error) {
+	var buf bytes.Buffer
 	for _, place := range places {
-		if place.Label != "" {
-			fmt.Fprintf(file, "%s %s\n", place.Target, place.Label)
-		} else {
-			fmt.Fprintf(file, "%s\n", place.Target)
-		}
+		fmt.Fprintln(&buf, formatGTKLine(place))
 	}
-
-	return nil
+	return buf.Bytes(), nil
 }
 
 // KDEBackend implements BookmarkSyncBackend for KDE bookmarks
-type KDEBackend struct{}
+type KDEBackend struct {
+	// BackendName overrides the name for additional places files
+	BackendName string
+	// Path overrides the default $XDG_DATA_HOME/user-places.xbel location
+	Path string
+}
 
 func (k *KDEBackend) Name() string {
+	if k.BackendName != "" {
+		return k.BackendName
+	}
 	return "kde"
 }
 
+// xbelPath returns the places file this backend reads and writes
+func (k *KDEBackend) xbelPath() (string, error) {
+	if k.Path != "" {
+		return k.Path, nil
+	}
+	dataHome, err := xdgDataHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataHome, "user-places.xbel"), nil
+}
+
+func (k *KDEBackend) Files() ([]string, error) {
+	xbelPath, err := k.xbelPath()
+	if err != nil {
+		return nil, err
+	}
+	return []string{xbelPath}, nil
+}
+
 type XBEL struct {
-	XMLName   xml.Name   `xml:"xbel"`
+	XMLName xml.Name `xml:"xbel"`
+	// Attrs are the root's attributes, which declare the namespaces of
+	// the metadata kept as read
+	Attrs     []xml.Attr `xml:",any,attr"`
 	Bookmarks []Bookmark `xml:"bookmark"`
 }
 
+// keepNamespaces turns the namespace declarations among the root's
+// attributes, which the decoder resolves, back into plain attributes the
+// encoder writes as they were
+func (x *XBEL) keepNamespaces() {
+	for i, attr := range x.Attrs {
+		if attr.Name.Space == "xmlns" {
+			x.Attrs[i].Name = xml.Name{Local: "xmlns:" + attr.Name.Local}
+		}
+	}
+}
+
 type Bookmark struct {
 	Href  string `xml:"href,attr"`
 	Title string `xml:"title"`
 	Info  Info   `xml:"info"`
 }
 
+// isSystem reports whether KDE itself put the bookmark there
+func (b Bookmark) isSystem() bool {
+	for _, metadata := range b.Info.Metadata {
+		if metadata.IsSystemItem != nil {
+			return true
+		}
+	}
+	return false
+}
+
 type Info struct {
 	Metadata []Metadata `xml:"metadata"`
+	// raw is the info as read, written back unchanged so that what KDE
+	// keeps there, like IDs and icons, survives a rewrite
+	raw []byte
+}
+
+func (i *Info) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var info struct {
+		Metadata []Metadata `xml:"metadata"`
+		Raw      []byte     `xml:",innerxml"`
+	}
+	if err := d.DecodeElement(&info, &start); err != nil {
+		return err
+	}
+	i.Metadata, i.raw = info.Metadata, info.Raw
+	return nil
+}
+
+func (i Info) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if i.raw != nil {
+		return e.EncodeElement(struct {
+			Raw []byte `xml:",innerxml"`
+		}{i.raw}, start)
+	}
+	return e.EncodeElement(struct {
+		Metadata []Metadata `xml:"metadata"`
+	}{i.Metadata}, start)
+}
+
+// id returns KDE's identifier of the bookmark, "" when it has none
+func (i Info) id() string {
+	for _, metadata := range i.Metadata {
+		if metadata.ID != "" {
+			return metadata.ID
+		}
+	}
+	return ""
 }
 
 type Metadata struct {
 	Owner        string        `xml:"owner,attr"`
+	ID           string        `xml:"ID,omitempty"`
 	IsSystemItem *IsSystemItem `xml:"isSystemItem"`
 }
 
 type IsSystemItem struct{}
 
-func (k *KDEBackend) GetPlaces() ([]Place, error) {
-	homeDir, err := os.UserHomeDir()
+// readXBEL reads the places file at xbelPath, which is empty when there is
+// none yet. A file that can't be parsed is a corruptError, never empty:
+// rewriting it as if it were would lose KDE's system items.
+func readXBEL(xbelPath string) (XBEL, error) {
+	var xbel XBEL
+	file, err := os.Open(xbelPath)
+	if os.IsNotExist(err) {
+		return xbel, nil
+	}
 	if err != nil {
-		return nil, err
+		return xbel, err
 	}
+	defer file.Close()
 
-	xbelPath := filepath.Join(homeDir, ".local", "share", "user-places.xbel")
-	file, err := os.Open(xbelPath)
+	if err := xml.NewDecoder(file).Decode(&xbel); err != nil {
+		return XBEL{}, &corruptError{File: xbelPath, Err: err}
+	}
+	return xbel, nil
+}
+
+// NativeIDs returns the IDs KDE gave the places, by normalized target
+func (k *KDEBackend) NativeIDs() (map[string]string, error) {
+	xbelPath, err := k.xbelPath()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []Place{}, nil
+		return nil, err
+	}
+	xbel, err := readXBEL(xbelPath)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]string)
+	for _, bookmark := range xbel.Bookmarks {
+		if id := bookmark.Info.id(); id != "" && !bookmark.isSystem() {
+			ids[normalizeTarget(bookmark.Href)] = id
 		}
+	}
+	return ids, nil
+}
+
+func (k *KDEBackend) GetPlaces() ([]Place, error) {
+	xbelPath, err := k.xbelPath()
+	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	var xbel XBEL
-	if err := xml.NewDecoder(file).Decode(&xbel); err != nil {
+	xbel, err := readXBEL(xbelPath)
+	if err != nil {
 		return nil, err
 	}
 
 	var places []Place
 	for _, bookmark := range xbel.Bookmarks {
 		// Skip system items
-		isSystem := false
-		for _, metadata := range bookmark.Info.Metadata {
-			if metadata.IsSystemItem != nil {
-				isSystem = true
-				break
-			}
-		}
-		if !isSystem {
+		if !bookmark.isSystem() {
 			places = append(places, Place{
 				Label:  bookmark.Title,
 				Target: bookmark.Href,
@@ -259,91 +1063,167 @@ func (k *KDEBackend) GetPlaces() ([]Place, error) {
 	return places, nil
 }
 
+func (k *KDEBackend) Merge(places []Place) error {
+	return mergeInto(k, places)
+}
+
 func (k *KDEBackend) Replace(places []Place) error {
-	homeDir, err := os.UserHomeDir()
+	xbelPath, err := k.xbelPath()
 	if err != nil {
 		return err
 	}
-
-	// First, read existing file to preserve system items
-	xbelPath := filepath.Join(homeDir, ".local", "share", "user-places.xbel")
-	var existingXBEL XBEL
-	
-	if file, err := os.Open(xbelPath); err == nil {
-		xml.NewDecoder(file).Decode(&existingXBEL)
-		file.Close()
+	data, err := k.Render(places)
+	if err != nil {
+		return err
 	}
 
 	// Create directory if it doesn't exist
-	shareDir := filepath.Join(homeDir, ".local", "share")
-	if err := os.MkdirAll(shareDir, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(xbelPath), 0755); err != nil {
 		return err
 	}
+	return replaceFile(xbelPath, data)
+}
 
-	// Keep system items, replace user items
+func (k *KDEBackend) Render(places []Place) ([]byte, error) {
+	// First, read existing file to preserve system items
+	xbelPath, err := k.xbelPath()
+	if err != nil {
+		return nil, err
+	}
+	existingXBEL, err := readXBEL(xbelPath)
+	if err != nil {
+		return nil, err
+	}
+	existingXBEL.keepNamespaces()
+
+	// Keep system items, and the records of the places that stay, found
+	// by the ID they had at the last sync or else by target, so that KDE's
+	// IDs and icons aren't lost to a rewrite
+	natives := mappedNativeIDs(k.Name())
+	byID := make(map[string]int)
+	byTarget := make(map[string]int)
 	var newBookmarks []Bookmark
-	for _, bookmark := range existingXBEL.Bookmarks {
-		isSystem := false
-		for _, metadata := range bookmark.Info.Metadata {
-			if metadata.IsSystemItem != nil {
-				isSystem = true
-				break
-			}
-		}
-		if isSystem {
+	for i, bookmark := range existingXBEL.Bookmarks {
+		if bookmark.isSystem() {
 			newBookmarks = append(newBookmarks, bookmark)
+			continue
+		}
+		if id := bookmark.Info.id(); id != "" {
+			byID[id] = i
+		}
+		if _, ok := byTarget[normalizeTarget(bookmark.Href)]; !ok {
+			byTarget[normalizeTarget(bookmark.Href)] = i
 		}
 	}
 
-	// Add new user places
-	for _, place := range places {
+	used := make(map[int]bool)
+	stamp := time.Now().Unix()
+	for n, place := range places {
+		key := normalizeTarget(place.Target)
+		i, ok := byID[natives[key]]
+		if !ok {
+			i, ok = byTarget[key]
+		}
+		if ok && !used[i] {
+			used[i] = true
+			bookmark := existingXBEL.Bookmarks[i]
+			bookmark.Href, bookmark.Title = place.Target, place.Label
+			newBookmarks = append(newBookmarks, bookmark)
+			continue
+		}
 		newBookmarks = append(newBookmarks, Bookmark{
 			Href:  place.Target,
 			Title: place.Label,
 			Info: Info{
 				Metadata: []Metadata{{
 					Owner: "http://www.kde.org",
+					// KDE's own IDs are a time and a counter
+					ID: fmt.Sprintf("%d/%d", stamp, n),
 				}},
 			},
 		})
 	}
 
 	xbel := XBEL{
+		Attrs:     existingXBEL.Attrs,
 		Bookmarks: newBookmarks,
 	}
 
-	file, err := os.Create(xbelPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	encoder := xml.NewEncoder(file)
+	var buf bytes.Buffer
+	encoder := xml.NewEncoder(&buf)
 	encoder.Indent("", "  ")
-	file.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
-	file.WriteString(`<!DOCTYPE xbel PUBLIC "+//IDN python.org//DTD XML Bookmark Exchange Language 1.0//EN//XML" "http://www.python.org/topics/xml/dtds/xbel-1.0.dtd">` + "\n")
-	return encoder.Encode(&xbel)
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString(`<!DOCTYPE xbel PUBLIC "+//IDN python.org//DTD XML Bookmark Exchange Language 1.0//EN//XML" "http://www.python.org/topics/xml/dtds/xbel-1.0.dtd">` + "\n")
+	if err := encoder.Encode(&xbel); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 // QtBackend implements BookmarkSyncBackend for Qt bookmarks
-type QtBackend struct{}
+type QtBackend struct {
+	// BackendName overrides the name for additional config files
+	BackendName string
+	// Path overrides the default $XDG_CONFIG_HOME/QtProject.conf location
+	Path string
+}
 
 func (q *QtBackend) Name() string {
+	if q.BackendName != "" {
+		return q.BackendName
+	}
 	return "qt"
 }
 
+func (q *QtBackend) Capabilities() Capabilities {
+	return Capabilities{Labels: false, Remote: false}
+}
+
+// configPath returns the QtProject.conf this backend reads and writes
+func (q *QtBackend) configPath() (string, error) {
+	if q.Path != "" {
+		return q.Path, nil
+	}
+	configHome, err := xdgConfigHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configHome, "QtProject.conf"), nil
+}
+
+func (q *QtBackend) Files() ([]string, error) {
+	qtConfigPath, err := q.configPath()
+	if err != nil {
+		return nil, err
+	}
+	return []string{qtConfigPath}, nil
+}
+
+// loadQtConfig loads the QtProject.conf at path, which is empty when there
+// is none yet. A file that can't be parsed is a corruptError.
+func loadQtConfig(path string) (*ini.File, error) {
+	cfg, err := ini.Load(path)
+	if err == nil {
+		return cfg, nil
+	}
+	if os.IsNotExist(err) {
+		return ini.Empty(), nil
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return nil, err
+	}
+	return nil, &corruptError{File: path, Err: err}
+}
+
 func (q *QtBackend) GetPlaces() ([]Place, error) {
-	homeDir, err := os.UserHomeDir()
+	qtConfigPath, err := q.configPath()
 	if err != nil {
 		return nil, err
 	}
 
-	qtConfigPath := filepath.Join(homeDir, ".config", "QtProject.conf")
-	cfg, err := ini.Load(qtConfigPath)
+	cfg, err := loadQtConfig(qtConfigPath)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []Place{}, nil
-		}
 		return nil, err
 	}
 
@@ -369,32 +1249,47 @@ func (q *QtBackend) GetPlaces() ([]Place, error) {
 	return places, nil
 }
 
+func (q *QtBackend) Merge(places []Place) error {
+	return mergeInto(q, places)
+}
+
 func (q *QtBackend) Replace(places []Place) error {
-	homeDir, err := os.UserHomeDir()
+	qtConfigPath, err := q.configPath()
+	if err != nil {
+		return err
+	}
+	data, err := q.Render(places)
 	if err != nil {
 		return err
 	}
 
-	qtConfigPath := filepath.Join(homeDir, ".config", "QtProject.conf")
-	
-	// Load existing config or create new one
-	var cfg *ini.File
-	if _, err := os.Stat(qtConfigPath); os.IsNotExist(err) {
-		cfg = ini.Empty()
-	} else {
-		cfg, err = ini.Load(qtConfigPath)
-		if err != nil {
-			return err
-		}
+	// Create config directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(qtConfigPath), 0755); err != nil {
+		return err
+	}
+	return replaceFile(qtConfigPath, data)
+}
+
+func (q *QtBackend) Render(places []Place) ([]byte, error) {
+	qtConfigPath, err := q.configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	// The rest of the file is kept as it is
+	cfg, err := loadQtConfig(qtConfigPath)
+	if err != nil {
+		return nil, err
 	}
 
 	// Filter places to only include local file:// URLs
 	var shortcuts []string
 	for _, place := range places {
 		if strings.HasPrefix(place.Target, "file://") {
-			// Remove file:// prefix and URL decode
+			// Remove file:// prefix and URL decode; a + is a plus in
+			// paths, which link names made from labels like C++ have
 			path := strings.TrimPrefix(place.Target, "file://")
-			if decoded, err := url.QueryUnescape(path); err == nil {
+			if decoded, err := url.PathUnescape(path); err == nil {
 				shortcuts = append(shortcuts, decoded)
 			} else {
 				shortcuts = append(shortcuts, path)
@@ -405,11 +1300,9 @@ func (q *QtBackend) Replace(places []Place) error {
 	fileDialogSection := cfg.Section("FileDialog")
 	fileDialogSection.Key("shortcuts").SetValue(strings.Join(shortcuts, ", "))
 
-	// Create config directory if it doesn't exist
-	configDir := filepath.Join(homeDir, ".config")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return err
+	var buf bytes.Buffer
+	if _, err := cfg.WriteTo(&buf); err != nil {
+		return nil, err
 	}
-
-	return cfg.SaveTo(qtConfigPath)
-}
\ No newline at end of file
+	return buf.Bytes(), nil
+}
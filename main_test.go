@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testHome gives the test an empty home directory, with the XDG base
+// directories in it, and the default configuration without a policy
+func testHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, env := range []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME", "XDG_CACHE_HOME", "XDG_RUNTIME_DIR"} {
+		t.Setenv(env, "")
+	}
+	saved, savedPolicy := config, policyFile
+	config = Config{}
+	// Not the machine's policy either
+	policyFile = filepath.Join(home, "policy.toml")
+	t.Cleanup(func() { config, policyFile = saved, savedPolicy })
+	return home
+}
+
+// writeFile writes data to path under dir, creating its directories
+func writeFile(t *testing.T, dir, path, data string) string {
+	t.Helper()
+	path = filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// places returns the places of labels and targets given in turn
+func places(labelsAndTargets ...string) []Place {
+	var places []Place
+	for i := 0; i+1 < len(labelsAndTargets); i += 2 {
+		places = append(places, Place{Label: labelsAndTargets[i], Target: labelsAndTargets[i+1]})
+	}
+	return places
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// placeArg turns a command line argument into a place target if it is a
+// path or URL, returning "" for anything else
+func placeArg(arg string) (string, error) {
+	if u, err := url.Parse(arg); err == nil && u.Scheme != "" && strings.Contains(arg, "://") {
+		return arg, nil
+	}
+	if !strings.HasPrefix(arg, "/") && !strings.HasPrefix(arg, ".") && !strings.HasPrefix(arg, "~") {
+		return "", nil
+	}
+	path, err := expandHome(arg)
+	if err != nil {
+		return "", err
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return "", err
+	}
+	return fileURI(path), nil
+}
+
+// resolvePlace finds the place an argument of a command changing it names:
+// a path or URL matches by target, anything else by label as in
+// "bookmarksync open", except that a label only matched loosely has to be
+// confirmed
+func resolvePlace(places []Place, arg string) (Place, error) {
+	target, err := placeArg(arg)
+	if err != nil {
+		return Place{}, err
+	}
+	if target == "" {
+		matches, loose := matchPlaces(places, arg)
+		if loose {
+			return confirmPlace(arg, matches)
+		}
+		return findPlace(places, arg)
+	}
+	key := normalizeTarget(target)
+	for _, place := range places {
+		if normalizeTarget(place.Target) == key {
+			return place, nil
+		}
+	}
+	return Place{}, fmt.Errorf("no bookmark for %s", target)
+}
+
+// runAdd implements "bookmarksync add PATH|URL [LABEL]", adding a bookmark
+// to every backend
+func runAdd(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return usageError("bookmarksync-go add PATH|URL [LABEL]")
+	}
+
+	target, err := placeArg(args[0])
+	if err != nil {
+		return err
+	}
+	if target == "" {
+		// A bare name is a folder relative to the working directory
+		if target, err = placeArg("./" + args[0]); err != nil {
+			return err
+		}
+	}
+	if path, err := localPath(target); err == nil && !isDir(path) {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+	if scheme := forbiddenScheme(target); scheme != "" {
+		return errors.New(tr("%s: places are forbidden by %s", scheme, policyFile))
+	}
+	place := Place{Label: defaultLabel(target), Target: target}
+	if len(args) == 2 {
+		place.Label = args[1]
+	}
+
+	if err := NewBookmarkSync().UpdateAll(func(current []Place) []Place {
+		return appendMissingPlaces(current, []Place{place})
+	}); err != nil {
+		return err
+	}
+	fmt.Print(tr("Added %s (%s)\n", place.Label, place.Target))
+	return nil
+}
+
+// runRemove implements "bookmarksync remove PATH|LABEL", removing a
+// bookmark from every backend
+func runRemove(args []string) error {
+	fs := flag.NewFlagSet("remove", flag.ExitOnError)
+	force := fs.Bool("force", false, "Remove the bookmark even if it is locked")
+	places, err := sourcePlaces(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("bookmarksync-go remove [-f BACKEND] [--force] PATH|LABEL")
+	}
+	place, err := resolvePlace(places, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if isMandated(place) {
+		return errors.New(tr("%s is required by %s", place.Label, policyFile))
+	}
+	if isLocked(place) && !*force {
+		return errors.New(tr("%s is locked: remove it with --force, or unlock it first", place.Label))
+	}
+	keepLocked = !*force
+
+	if err := NewBookmarkSync().UpdateAll(func(current []Place) []Place {
+		return removeTargets(current, []Place{place})
+	}); err != nil {
+		return err
+	}
+	fmt.Print(tr("Removed %s (%s)\n", place.Label, place.Target))
+	return nil
+}
+
+// runRename implements "bookmarksync rename PATH|LABEL NEW", relabeling a
+// bookmark in every backend that stores labels
+func runRename(args []string) error {
+	fs := flag.NewFlagSet("rename", flag.ExitOnError)
+	force := fs.Bool("force", false, "Rename the bookmark even if it is locked")
+	places, err := sourcePlaces(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return usageError("bookmarksync-go rename [-f BACKEND] [--force] PATH|LABEL NEW")
+	}
+	place, err := resolvePlace(places, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if isMandated(place) {
+		return errors.New(tr("%s is required by %s", place.Label, policyFile))
+	}
+	if isLocked(place) && !*force {
+		return errors.New(tr("%s is locked: rename it with --force, or unlock it first", place.Label))
+	}
+	keepLocked = !*force
+	label := fs.Arg(1)
+
+	key := normalizeTarget(place.Target)
+	if err := NewBookmarkSync().UpdateAll(func(current []Place) []Place {
+		renamed := make([]Place, len(current))
+		copy(renamed, current)
+		for i := range renamed {
+			if normalizeTarget(renamed[i].Target) == key {
+				renamed[i].Label = label
+			}
+		}
+		return renamed
+	}); err != nil {
+		return err
+	}
+	fmt.Print(tr("Renamed %s to %s\n", place.Label, label))
+	return nil
+}
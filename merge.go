@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// normalizeTarget reduces a place target to a form where equivalent URLs
+// compare equal: lower-case scheme and host, percent-decoded path, no
+// trailing slash and no localhost in file URLs. The user is kept as it is:
+// the same path as another user is another place.
+func normalizeTarget(target string) string {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" {
+		return strings.TrimSuffix(target, "/")
+	}
+
+	path := u.Path
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	scheme, host := strings.ToLower(u.Scheme), strings.ToLower(u.Host)
+	if scheme == "file" && host == "localhost" {
+		host = ""
+	}
+	if u.User != nil {
+		host = u.User.String() + "@" + host
+	}
+	normalized := scheme + "://" + host + path
+	if u.RawQuery != "" {
+		normalized += "?" + u.RawQuery
+	}
+	return normalized
+}
+
+// dedupePlaces keeps the first of the places with the same normalized
+// target, labeled with the first label any of them has. Backends spell
+// targets differently, so without it round trips add up duplicates.
+func dedupePlaces(places []Place) []Place {
+	deduped := make([]Place, 0, len(places))
+	index := make(map[string]int, len(places))
+	for _, place := range places {
+		key := normalizeTarget(place.Target)
+		if i, ok := index[key]; ok {
+			if deduped[i].Label == "" {
+				deduped[i].Label = place.Label
+			}
+			continue
+		}
+		index[key] = len(deduped)
+		deduped = append(deduped, place)
+	}
+	return deduped
+}
+
+// mergePlaces unions incoming places into existing ones. Existing entries
+// keep their position; an incoming place with the same normalized target
+// updates the label, and anything new is appended in incoming order. A new
+// place whose label matches exactly one existing entry that incoming no
+// longer has is taken to be that entry moved, and replaces its target.
+func mergePlaces(existing []Place, incoming []Place) []Place {
+	merged := dedupePlaces(existing)
+
+	index := make(map[string]int, len(merged))
+	for i, place := range merged {
+		index[normalizeTarget(place.Target)] = i
+	}
+	moves := movedPlaces(merged, incoming)
+
+	for _, place := range incoming {
+		key := normalizeTarget(place.Target)
+		if i, ok := index[key]; ok {
+			if place.Label != "" {
+				merged[i].Label = place.Label
+			}
+			continue
+		}
+		if i, ok := moves[key]; ok {
+			delete(index, normalizeTarget(merged[i].Target))
+			merged[i].Target = place.Target
+			index[key] = i
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, place)
+	}
+
+	return merged
+}
+
+// mergeInto is the Merge of backends that are written as a whole: it
+// replaces the places of backend with incoming merged into them
+func mergeInto(backend BookmarkSyncBackend, incoming []Place) error {
+	existing, err := backend.GetPlaces()
+	if err != nil {
+		return err
+	}
+	return backend.Replace(mergePlaces(existing, incoming))
+}
+
+// movedPlaces pairs incoming places missing from existing with the existing
+// entry of the same label missing from incoming, returning the index of
+// that entry by the normalized incoming target. Only labels unique on both
+// sides are paired.
+func movedPlaces(existing []Place, incoming []Place) map[string]int {
+	inExisting := make(map[string]bool, len(existing))
+	for _, place := range existing {
+		inExisting[normalizeTarget(place.Target)] = true
+	}
+	inIncoming := make(map[string]bool, len(incoming))
+	for _, place := range incoming {
+		inIncoming[normalizeTarget(place.Target)] = true
+	}
+
+	gone := make(map[string][]int)
+	for i, place := range existing {
+		if place.Label != "" && !inIncoming[normalizeTarget(place.Target)] {
+			gone[place.Label] = append(gone[place.Label], i)
+		}
+	}
+	fresh := make(map[string][]string)
+	for _, place := range incoming {
+		key := normalizeTarget(place.Target)
+		if place.Label != "" && !inExisting[key] {
+			fresh[place.Label] = append(fresh[place.Label], key)
+		}
+	}
+
+	moves := make(map[string]int)
+	for label, keys := range fresh {
+		if len(keys) == 1 && len(gone[label]) == 1 {
+			moves[keys[0]] = gone[label][0]
+		}
+	}
+	return moves
+}
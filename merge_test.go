@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergePlaces(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []Place
+		incoming []Place
+		want     []Place
+	}{
+		{
+			name:     "new places are appended",
+			existing: places("a", "file:///a"),
+			incoming: places("b", "file:///b", "a", "file:///a"),
+			want:     places("a", "file:///a", "b", "file:///b"),
+		},
+		{
+			name:     "labels are updated in place",
+			existing: places("a", "file:///a", "b", "file:///b"),
+			incoming: places("Bee", "file:///b/"),
+			want:     places("a", "file:///a", "Bee", "file:///b"),
+		},
+		{
+			name:     "an empty label keeps the existing one",
+			existing: places("a", "file:///a"),
+			incoming: places("", "file:///a"),
+			want:     places("a", "file:///a"),
+		},
+		{
+			name:     "a moved place keeps its position",
+			existing: places("docs", "file:///old/docs", "b", "file:///b"),
+			incoming: places("docs", "file:///new/docs", "b", "file:///b"),
+			want:     places("docs", "file:///new/docs", "b", "file:///b"),
+		},
+		{
+			name:     "the same path of another user is another place",
+			existing: places("alice", "sftp://alice@Host/x"),
+			incoming: places("bob", "sftp://bob@host/x", "alice", "sftp://alice@host/x"),
+			want:     places("alice", "sftp://alice@Host/x", "bob", "sftp://bob@host/x"),
+		},
+		{
+			name:     "places missing from incoming are kept",
+			existing: places("a", "file:///a"),
+			incoming: nil,
+			want:     places("a", "file:///a"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := mergePlaces(test.existing, test.incoming); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("mergePlaces() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDedupePlaces(t *testing.T) {
+	got := dedupePlaces(places(
+		"alice", "sftp://alice@host/x",
+		"bob", "sftp://bob@host/x",
+		"", "SFTP://alice@HOST/x/",
+	))
+	if want := places("alice", "sftp://alice@host/x", "bob", "sftp://bob@host/x"); !reflect.DeepEqual(got, want) {
+		t.Errorf("dedupePlaces() = %v, want %v", got, want)
+	}
+}
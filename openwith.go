@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// openWithPath returns the file mapping bookmark targets to the command
+// that should open them
+func openWithPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "open-with"), nil
+}
+
+// readOpenWith loads the "target<TAB>command" hints file
+func readOpenWith() (map[string]string, error) {
+	hintsPath, err := openWithPath()
+	if err != nil {
+		return nil, err
+	}
+
+	hints := make(map[string]string)
+	file, err := os.Open(hintsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return hints, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if target, command, ok := strings.Cut(scanner.Text(), "\t"); ok {
+			hints[target] = command
+		}
+	}
+	return hints, scanner.Err()
+}
+
+func writeOpenWith(hints map[string]string) error {
+	hintsPath, err := openWithPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(hintsPath), 0755); err != nil {
+		return err
+	}
+
+	var targets []string
+	for target := range hints {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	var content strings.Builder
+	for _, target := range targets {
+		fmt.Fprintf(&content, "%s\t%s\n", target, hints[target])
+	}
+	return replaceFile(hintsPath, []byte(content.String()))
+}
+
+// findPlace looks a bookmark up by its label or target, accepting partial
+// labels and asking the user when more than one bookmark matches
+func findPlace(places []Place, name string) (Place, error) {
+	matches, _ := matchPlaces(places, name)
+	switch len(matches) {
+	case 0:
+		return Place{}, fmt.Errorf("no bookmark named %q", name)
+	case 1:
+		return matches[0], nil
+	}
+	return choosePlace(name, matches)
+}
+
+// sourceName returns the name of the backend name names, or with "" the
+// one syncs and check take for the source: from in the configuration, else
+// canonical, else gtk
+func (bs *BookmarkSync) sourceName(name string) string {
+	if name == "" {
+		name = config.From
+	}
+	if _, ok := bs.backends["canonical"]; ok && name == "" {
+		name = "canonical"
+	}
+	if name == "" {
+		name = "gtk"
+	}
+	return strings.ToLower(name)
+}
+
+// sourcePlaces parses the -f flag shared by the bookmark commands and
+// returns the chosen backend's places
+func sourcePlaces(fs *flag.FlagSet, args []string) ([]Place, error) {
+	syncFrom := fs.String("f", "", "Backend to read bookmarks from (default: from in the configuration, else canonical, else gtk)")
+	fs.StringVar(syncFrom, "sync-from", "", "Backend to read bookmarks from (default: from in the configuration, else canonical, else gtk)")
+	fs.Parse(args)
+
+	bs := NewBookmarkSync()
+	backend, exists := bs.backends[bs.sourceName(*syncFrom)]
+	if !exists {
+		return nil, fmt.Errorf("unknown backend: %s", bs.sourceName(*syncFrom))
+	}
+	places, err := backend.GetPlaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get places from %s: %v", backend.Name(), err)
+	}
+	return places, nil
+}
+
+// runSetApp implements "bookmarksync set-app NAME [COMMAND]"
+func runSetApp(args []string) error {
+	fs := flag.NewFlagSet("set-app", flag.ExitOnError)
+	places, err := sourcePlaces(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return usageError("bookmarksync-go set-app [-f BACKEND] NAME [COMMAND]")
+	}
+
+	place, err := findPlace(places, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	hints, err := readOpenWith()
+	if err != nil {
+		return err
+	}
+	command := strings.Join(fs.Args()[1:], " ")
+	if command == "" {
+		delete(hints, place.Target)
+	} else {
+		hints[place.Target] = command
+	}
+	if err := writeOpenWith(hints); err != nil {
+		return err
+	}
+
+	// The place's launcher, if it has one, opens it with the command too
+	launchers := &LaunchersBackend{}
+	current, err := launchers.GetPlaces()
+	if err != nil || !slices.ContainsFunc(current, func(launched Place) bool { return launched.Target == place.Target }) {
+		return nil
+	}
+	return launchers.Replace(current)
+}
+
+// runOpen implements "bookmarksync open NAME", launching the bookmark's
+// preferred application or the desktop default (xdg-open)
+func runOpen(args []string) error {
+	fs := flag.NewFlagSet("open", flag.ExitOnError)
+	places, err := sourcePlaces(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("bookmarksync-go open [-f BACKEND] NAME")
+	}
+
+	place, err := findPlace(places, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	hints, err := readOpenWith()
+	if err != nil {
+		return err
+	}
+
+	argv := openCommand(hints[place.Target], place.Target)
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Start()
+}
+
+// openCommand expands an open-with command line. %u is replaced with the
+// URL and %f with the local path; without either, the path (or URL for
+// remote places) is appended.
+func openCommand(command string, target string) []string {
+	if command == "" {
+		return []string{"xdg-open", target}
+	}
+
+	local := target
+	if u, err := url.Parse(target); err == nil && u.Scheme == "file" {
+		local = u.Path
+	}
+
+	argv := strings.Fields(command)
+	expanded := false
+	for i, arg := range argv {
+		if strings.Contains(arg, "%u") || strings.Contains(arg, "%f") {
+			arg = strings.ReplaceAll(arg, "%u", target)
+			argv[i] = strings.ReplaceAll(arg, "%f", local)
+			expanded = true
+		}
+	}
+	if !expanded {
+		argv = append(argv, local)
+	}
+	return argv
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestSourceName(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      string
+		canonical bool
+		want      string
+	}{
+		{"", "", false, "gtk"},
+		{"", "", true, "canonical"},
+		{"", "KDE", true, "kde"},
+		{"Qt", "kde", true, "qt"},
+	}
+	for _, test := range tests {
+		testHome(t)
+		config.From, config.Canonical = test.from, test.canonical
+		if got := NewBookmarkSync().sourceName(test.name); got != test.want {
+			t.Errorf("sourceName(%q) with from %q, canonical %v = %q, want %q", test.name, test.from, test.canonical, got, test.want)
+		}
+	}
+}
+
+// Regression test: the bookmark commands read gtk whatever from said
+func TestSourcePlacesDefault(t *testing.T) {
+	home := testHome(t)
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///gtk gtk\n")
+	writeFile(t, home, ".config/bookmarksync/places.toml", "[[place]]\nlabel = \"canonical\"\ntarget = \"file:///canonical\"\n")
+	config.Canonical = true
+
+	got, err := sourcePlaces(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := places("canonical", "file:///canonical"); !reflect.DeepEqual(got, want) {
+		t.Errorf("sourcePlaces() = %v, want %v", got, want)
+	}
+}
+
+func TestOpenCommand(t *testing.T) {
+	tests := []struct {
+		command, target string
+		want            []string
+	}{
+		{"", "file:///srv/code", []string{"xdg-open", "file:///srv/code"}},
+		{"code", "file:///srv/code", []string{"code", "/srv/code"}},
+		{"filezilla %u", "sftp://host/srv", []string{"filezilla", "sftp://host/srv"}},
+		{"gimp --dir=%f", "file:///srv/img", []string{"gimp", "--dir=/srv/img"}},
+	}
+	for _, test := range tests {
+		if got := openCommand(test.command, test.target); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("openCommand(%q, %q) = %q, want %q", test.command, test.target, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/collate"
+)
+
+// Orders of written places, set with order in the configuration or --order
+const (
+	// orderSource keeps the order of the places synced from, the default
+	orderSource = "preserve-source"
+	// orderLabel sorts places by label, in the user's language
+	orderLabel = "alphabetical-by-label"
+	// orderPath sorts places by target
+	orderPath = "alphabetical-by-path"
+	// orderCustom puts the places of custom_order first, in that order
+	orderCustom = "custom"
+)
+
+// placeOrders are the valid orders
+var placeOrders = []string{orderSource, orderLabel, orderPath, orderCustom}
+
+// checkOrder returns an error if order isn't a valid order
+func checkOrder(order string) error {
+	if order != "" && !slices.Contains(placeOrders, order) {
+		return fmt.Errorf("unknown order %q, expected one of %s", order, strings.Join(placeOrders, ", "))
+	}
+	return nil
+}
+
+// orderPlaces returns places in the configured order. Sorting is stable,
+// so places that compare equal keep the order of the source.
+func orderPlaces(places []Place) []Place {
+	switch config.Order {
+	case orderLabel:
+		c := collate.New(userLanguage(), collate.IgnoreCase, collate.Numeric)
+		return sortedPlaces(places, func(a, b Place) int {
+			return c.CompareString(a.Label, b.Label)
+		})
+	case orderPath:
+		return sortedPlaces(places, func(a, b Place) int {
+			return strings.Compare(normalizeTarget(a.Target), normalizeTarget(b.Target))
+		})
+	case orderCustom:
+		return sortedPlaces(places, func(a, b Place) int {
+			return customRank(a) - customRank(b)
+		})
+	}
+	return places
+}
+
+// sortedPlaces returns a sorted copy of places
+func sortedPlaces(places []Place, cmp func(a, b Place) int) []Place {
+	sorted := slices.Clone(places)
+	slices.SortStableFunc(sorted, cmp)
+	return sorted
+}
+
+// customRank is the position of the first custom_order entry matching
+// place by label or path, after all of them when none does
+func customRank(place Place) int {
+	key := normalizeTarget(place.Target)
+	for i, entry := range config.CustomOrder {
+		if target, err := placeArg(entry); err == nil && target != "" {
+			if normalizeTarget(target) == key {
+				return i
+			}
+		} else if strings.EqualFold(entry, place.Label) {
+			return i
+		}
+	}
+	return len(config.CustomOrder)
+}
+
+// orderedBackend writes places in the configured order
+type orderedBackend struct {
+	BookmarkSyncBackend
+}
+
+func (o *orderedBackend) Capabilities() Capabilities {
+	return capabilitiesOf(o.BookmarkSyncBackend)
+}
+
+func (o *orderedBackend) Unwrap() BookmarkSyncBackend {
+	return o.BookmarkSyncBackend
+}
+
+func (o *orderedBackend) Merge(places []Place) error {
+	if config.Order == "" || config.Order == orderSource {
+		return o.BookmarkSyncBackend.Merge(places)
+	}
+	return mergeInto(o, places)
+}
+
+func (o *orderedBackend) Replace(places []Place) error {
+	return o.BookmarkSyncBackend.Replace(orderPlaces(places))
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"sort"
+)
+
+// pinnedBackend keeps a backend's pinned bookmarks, and the locked places
+// of the canonical places file and policy file, whenever it is replaced,
+// so places only one backend has survive syncs from the others. Places
+// the policy forbids are dropped.
+type pinnedBackend struct {
+	BookmarkSyncBackend
+}
+
+func (p *pinnedBackend) Capabilities() Capabilities {
+	return capabilitiesOf(p.BookmarkSyncBackend)
+}
+
+func (p *pinnedBackend) Unwrap() BookmarkSyncBackend {
+	return p.BookmarkSyncBackend
+}
+
+func (p *pinnedBackend) Replace(places []Place) error {
+	state, err := LoadState()
+	if err != nil {
+		return err
+	}
+	if pins := pinnedPlaces(state, p.Name()); len(pins) > 0 {
+		current, err := p.BookmarkSyncBackend.GetPlaces()
+		if err != nil {
+			return err
+		}
+		places = withPins(pins, current, places)
+	}
+	return p.BookmarkSyncBackend.Replace(withLocked(withoutForbidden(places)))
+}
+
+// Merge goes through Replace, so merged places are filtered the same way
+func (p *pinnedBackend) Merge(places []Place) error {
+	return mergeInto(p, places)
+}
+
+// pinnedPlaces returns the places pinned to the backend called name, with
+// "bookmarksync pin" or in the configuration
+func pinnedPlaces(state *State, name string) []Place {
+	pins := append([]Place(nil), state.Pins[name]...)
+	for _, target := range config.Pins[name] {
+		pins = append(pins, Place{Label: defaultLabel(target), Target: target})
+	}
+	return pins
+}
+
+// withPins returns places followed by the pins they lack. A pin the
+// backend currently has keeps its label there.
+func withPins(pins, current, places []Place) []Place {
+	seen := make(map[string]bool, len(places))
+	for _, place := range places {
+		seen[normalizeTarget(place.Target)] = true
+	}
+	labels := make(map[string]string, len(current))
+	for _, place := range current {
+		labels[normalizeTarget(place.Target)] = place.Label
+	}
+	// Clipped, so appending never writes into a slice the caller shares
+	kept := slices.Clip(places)
+	for _, pin := range pins {
+		key := normalizeTarget(pin.Target)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if label, ok := labels[key]; ok {
+			pin.Label = label
+		}
+		kept = append(kept, pin)
+	}
+	return kept
+}
+
+// defaultLabel is the label of a bookmark added without one: the folder
+// name, or the host and path of a URL
+func defaultLabel(target string) string {
+	if path, err := localPath(target); err == nil {
+		return filepath.Base(path)
+	}
+	u, _ := url.Parse(target)
+	return u.Host + u.Path
+}
+
+// runPin implements "bookmarksync pin [-f BACKEND] PATH|LABEL", pinning
+// one of a backend's bookmarks there, and "bookmarksync pin list"
+func runPin(args []string) error {
+	state, err := LoadState()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 && args[0] == "list" {
+		var names []string
+		for name := range state.Pins {
+			names = append(names, name)
+		}
+		for name := range config.Pins {
+			if _, exists := state.Pins[name]; !exists {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, pin := range pinnedPlaces(state, name) {
+				fmt.Printf("%s\t%s\t%s\n", name, pin.Label, pin.Target)
+			}
+		}
+		return nil
+	}
+
+	fs := flag.NewFlagSet("pin", flag.ExitOnError)
+	places, err := sourcePlaces(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("bookmarksync-go pin [-f BACKEND] PATH|LABEL | pin list")
+	}
+	place, err := resolvePlace(places, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	name := NewBookmarkSync().sourceName(fs.Lookup("f").Value.String())
+
+	key := normalizeTarget(place.Target)
+	for _, pin := range pinnedPlaces(state, name) {
+		if normalizeTarget(pin.Target) == key {
+			fmt.Print(tr("%s is already pinned in %s\n", place.Label, name))
+			return nil
+		}
+	}
+	if state.Pins == nil {
+		state.Pins = make(map[string][]Place)
+	}
+	state.Pins[name] = append(state.Pins[name], place)
+	if err := state.Save(); err != nil {
+		return err
+	}
+	fmt.Print(tr("Pinned %s (%s) in %s\n", place.Label, place.Target, name))
+	return nil
+}
+
+// runUnpin implements "bookmarksync unpin [-f BACKEND] PATH|LABEL". The
+// bookmark stays in the backend until a sync replaces it.
+func runUnpin(args []string) error {
+	fs := flag.NewFlagSet("unpin", flag.ExitOnError)
+	backend := fs.String("f", "", "Backend to unpin the bookmark from (default: from in the configuration, else canonical, else gtk)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return usageError("bookmarksync-go unpin [-f BACKEND] PATH|LABEL")
+	}
+	name := NewBookmarkSync().sourceName(*backend)
+
+	state, err := LoadState()
+	if err != nil {
+		return err
+	}
+	place, err := resolvePlace(pinnedPlaces(state, name), fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	pins := removeTargets(state.Pins[name], []Place{place})
+	if len(pins) == len(state.Pins[name]) {
+		return fmt.Errorf("%s is pinned in %s by the configuration", place.Target, name)
+	}
+	if len(pins) == 0 {
+		delete(state.Pins, name)
+	} else {
+		state.Pins[name] = pins
+	}
+	if err := state.Save(); err != nil {
+		return err
+	}
+	fmt.Print(tr("Unpinned %s (%s) in %s\n", place.Label, place.Target, name))
+	return nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// policyFile is the system-wide policy of managed deployments, which
+// administrators put in place for every user of a machine
+var policyFile = "/etc/bookmarksync/policy.toml"
+
+// Policy is the layout of the policy file
+type Policy struct {
+	// Locked are settings users can't change in config.toml: they get the
+	// policy's, or none
+	Locked []string `toml:"locked"`
+	// ForbidSchemes are URL schemes whose places are never synced
+	ForbidSchemes []string `toml:"forbid_schemes"`
+	// Bookmarks are places every backend is written with, that syncs and
+	// commands can't remove or relabel
+	Bookmarks []struct {
+		Label  string `toml:"label"`
+		Target string `toml:"target"`
+	} `toml:"bookmark"`
+	// Config are the settings users get unless config.toml has its own
+	Config toml.Primitive `toml:"config"`
+}
+
+// configKeys returns the settings of config.toml, by their name there
+func configKeys() []string {
+	var keys []string
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if key, _, _ := strings.Cut(t.Field(i).Tag.Get("toml"), ","); key != "" && key != "-" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// setConfigKey sets the setting called key of cfg to the one of from
+func setConfigKey(cfg *Config, from Config, key string) {
+	t := reflect.TypeOf(*cfg)
+	for i := 0; i < t.NumField(); i++ {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("toml"), ","); name == key {
+			reflect.ValueOf(cfg).Elem().Field(i).Set(reflect.ValueOf(from).Field(i))
+		}
+	}
+}
+
+// applyPolicy reads the policy file into cfg, read from config.toml with
+// meta: the settings of its [config] table cfg doesn't have, and those
+// it locks, its bookmarks and forbidden schemes. Without a policy file cfg
+// stays as it is.
+func applyPolicy(cfg *Config, meta toml.MetaData) error {
+	var policy Policy
+	policyMeta, err := toml.DecodeFile(policyFile, &policy)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("%s: %v", policyFile, err)
+	}
+	var base Config
+	if err := policyMeta.PrimitiveDecode(policy.Config, &base); err != nil {
+		return fmt.Errorf("%s: %v", policyFile, err)
+	}
+	if undecoded := policyMeta.Undecoded(); len(undecoded) > 0 {
+		return fmt.Errorf("%s: unknown setting %s", policyFile, undecoded[0])
+	}
+
+	keys := configKeys()
+	for _, key := range policy.Locked {
+		if !slices.Contains(keys, key) {
+			return fmt.Errorf("%s: can't lock %s: there is no such setting", policyFile, key)
+		}
+	}
+	for _, key := range keys {
+		locked := slices.Contains(policy.Locked, key)
+		if locked && meta.IsDefined(key) {
+			log.Print(tr("Warning: ignoring %s in the configuration: %s sets it", key, policyFile))
+		}
+		if locked || (policyMeta.IsDefined("config", key) && !meta.IsDefined(key)) {
+			setConfigKey(cfg, base, key)
+		}
+	}
+
+	cfg.Locked = policy.Locked
+	for _, scheme := range policy.ForbidSchemes {
+		cfg.ForbidSchemes = append(cfg.ForbidSchemes, strings.ToLower(strings.TrimSuffix(scheme, ":")))
+	}
+	for _, bookmark := range policy.Bookmarks {
+		target, err := placeArg(bookmark.Target)
+		if err != nil {
+			return fmt.Errorf("%s: %v", policyFile, err)
+		}
+		if target == "" {
+			return fmt.Errorf("%s: bookmark %q is not a path or URL", policyFile, bookmark.Target)
+		}
+		place := Place{Label: bookmark.Label, Target: target}
+		if place.Label == "" {
+			place.Label = defaultLabel(target)
+		}
+		cfg.Mandated = append(cfg.Mandated, place)
+	}
+	return nil
+}
+
+// flagSettings are the settings of config.toml command line flags set, by
+// flag
+var flagSettings = map[string]string{
+	"from":         "from",
+	"sync-from":    "from",
+	"f":            "from",
+	"merge":        "merge",
+	"order":        "order",
+	"exclude":      "exclude",
+	"include":      "include",
+	"backend-path": "paths",
+	"simulate":     "simulate",
+	"debounce":     "debounce",
+	"max-delay":    "max_delay",
+}
+
+// checkLockedFlags returns an error for the first flag given to fs that
+// sets a setting the policy locks, which would get around the lock
+func checkLockedFlags(fs *flag.FlagSet) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if key, ok := flagSettings[f.Name]; ok && err == nil && slices.Contains(config.Locked, key) {
+			err = errors.New(tr("--%s can't be used: %s locks %s", f.Name, policyFile, key))
+		}
+	})
+	return err
+}
+
+// isMandated reports whether place is one of the policy's bookmarks
+func isMandated(place Place) bool {
+	key := normalizeTarget(place.Target)
+	return slices.ContainsFunc(config.Mandated, func(mandated Place) bool {
+		return normalizeTarget(mandated.Target) == key
+	})
+}
+
+// forbiddenScheme returns the scheme of target when the policy forbids it,
+// "" otherwise
+func forbiddenScheme(target string) string {
+	scheme, _, ok := strings.Cut(target, ":")
+	if !ok {
+		return ""
+	}
+	scheme = strings.ToLower(strings.TrimSpace(scheme))
+	if slices.Contains(config.ForbidSchemes, scheme) {
+		return scheme
+	}
+	return ""
+}
+
+// withoutForbidden returns places without those the policy forbids
+func withoutForbidden(places []Place) []Place {
+	if len(config.ForbidSchemes) == 0 {
+		return places
+	}
+	allowed := make([]Place, 0, len(places))
+	for _, place := range places {
+		if forbiddenScheme(place.Target) == "" {
+			allowed = append(allowed, place)
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+// testPolicy makes data the policy file for the test
+func testPolicy(t *testing.T, data string) {
+	t.Helper()
+	saved := policyFile
+	policyFile = writeFile(t, t.TempDir(), "policy.toml", data)
+	t.Cleanup(func() { policyFile = saved })
+}
+
+func TestApplyPolicy(t *testing.T) {
+	testHome(t)
+	testPolicy(t, `
+locked = ["order"]
+forbid_schemes = ["SMB:"]
+
+[config]
+order = "alphabetical-by-label"
+merge = true
+from = "kde"
+
+[[bookmark]]
+target = "/srv/share"
+
+[[bookmark]]
+label = "Wiki"
+target = "https://wiki.example.com"
+`)
+	var cfg Config
+	meta, err := toml.Decode(`
+order = "preserve-source"
+from = "gtk"
+`, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := applyPolicy(&cfg, meta); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Order != orderLabel {
+		t.Errorf("order = %q, want the locked %q", cfg.Order, orderLabel)
+	}
+	if cfg.From != "gtk" {
+		t.Errorf("from = %q, want the configuration's gtk", cfg.From)
+	}
+	if !cfg.Merge {
+		t.Error("merge not taken from the policy")
+	}
+	if want := []string{"smb"}; !reflect.DeepEqual(cfg.ForbidSchemes, want) {
+		t.Errorf("forbidden schemes = %v, want %v", cfg.ForbidSchemes, want)
+	}
+	if want := places("share", "file:///srv/share", "Wiki", "https://wiki.example.com"); !reflect.DeepEqual(cfg.Mandated, want) {
+		t.Errorf("mandated = %v, want %v", cfg.Mandated, want)
+	}
+	if want := []string{"order"}; !reflect.DeepEqual(cfg.Locked, want) {
+		t.Errorf("locked = %v, want %v", cfg.Locked, want)
+	}
+}
+
+func TestLockedFlags(t *testing.T) {
+	tests := [][]string{
+		{"--order", "preserve-source"},
+		{"--merge"},
+		{"--exclude", "smb://*"},
+		{"--include", "file:///home/*"},
+		{"--backend-path", "gtk=/tmp/bookmarks"},
+		{"--simulate", "qt=drop"},
+		{"-f", "kde"},
+	}
+	for _, args := range tests {
+		testHome(t)
+		config.Locked = []string{"order", "merge", "exclude", "include", "paths", "simulate", "from"}
+		if err := runSync(args); err == nil || !strings.Contains(err.Error(), "locks") {
+			t.Errorf("sync %s: error %v, want the flag rejected", strings.Join(args, " "), err)
+		}
+	}
+
+	home := testHome(t)
+	config.Locked = []string{"order"}
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\n")
+	if err := runSync([]string{"--dry-run", "--merge"}); err != nil {
+		t.Errorf("sync with flags of unlocked settings: %v", err)
+	}
+}
+
+func TestApplyPolicyErrors(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   string
+	}{
+		{`locked = ["colour"]`, "can't lock colour"},
+		{"[config]\ncolour = \"blue\"", "unknown setting config.colour"},
+		{"[[bookmark]]\ntarget = \"share\"", "is not a path or URL"},
+	}
+	for _, test := range tests {
+		testHome(t)
+		testPolicy(t, test.policy)
+		var cfg Config
+		if err := applyPolicy(&cfg, toml.MetaData{}); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("policy %q: error %v, want one with %q", test.policy, err, test.want)
+		}
+	}
+}
+
+func TestApplyPolicyWithoutFile(t *testing.T) {
+	testHome(t)
+	saved := policyFile
+	policyFile = filepath.Join(t.TempDir(), "policy.toml")
+	t.Cleanup(func() { policyFile = saved })
+	cfg := Config{From: "kde"}
+	if err := applyPolicy(&cfg, toml.MetaData{}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, Config{From: "kde"}) {
+		t.Errorf("configuration changed to %+v", cfg)
+	}
+}
+
+// Regression test: sync --merge went around the pinned backend's Replace,
+// and with it the policy
+func TestMergeKeepsPolicy(t *testing.T) {
+	home := testHome(t)
+	config.ForbidSchemes = []string{"smb"}
+	config.Mandated = places("Wiki", "https://wiki.example.com")
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\n")
+	backend := &pinnedBackend{&GTKBackend{}}
+
+	if err := backend.Merge(places("share", "smb://nas/share", "b", "file:///b")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := backend.GetPlaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := places("a", "file:///a", "b", "file:///b", "Wiki", "https://wiki.example.com"); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged %v, want %v", got, want)
+	}
+}
+
+// Regression test: dry runs showed forbidden places being written
+func TestWrittenKeepsPolicy(t *testing.T) {
+	testHome(t)
+	config.ForbidSchemes = []string{"smb"}
+	config.Mandated = places("Wiki", "https://wiki.example.com")
+	current := places("a", "file:///a")
+	incoming := places("share", "smb://nas/share", "b", "file:///b")
+	caps := Capabilities{Labels: true, Remote: true}
+
+	for _, merge := range []bool{false, true} {
+		bs := &BookmarkSync{Merge: merge}
+		got := bs.written(&State{}, "gtk", caps, current, incoming)
+		want := places("b", "file:///b", "Wiki", "https://wiki.example.com")
+		if merge {
+			want = append(places("a", "file:///a"), want...)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("merge %v: written %v, want %v", merge, got, want)
+		}
+	}
+}
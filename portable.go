@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+)
+
+// placeholder is a ${NAME} in a target of the canonical places file, which
+// is the environment variable NAME on every machine that reads it
+var placeholder = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// placeholderValue returns what the placeholder called name stands for in
+// a target, escaped for a URI: a path where it is one, like ${HOME}. HOME
+// and USER are looked up even where the environment doesn't have them.
+func placeholderValue(name string) (string, bool) {
+	value, ok := os.LookupEnv(name)
+	switch {
+	case name == "HOME" && value == "":
+		home, err := os.UserHomeDir()
+		value, ok = home, err == nil
+	case name == "USER" && value == "":
+		current, err := user.Current()
+		if err == nil {
+			value, ok = current.Username, true
+		}
+	}
+	if !ok || value == "" {
+		return "", false
+	}
+	if strings.HasPrefix(value, "/") {
+		return strings.TrimPrefix(fileURI(strings.TrimRight(value, "/")), "file://"), true
+	}
+	return url.PathEscape(value), true
+}
+
+// expandPlaceholders replaces the placeholders of target with what they
+// stand for here. One for a variable that isn't set is left as it is.
+func expandPlaceholders(target string) string {
+	if !strings.Contains(target, "${") {
+		return target
+	}
+	return placeholder.ReplaceAllStringFunc(target, func(match string) string {
+		if value, ok := placeholderValue(match[2 : len(match)-1]); ok {
+			return value
+		}
+		return match
+	})
+}
+
+// portableTarget puts placeholders into a local target where it depends on
+// who the user is: ${HOME} for the home folder, and ${USER} for the folder
+// of the user's removable volumes, so that the same canonical places file
+// works for users with other names and homes on other machines
+func portableTarget(target string) string {
+	if !strings.HasPrefix(target, "file://") {
+		return target
+	}
+	path := strings.TrimPrefix(target, "file://")
+	if home, ok := placeholderValue("HOME"); ok && home != "/" {
+		if path == home || strings.HasPrefix(path, home+"/") {
+			return "file://${HOME}" + strings.TrimPrefix(path, home)
+		}
+	}
+	if name, ok := placeholderValue("USER"); ok {
+		for _, root := range []string{"/run/media/", "/media/"} {
+			if rest, found := strings.CutPrefix(path, root+name+"/"); found {
+				return "file://" + root + "${USER}/" + rest
+			}
+		}
+	}
+	return target
+}
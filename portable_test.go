@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPortableTarget(t *testing.T) {
+	home := testHome(t)
+	t.Setenv("USER", "jo")
+	tests := []struct {
+		target, want string
+	}{
+		{fileURI(home), "file://${HOME}"},
+		{fileURI(filepath.Join(home, "My Documents")), "file://${HOME}/My%20Documents"},
+		{fileURI(home + "x"), fileURI(home + "x")},
+		{"file:///run/media/jo/stick/photos", "file:///run/media/${USER}/stick/photos"},
+		{"file:///media/jo/stick", "file:///media/${USER}/stick"},
+		{"file:///media/joe/stick", "file:///media/joe/stick"},
+		{"sftp://host" + home, "sftp://host" + home},
+	}
+	for _, test := range tests {
+		got := portableTarget(test.target)
+		if got != test.want {
+			t.Errorf("portable %s is %s, want %s", test.target, got, test.want)
+		}
+		if back := expandPlaceholders(got); back != test.target {
+			t.Errorf("%s expands to %s, want %s", got, back, test.target)
+		}
+	}
+}
+
+func TestExpandPlaceholders(t *testing.T) {
+	testHome(t)
+	t.Setenv("USER", "jo smith")
+	t.Setenv("PROJECTS", "/srv/my projects/")
+	tests := []struct {
+		target, want string
+	}{
+		{"file:///media/${USER}/stick", "file:///media/jo%20smith/stick"},
+		{"file://${PROJECTS}/a", "file:///srv/my%20projects/a"},
+		{"file://${BOOKMARKSYNC_UNSET}/a", "file://${BOOKMARKSYNC_UNSET}/a"},
+		{"file:///$HOME/a", "file:///$HOME/a"},
+	}
+	for _, test := range tests {
+		if got := expandPlaceholders(test.target); got != test.want {
+			t.Errorf("%s expands to %s, want %s", test.target, got, test.want)
+		}
+	}
+}
+
+// The canonical places file holds the placeholders, and the backends get
+// the places expanded
+func TestCanonicalPlaceholders(t *testing.T) {
+	home := testHome(t)
+	work := fileURI(filepath.Join(home, "work"))
+	backend := &CanonicalBackend{}
+	if err := backend.Replace(places("work", work)); err != nil {
+		t.Fatal(err)
+	}
+	files, err := backend.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"file://${HOME}/work"`) || strings.Contains(string(data), home) {
+		t.Errorf("canonical places file holds\n%s", data)
+	}
+	if got, _ := backend.GetPlaces(); !reflect.DeepEqual(got, places("work", work)) {
+		t.Errorf("read %v", got)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	// documentsPortalName is the bus name of the documents portal
+	documentsPortalName = "org.freedesktop.portal.Documents"
+	// documentsPortalPath is the object path of the documents portal
+	documentsPortalPath = dbus.ObjectPath("/org/freedesktop/portal/documents")
+)
+
+// portalDocumentRe matches files a sandboxed app was given through the
+// documents portal: /run/user/UID/doc/ID/NAME on the host or
+// /run/flatpak/doc/ID/NAME inside a sandbox, optionally under by-app/APP
+var portalDocumentRe = regexp.MustCompile(`^(?:/run/user/\d+|/run/flatpak)/doc/(?:by-app/[^/]+/)?([^/]+)/(.+)$`)
+
+// withPortalPathsResolved replaces the documents portal paths of bookmarks
+// made in sandboxed apps with the real paths they stand for, which work in
+// every app. Places the portal doesn't know are kept as they are.
+func withPortalPathsResolved(places []Place) []Place {
+	var conn *dbus.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	resolved := make([]Place, len(places))
+	copy(resolved, places)
+	for i, place := range resolved {
+		path, err := localPath(place.Target)
+		if err != nil {
+			continue
+		}
+		match := portalDocumentRe.FindStringSubmatch(path)
+		if match == nil {
+			continue
+		}
+
+		if conn == nil {
+			if conn, err = dbus.ConnectSessionBus(); err != nil {
+				log.Print(tr("Warning: can't resolve documents portal path %s: %v", path, err))
+				return resolved
+			}
+		}
+		host, err := portalDocumentPath(conn, match[1])
+		if err != nil {
+			log.Print(tr("Warning: can't resolve documents portal path %s: %v", path, err))
+			continue
+		}
+		// The document is the first component after the ID; anything
+		// below it is inside an exported folder
+		if _, below, ok := strings.Cut(match[2], "/"); ok {
+			host = filepath.Join(host, below)
+		}
+		resolved[i].Target = fileURI(host)
+	}
+	return resolved
+}
+
+// portalDocumentPath asks the documents portal for the host path of the
+// document with the given ID
+func portalDocumentPath(conn *dbus.Conn, id string) (string, error) {
+	var path []byte
+	var apps map[string][]string
+	err := conn.Object(documentsPortalName, documentsPortalPath).
+		Call(documentsPortalName+".Info", 0, id).Store(&path, &apps)
+	if err != nil {
+		return "", err
+	}
+	// The path is a nul-terminated byte string
+	return strings.TrimRight(string(path), "\x00"), nil
+}
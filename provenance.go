@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// Provenance records where a bookmark came from when bookmarksync first
+// saw it
+type Provenance struct {
+	// Backend is the backend it was synced from, empty when a command
+	// added it to every backend at once
+	Backend string `json:"backend,omitempty"`
+	// Host is the machine it was first seen on
+	Host string `json:"host,omitempty"`
+	// Command is the bookmarksync command that first saw it
+	Command string `json:"command"`
+	// Added is when that was
+	Added time.Time `json:"added"`
+	// Label is the label it had when last recorded, and Modified when it
+	// was last relabelled
+	Label    string    `json:"label,omitempty"`
+	Modified time.Time `json:"modified,omitempty"`
+}
+
+// commandName is the command bookmarksync is running, recorded as the
+// provenance of the bookmarks it brings in
+var commandName = "sync"
+
+// noteOrigins remembers that places were read from backend, so the ones
+// that are new can be traced back to it
+func (bs *BookmarkSync) noteOrigins(backend string, places []Place) {
+	if bs.origins == nil {
+		bs.origins = make(map[string]string)
+	}
+	for _, place := range places {
+		key := normalizeTarget(place.Target)
+		if _, ok := bs.origins[key]; !ok {
+			bs.origins[key] = backend
+		}
+	}
+}
+
+// recordProvenance adds the provenance of every place of state.Seen that
+// has none yet, notes when the others were relabelled, and forgets the
+// places no backend holds any more
+func (bs *BookmarkSync) recordProvenance(state *State) {
+	holders := make(map[string][]string)
+	labels := make(map[string]string)
+	for _, name := range bs.order {
+		for _, place := range state.Seen[name] {
+			key := normalizeTarget(place.Target)
+			holders[key] = append(holders[key], name)
+			// Backends that can't store labels make them up
+			if _, ok := labels[key]; !ok && capabilitiesOf(bs.backends[name]).Labels {
+				labels[key] = place.Label
+			}
+		}
+	}
+
+	if state.Provenance == nil {
+		state.Provenance = make(map[string]Provenance)
+	}
+	for key := range state.Provenance {
+		if len(holders[key]) == 0 {
+			delete(state.Provenance, key)
+		}
+	}
+
+	host, _ := os.Hostname()
+	now := time.Now()
+	for key, names := range holders {
+		if p, ok := state.Provenance[key]; ok {
+			if label, ok := labels[key]; ok && label != p.Label {
+				// Provenance recorded before labels were has no
+				// relabelling to note
+				if p.Label != "" {
+					p.Modified = now
+				}
+				p.Label = label
+				state.Provenance[key] = p
+			}
+			continue
+		}
+		origin := bs.origins[key]
+		if origin == "" && len(names) == 1 {
+			origin = names[0]
+		}
+		state.Provenance[key] = Provenance{Backend: origin, Host: host, Command: commandName, Added: now, Label: labels[key]}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// removableRoots are where removable and network drives are mounted.
+// Folders under them are kept while their drive is away.
+var removableRoots = []string{"/media/", "/run/media/", "/mnt/", "/run/user/"}
+
+// missingPlaces returns the local places whose folder no longer exists
+func missingPlaces(places []Place) []Place {
+	var missing []Place
+	for _, place := range places {
+		path, err := localPath(place.Target)
+		if err != nil || path == "" {
+			continue
+		}
+		removable := false
+		for _, root := range removableRoots {
+			removable = removable || strings.HasPrefix(path, root)
+		}
+		if removable {
+			continue
+		}
+		// Only a folder known to be gone counts; permission errors and the
+		// like keep the place
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			missing = append(missing, place)
+		}
+	}
+	return missing
+}
+
+// pruned drops the places whose folder no longer exists with Prune set,
+// reporting each. Locked places stay.
+func (bs *BookmarkSync) pruned(places []Place) []Place {
+	if !bs.Prune {
+		return places
+	}
+	missing := withoutLocked(missingPlaces(places))
+	if len(missing) == 0 {
+		return places
+	}
+	for _, place := range missing {
+		if bs.Report != nil {
+			bs.Report.Pruned = append(bs.Report.Pruned, place)
+		} else {
+			fmt.Print(tr("Dropping %s (%s): the folder no longer exists\n", place.Label, place.Target))
+		}
+	}
+	return removeTargets(places, missing)
+}
+
+// parseAge parses a duration like time.ParseDuration does, and also in
+// days and weeks, like 90d or 2w
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// agedPlaces returns the places that weren't added or relabelled since
+// cutoff, going by the times of the canonical places file in entries or
+// else their provenance. Places whose age isn't known are kept.
+func agedPlaces(places []Place, entries map[string]canonicalPlace, provenance map[string]Provenance, cutoff time.Time) []Place {
+	var aged []Place
+	for _, place := range places {
+		key := normalizeTarget(place.Target)
+		changed := entries[key].Modified
+		if p, ok := provenance[key]; ok && changed.IsZero() {
+			changed = p.Added
+			if p.Modified.After(changed) {
+				changed = p.Modified
+			}
+		}
+		if !changed.IsZero() && changed.Before(cutoff) {
+			aged = append(aged, place)
+		}
+	}
+	return aged
+}
+
+// runPrune implements "bookmarksync prune", which removes the bookmarks
+// of folders that no longer exist from every backend, and with
+// --older-than those that weren't added or relabelled for that long.
+// Locked places are only removed with --force.
+func runPrune(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Only print the bookmarks that would be removed")
+	force := fs.Bool("force", false, "Also remove locked bookmarks")
+	var olderThan time.Duration
+	var age string
+	fs.Func("older-than", "Also remove bookmarks not added or relabelled for DURATION, like 90d", func(s string) (err error) {
+		age = s
+		olderThan, err = parseAge(s)
+		return err
+	})
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		return usageError("bookmarksync-go prune [--dry-run] [--force] [--older-than DURATION]")
+	}
+	keepLocked = !*force
+
+	bs := NewBookmarkSync()
+	var provenance map[string]Provenance
+	if olderThan > 0 {
+		state, err := LoadState()
+		if err != nil {
+			return err
+		}
+		provenance = state.Provenance
+	}
+	entries := bs.canonicalEntries()
+	cutoff := time.Now().Add(-olderThan)
+
+	var missing, aged []Place
+	for _, backend := range bs.Backends() {
+		places, err := backend.GetPlaces()
+		if err != nil {
+			return fmt.Errorf("failed to get places from %s: %v", backend.Name(), err)
+		}
+		missing = appendMissingPlaces(missing, missingPlaces(places))
+		if olderThan > 0 {
+			aged = append(aged, agedPlaces(places, entries, provenance, cutoff)...)
+		}
+	}
+	missing = withoutLocked(dedupePlaces(missing))
+	aged = removeTargets(withoutLocked(dedupePlaces(aged)), missing)
+	if len(missing) == 0 && len(aged) == 0 {
+		fmt.Print(tr("Every bookmarked folder exists\n"))
+		return nil
+	}
+	for _, place := range missing {
+		fmt.Print(tr("Dropping %s (%s): the folder no longer exists\n", place.Label, place.Target))
+	}
+	for _, place := range aged {
+		fmt.Print(tr("Dropping %s (%s): unchanged for longer than %s\n", place.Label, place.Target, age))
+	}
+	missing = append(missing, aged...)
+	if *dryRun {
+		return nil
+	}
+	return bs.UpdateAll(func(current []Place) []Place {
+		return removeTargets(current, missing)
+	})
+}
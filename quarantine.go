@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// corruptError is a backend file that exists but can't be parsed
+type corruptError struct {
+	File string
+	Err  error
+}
+
+func (e *corruptError) Error() string {
+	return fmt.Sprintf("%s is corrupt: %v", e.File, e.Err)
+}
+
+func (e *corruptError) Unwrap() error {
+	return e.Err
+}
+
+// isCorrupt reports whether err is a backend file that can't be parsed
+func isCorrupt(err error) bool {
+	var corrupt *corruptError
+	return errors.As(err, &corrupt)
+}
+
+// checkText returns a corruptError for the text file path when data has
+// NUL bytes, which a write cut short by a crash leaves in place of the text
+// it never got to. Line-based formats read anything else.
+func checkText(path string, data []byte) error {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return &corruptError{File: path, Err: fmt.Errorf("NUL byte at offset %d", i)}
+	}
+	return nil
+}
+
+// repairFiles lets backends put their files right when reading them: put
+// corrupt ones back from a backup or the state, and merge in the conflict
+// copies of file synchronizers. Commands that promise not to write anything
+// turn it off.
+var repairFiles = true
+
+// quarantineDir returns where the corrupt files of a backend are kept
+func quarantineDir(name string) (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "quarantine", strings.ReplaceAll(name, ":", "-")), nil
+}
+
+// quarantineBackend keeps a copy of a backend file it finds corrupt, then
+// puts the file back from the newest backup that parses or, failing that,
+// writes the places the backend held after the last sync. Syncs then go on
+// as if the file had never broken, rather than failing or taking it for a
+// backend without places.
+type quarantineBackend struct {
+	BookmarkSyncBackend
+}
+
+func (q *quarantineBackend) Capabilities() Capabilities {
+	return capabilitiesOf(q.BookmarkSyncBackend)
+}
+
+func (q *quarantineBackend) Unwrap() BookmarkSyncBackend {
+	return q.BookmarkSyncBackend
+}
+
+func (q *quarantineBackend) GetPlaces() ([]Place, error) {
+	places, err := q.BookmarkSyncBackend.GetPlaces()
+	if err == nil || !isCorrupt(err) || !repairFiles {
+		return places, err
+	}
+	return q.recover(err)
+}
+
+func (q *quarantineBackend) Merge(places []Place) error {
+	// Merging into a corrupt file would have nothing to merge with
+	return mergeInto(q, places)
+}
+
+func (q *quarantineBackend) Replace(places []Place) error {
+	err := q.BookmarkSyncBackend.Replace(places)
+	if err == nil || !isCorrupt(err) || !repairFiles {
+		return err
+	}
+	// Backends that keep more than places in their file, like KDE's
+	// system items, only replace the places of one that parses
+	if _, err := q.recover(err); err != nil {
+		return err
+	}
+	return q.BookmarkSyncBackend.Replace(places)
+}
+
+// recover quarantines the backend's files, which failed to parse with
+// corrupt, and puts them right. If nothing can, the files are left as they
+// were and corrupt is returned.
+func (q *quarantineBackend) recover(corrupt error) ([]Place, error) {
+	name := q.Name()
+	files, err := q.Files()
+	if err != nil {
+		return nil, corrupt
+	}
+	dir, err := quarantineDir(name)
+	if err != nil {
+		return nil, corrupt
+	}
+	quarantine := filepath.Join(dir, time.Now().Format(backupTimeFormat))
+	saved := make(map[string]string)
+	for _, file := range files {
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		saved[file] = filepath.Join(quarantine, filepath.Base(file))
+		if err := copyFileSynced(file, saved[file]); err != nil {
+			return nil, corrupt
+		}
+	}
+	// note records what happened next to the quarantined files, for doctor
+	note := func(recovery string) {
+		text := fmt.Sprintf("%s\n%s\n", corrupt, recovery)
+		os.WriteFile(filepath.Join(quarantine, "reason"), []byte(text), 0644)
+	}
+
+	// The newest backup that parses has the rest of the file, like KDE's
+	// system items, but the places the last sync left are newer
+	restored, places := "", []Place(nil)
+	if backups, err := backupsDir(name); err == nil {
+		entries, _ := os.ReadDir(backups)
+		for i := len(entries) - 1; i >= 0 && restored == ""; i-- {
+			if restoreBackup(q.BookmarkSyncBackend, entries[i].Name()) != nil {
+				continue
+			}
+			if places, err = q.BookmarkSyncBackend.GetPlaces(); err == nil {
+				restored = entries[i].Name()
+			}
+		}
+	}
+	var seen []Place
+	haveSeen := false
+	if state, err := LoadState(); err == nil {
+		seen, haveSeen = state.Seen[name]
+	}
+
+	switch {
+	case restored != "" && (!haveSeen || samePlaces(places, seen, capabilitiesOf(q))):
+		note(tr("Restored from backup %s", restored))
+		log.Print(tr("Warning: %v; kept a copy in %s and restored %s from backup %s", corrupt, quarantine, name, restored))
+		return places, nil
+	case haveSeen && q.BookmarkSyncBackend.Replace(seen) == nil:
+		note(tr("Rewritten with the places it had after the last sync"))
+		log.Print(tr("Warning: %v; kept a copy in %s and rewrote %s with the places it had after the last sync", corrupt, quarantine, name))
+		return seen, nil
+	}
+
+	// Nothing parses: leave the files as they were
+	for file, copy := range saved {
+		if data, err := os.ReadFile(copy); err == nil {
+			replaceFile(file, data)
+		}
+	}
+	if haveSeen {
+		note(tr("Not recovered: no backup parses, and the places of the last sync can't be written without one"))
+	} else {
+		note(tr("Not recovered: no backup parses and no sync recorded its places"))
+	}
+	return nil, corrupt
+}
+
+// quarantined is a corrupt file kept by quarantineBackend
+type quarantined struct {
+	Backend string
+	When    time.Time
+	Dir     string
+	Reason  string
+}
+
+// listQuarantined returns the quarantined files of every backend, oldest
+// first
+func listQuarantined() ([]quarantined, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	root := filepath.Join(dir, "quarantine")
+	backends, err := os.ReadDir(root)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var all []quarantined
+	for _, backend := range backends {
+		entries, err := os.ReadDir(filepath.Join(root, backend.Name()))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			when, err := time.ParseInLocation(backupTimeFormat, entry.Name(), time.Local)
+			if err != nil {
+				continue
+			}
+			path := filepath.Join(root, backend.Name(), entry.Name())
+			reason, _ := os.ReadFile(filepath.Join(path, "reason"))
+			all = append(all, quarantined{Backend: backend.Name(), When: when, Dir: path, Reason: strings.TrimSpace(string(reason))})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].When.Before(all[j].When) })
+	return all, nil
+}
+
+// runDoctor implements "bookmarksync doctor", which reads every backend and
+// shows the corrupt files that were quarantined, failing when a backend
+// can't be read
+func runDoctor(args []string) error {
+	if len(args) != 0 {
+		return usageError("bookmarksync-go doctor")
+	}
+	// Reading shouldn't repair anything behind the user's back here
+	repairFiles = false
+
+	var broken []string
+	for _, backend := range NewBookmarkSync().Backends() {
+		if _, err := backend.GetPlaces(); err != nil {
+			fmt.Print(tr("%s: %v\n", backend.Name(), err))
+			broken = append(broken, backend.Name())
+		} else {
+			fmt.Print(tr("%s: ok\n", backend.Name()))
+		}
+	}
+
+	all, err := listQuarantined()
+	if err != nil {
+		return err
+	}
+	if len(all) > 0 {
+		fmt.Print(tr("\nQuarantined files:\n"))
+	}
+	for _, q := range all {
+		fmt.Printf("%s\t%s\t%s\n", q.When.Format(time.DateTime), q.Backend, q.Dir)
+		for _, line := range strings.Split(q.Reason, "\n") {
+			if line != "" {
+				fmt.Printf("  %s\n", line)
+			}
+		}
+	}
+	if len(broken) > 0 {
+		return errors.New(tr("%s can't be read", strings.Join(broken, ", ")))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// corruptCanonical is a canonical places file a crash cut short
+const corruptCanonical = "[[place]]\nlabel = \"a\"\ntarget = \"file:///a"
+
+// testQuarantined returns the one quarantine of the canonical backend and
+// why it was made
+func testQuarantined(t *testing.T) quarantined {
+	t.Helper()
+	all, err := listQuarantined()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].Backend != "canonical" {
+		t.Fatalf("quarantined %v, want one canonical file", all)
+	}
+	data, err := os.ReadFile(filepath.Join(all[0].Dir, "places.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != corruptCanonical {
+		t.Errorf("kept %q, want the corrupt file", data)
+	}
+	return all[0]
+}
+
+func TestQuarantineRestoresBackup(t *testing.T) {
+	home := testHome(t)
+	path := filepath.Join(home, "places.toml")
+	canonical := &CanonicalBackend{Path: path}
+	if err := canonical.Replace(places("a", "file:///a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&backupBackend{canonical}).backup(); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, home, "places.toml", corruptCanonical)
+
+	got, err := (&quarantineBackend{canonical}).GetPlaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := places("a", "file:///a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("read %v, want the backup's %v", got, want)
+	}
+	if _, err := canonical.GetPlaces(); err != nil {
+		t.Errorf("file not restored: %v", err)
+	}
+	if q := testQuarantined(t); !strings.Contains(q.Reason, "Restored from backup") {
+		t.Errorf("reason %q", q.Reason)
+	}
+}
+
+func TestQuarantineRewritesSeenPlaces(t *testing.T) {
+	home := testHome(t)
+	path := writeFile(t, home, "places.toml", corruptCanonical)
+	state, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Seen = map[string][]Place{"canonical": places("b", "file:///b")}
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := (&quarantineBackend{&CanonicalBackend{Path: path}}).GetPlaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := places("b", "file:///b"); !reflect.DeepEqual(got, want) {
+		t.Errorf("read %v, want the last sync's %v", got, want)
+	}
+	if q := testQuarantined(t); !strings.Contains(q.Reason, "after the last sync") {
+		t.Errorf("reason %q", q.Reason)
+	}
+}
+
+func TestQuarantineWithoutBackups(t *testing.T) {
+	home := testHome(t)
+	path := writeFile(t, home, "places.toml", corruptCanonical)
+
+	_, err := (&quarantineBackend{&CanonicalBackend{Path: path}}).GetPlaces()
+	if !isCorrupt(err) {
+		t.Errorf("error %v, want the file's corruption", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != corruptCanonical {
+		t.Errorf("file changed to %q", data)
+	}
+	if q := testQuarantined(t); !strings.Contains(q.Reason, "Not recovered") {
+		t.Errorf("reason %q", q.Reason)
+	}
+}
+
+func TestQuarantineOff(t *testing.T) {
+	home := testHome(t)
+	path := writeFile(t, home, "places.toml", corruptCanonical)
+	repairFiles = false
+	t.Cleanup(func() { repairFiles = true })
+
+	if _, err := (&quarantineBackend{&CanonicalBackend{Path: path}}).GetPlaces(); !isCorrupt(err) {
+		t.Errorf("error %v, want the file's corruption", err)
+	}
+	if all, err := listQuarantined(); err != nil || len(all) != 0 {
+		t.Errorf("quarantined %v, %v without repairs", all, err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// readOnlyError reports the backends a sync left alone because their
+// files can't be written, as on live systems and kiosks
+type readOnlyError struct {
+	backends []string
+}
+
+func (e *readOnlyError) Error() string {
+	return tr("%s can't be written to (read-only filesystem or no permission)", strings.Join(e.backends, ", "))
+}
+
+func (e *readOnlyError) ExitCode() int {
+	return exitReadOnly
+}
+
+// unwritable returns the names of the backends whose files can't be
+// written, and "state" when the state directory, where transactions start,
+// can't be either
+func unwritable(backends []BookmarkSyncBackend) []string {
+	var names []string
+	if dir, err := stateDir(); err == nil && !dirWritable(dir) {
+		names = append(names, "state")
+	}
+	for _, backend := range backends {
+		files, err := backend.Files()
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if !dirWritable(filepath.Dir(file)) {
+				names = append(names, backend.Name())
+				break
+			}
+		}
+	}
+	return names
+}
+
+// dirWritable reports whether a file can be created in dir, or in the
+// nearest folder above it that exists when dir doesn't. Only a read-only
+// filesystem or missing permission count; other errors are left for the
+// write to report.
+func dirWritable(dir string) bool {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return true
+		}
+		dir = parent
+	}
+	file, err := os.CreateTemp(dir, ".bookmarksync-probe-*")
+	if err != nil {
+		return !errors.Is(err, syscall.EROFS) && !errors.Is(err, os.ErrPermission)
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return true
+}
+
+// checkWritable returns a readOnlyError if any of the backends a sync
+// writes can't be written, so that it stops before changing any of them
+func (bs *BookmarkSync) checkWritable(backends []BookmarkSyncBackend) error {
+	var written []BookmarkSyncBackend
+	for _, backend := range backends {
+		if bs.writable(backend.Name()) {
+			written = append(written, backend)
+		}
+	}
+	if names := unwritable(written); len(names) > 0 {
+		return &readOnlyError{backends: names}
+	}
+	return nil
+}
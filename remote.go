@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// RemoteBackend implements BookmarkSyncBackend for a canonical places file
+// on another host, read and written with ssh, so that a desktop and a
+// headless box share places without a repository in between. A copy of
+// what was last read or written there is kept in the state directory, and
+// read while the host can't be reached.
+type RemoteBackend struct {
+	// URL is the file's ssh://[USER@]HOST[:PORT]/PATH, with a PATH that
+	// starts with ~/ in the remote home
+	URL string
+}
+
+// remoteFile is a parsed remote URL
+type remoteFile struct {
+	// Destination is the [USER@]HOST ssh connects to
+	Destination string
+	Port        string
+	Path        string
+}
+
+// parseRemoteURL parses the URL of a RemoteBackend
+func parseRemoteURL(s string) (remoteFile, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return remoteFile{}, err
+	}
+	if u.Scheme != "ssh" || u.Hostname() == "" {
+		return remoteFile{}, fmt.Errorf("invalid remote %q: expected ssh://[USER@]HOST[:PORT]/PATH", s)
+	}
+	path := u.Path
+	if strings.HasPrefix(path, "/~/") {
+		path = path[1:]
+	}
+	if path == "" || path == "/" || strings.HasSuffix(path, "/") {
+		return remoteFile{}, fmt.Errorf("invalid remote %q: no file", s)
+	}
+	destination := u.Hostname()
+	if u.User != nil {
+		destination = u.User.Username() + "@" + destination
+	}
+	return remoteFile{Destination: destination, Port: u.Port(), Path: path}, nil
+}
+
+// shellPath quotes the remote path for the remote shell, leaving a leading
+// ~/ to it
+func (f remoteFile) shellPath() string {
+	if rest, ok := strings.CutPrefix(f.Path, "~/"); ok {
+		return `"$HOME"/` + shellQuote(rest)
+	}
+	return shellQuote(f.Path)
+}
+
+// remoteMissing is the status the read command exits with when the remote
+// file doesn't exist, which ssh passes on
+const remoteMissing = 3
+
+// ssh runs command on the remote host with stdin as its input, returning
+// its output
+func (f remoteFile) ssh(command string, stdin []byte) ([]byte, error) {
+	if err := checkKeyring(sshKeysInKeyring()); err != nil {
+		return nil, err
+	}
+	// Nobody answers prompts in the daemon: keys have to come from an
+	// agent or be without passphrase
+	args := []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=10"}
+	if f.Port != "" {
+		args = append(args, "-p", f.Port)
+	}
+	args = append(args, "--", f.Destination, command)
+	var stderr bytes.Buffer
+	cmd := exec.Command("ssh", args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%w: %s", err, message)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *RemoteBackend) Name() string {
+	return "remote"
+}
+
+// mirror is the copy of the remote file kept in the state directory
+func (r *RemoteBackend) mirror() (*CanonicalBackend, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	return &CanonicalBackend{Path: filepath.Join(dir, "remote", "places.toml")}, nil
+}
+
+func (r *RemoteBackend) Files() ([]string, error) {
+	mirror, err := r.mirror()
+	if err != nil {
+		return nil, err
+	}
+	return mirror.Files()
+}
+
+func (r *RemoteBackend) GetPlaces() ([]Place, error) {
+	file, err := parseRemoteURL(r.URL)
+	if err != nil {
+		return nil, err
+	}
+	mirror, err := r.mirror()
+	if err != nil {
+		return nil, err
+	}
+
+	path := file.shellPath()
+	data, err := file.ssh(fmt.Sprintf("test -e %s || exit %d; cat %s", path, remoteMissing, path), nil)
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == remoteMissing:
+		return []Place{}, nil
+	case err != nil:
+		log.Print(tr("Warning: failed to read the places from %s, using those read last: %v", r.URL, err))
+		return mirror.GetPlaces()
+	}
+
+	plain, err := openPlaces(r.URL, data)
+	if err != nil {
+		return nil, err
+	}
+	places, err := hostPlaces(plain)
+	if err != nil {
+		return nil, &corruptError{File: r.URL, Err: err}
+	}
+	// As it is there, with the times of the places
+	if err := mirror.keep(data); err != nil {
+		return nil, err
+	}
+	return places, nil
+}
+
+func (r *RemoteBackend) Merge(places []Place) error {
+	return mergeInto(r, places)
+}
+
+func (r *RemoteBackend) Replace(places []Place) error {
+	file, err := parseRemoteURL(r.URL)
+	if err != nil {
+		return err
+	}
+	mirror, err := r.mirror()
+	if err != nil {
+		return err
+	}
+	places, err = exportedPlaces(places)
+	if err != nil {
+		return err
+	}
+	data, err := mirror.Render(places)
+	if err != nil {
+		return err
+	}
+	if data, err = sealPlaces(data, ""); err != nil {
+		return err
+	}
+
+	// Written next to the file and moved over it, so that the other host
+	// never reads half of it
+	path := file.shellPath()
+	tmp := file
+	tmp.Path += ".bookmarksync"
+	command := fmt.Sprintf(`mkdir -p "$(dirname %s)" && cat > %s && mv %s %s`, path, tmp.shellPath(), tmp.shellPath(), path)
+	if _, err := file.ssh(command, data); err != nil {
+		return fmt.Errorf("failed to write the places to %s: %w", r.URL, err)
+	}
+	return mirror.keep(data)
+}
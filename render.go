@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// renderer is implemented by backends that keep their places in one file:
+// Render returns the exact contents Replace writes there for places
+type renderer interface {
+	Render(places []Place) ([]byte, error)
+}
+
+// rendererOf finds the renderer of a backend, looking through the
+// wrappers AddBackend puts around it
+func rendererOf(backend BookmarkSyncBackend) (renderer, bool) {
+	for {
+		if r, ok := backend.(renderer); ok {
+			return r, true
+		}
+		wrapper, ok := backend.(interface{ Unwrap() BookmarkSyncBackend })
+		if !ok {
+			return nil, false
+		}
+		backend = wrapper.Unwrap()
+	}
+}
+
+// runRender implements "bookmarksync render --backend BACKEND [--from
+// SOURCE]", which prints the file a backend would be written with instead
+// of writing it: its own places, or what a sync from SOURCE would leave
+// there. Comparing it with a known-good file helps with toolkit quirks.
+func runRender(args []string) error {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	name := fs.String("backend", "", "Backend to render the file of")
+	from := fs.String("from", "", "Render what a sync from this backend would write")
+	fs.Parse(args)
+	if *name == "" || fs.NArg() != 0 {
+		return usageError("bookmarksync-go render --backend BACKEND [--from BACKEND]")
+	}
+
+	bs := NewBookmarkSync()
+	bs.Merge = config.Merge
+	backend, exists := bs.backends[strings.ToLower(*name)]
+	if !exists {
+		return fmt.Errorf("unknown backend: %s", *name)
+	}
+	r, ok := rendererOf(backend)
+	if !ok {
+		return fmt.Errorf("%s isn't written as a single file and can't be rendered", backend.Name())
+	}
+
+	places, err := backend.GetPlaces()
+	if err != nil {
+		return fmt.Errorf("failed to get places from %s: %v", backend.Name(), err)
+	}
+	if *from != "" {
+		source, exists := bs.backends[strings.ToLower(*from)]
+		if !exists {
+			return fmt.Errorf("unknown backend: %s", *from)
+		}
+		synced, err := bs.readSource(source)
+		if err != nil {
+			return err
+		}
+		state, err := LoadState()
+		if err != nil {
+			return err
+		}
+		places = bs.written(state, backend.Name(), capabilitiesOf(backend), places, synced)
+	}
+
+	data, err := r.Render(places)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
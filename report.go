@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// jsonOutput is set by the global --json flag: commands print structured
+// JSON instead of text
+var jsonOutput bool
+
+// SyncReport is the outcome of one sync, printed as a single JSON line
+// with --json
+type SyncReport struct {
+	Mode      string          `json:"mode"`
+	Source    string          `json:"source,omitempty"`
+	DryRun    bool            `json:"dry_run,omitempty"`
+	Backends  []BackendReport `json:"backends"`
+	Conflicts []string        `json:"conflicts,omitempty"`
+	Pruned    []Place         `json:"pruned,omitempty"`
+	Messages  []string        `json:"messages,omitempty"`
+	Warnings  []string        `json:"warnings,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	OK        bool            `json:"ok"`
+}
+
+// BackendReport is what a sync did, or with --dry-run would do, to one
+// backend. Status is one of written, unchanged, failed or planned.
+type BackendReport struct {
+	Name      string         `json:"name"`
+	Status    string         `json:"status"`
+	Error     string         `json:"error,omitempty"`
+	Added     []Place        `json:"added,omitempty"`
+	Removed   []Place        `json:"removed,omitempty"`
+	Moved     []PlaceMove    `json:"moved,omitempty"`
+	Relabeled []PlaceRelabel `json:"relabeled,omitempty"`
+	Reordered bool           `json:"reordered,omitempty"`
+}
+
+// PlaceMove is a place that kept its label but changed target
+type PlaceMove struct {
+	Label string `json:"label"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// PlaceRelabel is a place whose label changed
+type PlaceRelabel struct {
+	Target string `json:"target"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// say prints a progress message, or records it in the report with --json
+func (bs *BookmarkSync) say(message string) {
+	if bs.Report != nil {
+		bs.Report.Messages = append(bs.Report.Messages, strings.TrimSpace(message))
+		return
+	}
+	fmt.Print(message)
+}
+
+// noteBackend records in the report, and under systemd in the journal,
+// what happened to a backend
+func (bs *BookmarkSync) noteBackend(name, status string, err error) {
+	bs.Stats.noteBackend(name, status, err)
+	if journald != nil {
+		priority, message := journalInfo, fmt.Sprintf("%s: %s", name, status)
+		if err != nil {
+			priority, message, status = journalWarning, fmt.Sprintf("%s: %v", name, err), "failed"
+		}
+		journald.Send(priority, message, map[string]string{"BACKEND": name, "OP": status})
+	}
+	if bs.Report == nil {
+		return
+	}
+	backend := BackendReport{Name: name, Status: status}
+	if err != nil {
+		backend.Status = "failed"
+		backend.Error = err.Error()
+	}
+	bs.Report.Backends = append(bs.Report.Backends, backend)
+}
+
+// withReport runs a sync with a fresh report and prints it as one JSON
+// line, so a daemon emits one line per sync
+func (bs *BookmarkSync) withReport(report *SyncReport, run func() error) error {
+	bs.Report = report
+	err := run()
+	bs.Report = nil
+
+	if report.Backends == nil {
+		report.Backends = []BackendReport{}
+	}
+	report.OK = err == nil
+	if err != nil {
+		report.Error = err.Error()
+	}
+	data, jsonErr := json.Marshal(report)
+	if jsonErr != nil {
+		return jsonErr
+	}
+	fmt.Println(string(data))
+	return err
+}
+
+// reportLog collects log output as warnings of the current report,
+// passing it to stderr between syncs
+type reportLog struct {
+	bs *BookmarkSync
+	mu sync.Mutex
+}
+
+func (l *reportLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if report := l.bs.Report; report != nil {
+		report.Warnings = append(report.Warnings, strings.TrimSpace(string(p)))
+		return len(p), nil
+	}
+	return os.Stderr.Write(p)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy says how often an operation failing with a class of errors is
+// tried, and how long to wait before the first retry; the wait doubles on
+// every further retry
+type RetryPolicy struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+// errorClasses group the errors worth retrying on network and automounted
+// home directories
+var errorClasses = map[string][]syscall.Errno{
+	"io":      {syscall.EIO},
+	"stale":   {syscall.ESTALE},
+	"busy":    {syscall.EAGAIN, syscall.EBUSY, syscall.EINTR},
+	"timeout": {syscall.ETIMEDOUT},
+}
+
+// defaultRetryPolicies are used for error classes not configured with --retry
+var defaultRetryPolicies = map[string]RetryPolicy{
+	"io":      {Attempts: 3, Backoff: 100 * time.Millisecond},
+	"stale":   {Attempts: 5, Backoff: 100 * time.Millisecond},
+	"busy":    {Attempts: 3, Backoff: 50 * time.Millisecond},
+	"timeout": {Attempts: 3, Backoff: 500 * time.Millisecond},
+}
+
+// errorClass returns the retry class of err, or "" if it is not transient.
+// Backends often wrap errors with %v, so the errno's message is matched
+// when the errno itself is no longer reachable.
+func errorClass(err error) string {
+	var errno syscall.Errno
+	hasErrno := errors.As(err, &errno)
+	for class, errnos := range errorClasses {
+		for _, candidate := range errnos {
+			if hasErrno && errno == candidate {
+				return class
+			}
+			if !hasErrno && strings.Contains(err.Error(), candidate.Error()) {
+				return class
+			}
+		}
+	}
+	return ""
+}
+
+// retryBackend retries a backend's reads and writes on transient errors
+type retryBackend struct {
+	BookmarkSyncBackend
+	policies map[string]RetryPolicy
+}
+
+func (r *retryBackend) Capabilities() Capabilities {
+	return capabilitiesOf(r.BookmarkSyncBackend)
+}
+
+func (r *retryBackend) Unwrap() BookmarkSyncBackend {
+	return r.BookmarkSyncBackend
+}
+
+func (r *retryBackend) GetPlaces() ([]Place, error) {
+	var places []Place
+	err := r.retry("Warning: failed to read %s (%v), retrying in %s", func() error {
+		var err error
+		places, err = r.BookmarkSyncBackend.GetPlaces()
+		return err
+	})
+	return places, err
+}
+
+func (r *retryBackend) Replace(places []Place) error {
+	return r.retry("Warning: failed to write %s (%v), retrying in %s", func() error {
+		return r.BookmarkSyncBackend.Replace(places)
+	})
+}
+
+func (r *retryBackend) Merge(places []Place) error {
+	return r.retry("Warning: failed to write %s (%v), retrying in %s", func() error {
+		return r.BookmarkSyncBackend.Merge(places)
+	})
+}
+
+// retry runs op until it succeeds, fails with a non-transient error, or its
+// error class runs out of attempts. warning is logged before every retry.
+func (r *retryBackend) retry(warning string, op func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		class := errorClass(err)
+		policy, ok := r.policies[class]
+		if class == "" || !ok || attempt >= policy.Attempts {
+			return err
+		}
+		wait := policy.Backoff << (attempt - 1)
+		log.Print(tr(warning, r.Name(), err, wait))
+		time.Sleep(wait)
+	}
+}
+
+// retryFlag collects --retry CLASS=ATTEMPTS[:BACKOFF] policies
+type retryFlag map[string]RetryPolicy
+
+func (f retryFlag) String() string {
+	var policies []string
+	for class, policy := range f {
+		policies = append(policies, fmt.Sprintf("%s=%d:%s", class, policy.Attempts, policy.Backoff))
+	}
+	return strings.Join(policies, ",")
+}
+
+func (f retryFlag) Set(value string) error {
+	class, spec, ok := strings.Cut(value, "=")
+	class = strings.ToLower(strings.TrimSpace(class))
+	if _, known := errorClasses[class]; !ok || !known {
+		return fmt.Errorf("expected CLASS=ATTEMPTS[:BACKOFF] with CLASS one of io, stale, busy, timeout, got %q", value)
+	}
+
+	policy := defaultRetryPolicies[class]
+	attempts, backoff, hasBackoff := strings.Cut(spec, ":")
+	n, err := strconv.Atoi(attempts)
+	if err != nil || n < 1 {
+		return fmt.Errorf("invalid number of attempts %q", attempts)
+	}
+	policy.Attempts = n
+	if hasBackoff {
+		if policy.Backoff, err = time.ParseDuration(backoff); err != nil {
+			return err
+		}
+	}
+	f[class] = policy
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyBackend fails its first calls with err
+type flakyBackend struct {
+	*GTKBackend
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyBackend) fail() error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func (f *flakyBackend) GetPlaces() ([]Place, error) {
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+	return f.GTKBackend.GetPlaces()
+}
+
+func (f *flakyBackend) Replace(places []Place) error {
+	if err := f.fail(); err != nil {
+		return err
+	}
+	return f.GTKBackend.Replace(places)
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err   error
+		class string
+	}{
+		{&os.PathError{Op: "read", Path: "bookmarks", Err: syscall.EIO}, "io"},
+		{fmt.Errorf("failed to read: %v", syscall.ESTALE), "stale"},
+		{fmt.Errorf("failed to write: %w", syscall.EAGAIN), "busy"},
+		{&os.PathError{Op: "open", Path: "bookmarks", Err: syscall.ENOENT}, ""},
+		{errors.New("line 3: expected a URL"), ""},
+	}
+	for _, test := range tests {
+		if class := errorClass(test.err); class != test.class {
+			t.Errorf("errorClass(%v) = %q, want %q", test.err, class, test.class)
+		}
+	}
+}
+
+func TestRetryBackend(t *testing.T) {
+	home := testHome(t)
+	gtk := writeFile(t, home, "bookmarks", "file:///a a\n")
+	policies := map[string]RetryPolicy{"io": {Attempts: 3, Backoff: time.Millisecond}}
+	eio := &os.PathError{Op: "read", Path: gtk, Err: syscall.EIO}
+
+	// Transient errors are retried
+	flaky := &flakyBackend{GTKBackend: &GTKBackend{Path: gtk}, failures: 2, err: eio}
+	places, err := (&retryBackend{flaky, policies}).GetPlaces()
+	if err != nil || len(places) != 1 {
+		t.Errorf("read %v, %v after two failures", places, err)
+	}
+	if flaky.calls != 3 {
+		t.Errorf("read %d times, want 3", flaky.calls)
+	}
+
+	// Until the attempts run out
+	flaky = &flakyBackend{GTKBackend: &GTKBackend{Path: gtk}, failures: 3, err: eio}
+	if err := (&retryBackend{flaky, policies}).Replace(nil); !errors.Is(err, syscall.EIO) {
+		t.Errorf("error %v, want the last EIO", err)
+	}
+	if flaky.calls != 3 {
+		t.Errorf("written %d times, want 3", flaky.calls)
+	}
+	if data, _ := os.ReadFile(gtk); string(data) != "file:///a a\n" {
+		t.Errorf("gtk holds %q", data)
+	}
+
+	// Others fail right away, as do classes without a policy
+	for _, err := range []error{os.ErrPermission, &os.PathError{Op: "read", Path: gtk, Err: syscall.ESTALE}} {
+		flaky = &flakyBackend{GTKBackend: &GTKBackend{Path: gtk}, failures: 1, err: err}
+		if _, got := (&retryBackend{flaky, policies}).GetPlaces(); !errors.Is(got, err) || flaky.calls != 1 {
+			t.Errorf("%v: error %v after %d reads, want it after one", err, got, flaky.calls)
+		}
+	}
+}
+
+func TestRetryFlag(t *testing.T) {
+	flag := retryFlag{}
+	if err := flag.Set("stale=7:1s"); err != nil {
+		t.Fatal(err)
+	}
+	if err := flag.Set("IO=1"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (RetryPolicy{Attempts: 7, Backoff: time.Second}); flag["stale"] != want {
+		t.Errorf("stale = %+v, want %+v", flag["stale"], want)
+	}
+	if want := (RetryPolicy{Attempts: 1, Backoff: defaultRetryPolicies["io"].Backoff}); flag["io"] != want {
+		t.Errorf("io = %+v, want %+v", flag["io"], want)
+	}
+	for _, value := range []string{"disk=3", "io=0", "io=many", "busy=2:soon"} {
+		if err := flag.Set(value); err == nil {
+			t.Errorf("--retry %s accepted", value)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Directions of a [[rewrite]] rule
+const (
+	// rewriteBoth rewrites targets written to the backends, and those read
+	// from them back, the default of prefix rules
+	rewriteBoth = "both"
+	// rewriteWrite only rewrites targets written, the default of re: rules
+	rewriteWrite = "write"
+	// rewriteRead only rewrites targets read
+	rewriteRead = "read"
+)
+
+// rewriteRule is a [[rewrite]] table of the configuration: the targets of
+// the places written to its backends that start with From, a path or URL
+// prefix, start with To there instead, and those read from them that start
+// with To with From again. A From written re:EXPR is a regular expression
+// whose matches are replaced by To, with $1 for its groups; as that can't
+// be undone, it only rewrites writes unless it says otherwise.
+//
+// Local places are matched by their path, and come out local when what
+// they are rewritten to is one.
+type rewriteRule struct {
+	// Backends are the backends the rule is for
+	Backends []string `toml:"backends"`
+	// Hosts are the hostnames or globs of the machines the rule is for,
+	// every one when empty
+	Hosts     []string `toml:"hosts"`
+	From      string   `toml:"from"`
+	To        string   `toml:"to"`
+	Direction string   `toml:"direction"`
+
+	re *regexp.Regexp
+}
+
+// check validates the rule, and compiles its regular expression or expands
+// ~ in its paths
+func (r *rewriteRule) check() error {
+	if len(r.Backends) == 0 {
+		return fmt.Errorf("rewrite from %q: no backends", r.From)
+	}
+	if r.From == "" {
+		return fmt.Errorf("rewrite of %s: no from", strings.Join(r.Backends, ", "))
+	}
+	for _, host := range r.Hosts {
+		if _, err := path.Match(host, ""); err != nil || host == "" {
+			return fmt.Errorf("rewrite from %q: invalid host %q", r.From, host)
+		}
+	}
+	if expr, ok := strings.CutPrefix(r.From, "re:"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("rewrite from %q: %v", r.From, err)
+		}
+		r.re = re
+	} else {
+		var err error
+		if r.From, err = expandHome(r.From); err != nil {
+			return err
+		}
+		if r.To, err = expandHome(r.To); err != nil {
+			return err
+		}
+	}
+	switch {
+	case r.Direction == "" && r.re != nil:
+		r.Direction = rewriteWrite
+	case r.Direction == "":
+		r.Direction = rewriteBoth
+	case r.Direction == rewriteBoth && r.re != nil:
+		return fmt.Errorf("rewrite from %q: a re: rule can't be undone on read; add one with direction = \"read\" for that", r.From)
+	case r.Direction != rewriteBoth && r.Direction != rewriteWrite && r.Direction != rewriteRead:
+		return fmt.Errorf("rewrite from %q: unknown direction %q, expected both, write or read", r.From, r.Direction)
+	}
+	if r.Direction == rewriteBoth && strings.Trim(r.To, "/") == "" {
+		// Read back, it would match every path
+		return fmt.Errorf("rewrite from %q: a rule to %q can't be undone on read; give it direction = \"write\"", r.From, r.To)
+	}
+	return nil
+}
+
+// appliesTo reports whether the rule rewrites the backend called name,
+// on this host
+func (r *rewriteRule) appliesTo(name string) bool {
+	return slices.ContainsFunc(r.Backends, func(backend string) bool {
+		return strings.EqualFold(backend, name)
+	}) && onThisHost(r.Hosts)
+}
+
+// rewrite returns target rewritten by the rule, one way or the other, and
+// whether the rule matched it
+func (r *rewriteRule) rewrite(target string, reading bool) (string, bool) {
+	subject := target
+	if local, err := localPath(target); err == nil {
+		subject = local
+	}
+	from, to := r.From, r.To
+	if reading && r.Direction == rewriteBoth {
+		from, to = to, from
+	}
+
+	var rewritten string
+	switch {
+	case r.re != nil:
+		if !r.re.MatchString(subject) {
+			return target, false
+		}
+		rewritten = r.re.ReplaceAllString(subject, to)
+	case subject == from:
+		rewritten = to
+	case strings.HasPrefix(subject, from) && (strings.HasSuffix(from, "/") || subject[len(from)] == '/'):
+		// On a path or URL boundary, so /work doesn't rewrite /workshop
+		rewritten = strings.TrimSuffix(to, "/") + "/" + strings.TrimPrefix(subject[len(from):], "/")
+	default:
+		return target, false
+	}
+	if strings.HasPrefix(rewritten, "/") {
+		return fileURI(rewritten), true
+	}
+	return rewritten, true
+}
+
+// rewriteTargets rewrites the targets of places written to, or with
+// reading read from, the backend called name by the first of the rules
+// that matches each
+func rewriteTargets(name string, places []Place, reading bool) []Place {
+	var rules []*rewriteRule
+	for i := range config.Rewrite {
+		rule := &config.Rewrite[i]
+		if !rule.appliesTo(name) {
+			continue
+		}
+		if (reading && rule.Direction != rewriteWrite) || (!reading && rule.Direction != rewriteRead) {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return places
+	}
+	rewritten := make([]Place, len(places))
+	for i, place := range places {
+		for _, rule := range rules {
+			if target, ok := rule.rewrite(place.Target, reading); ok {
+				place.Target = target
+				break
+			}
+		}
+		rewritten[i] = place
+	}
+	return rewritten
+}
+
+// rewriteBackend applies the [[rewrite]] rules of a backend to the places
+// written to it and read from it, so that every other backend sees the
+// places as if the rules weren't there
+type rewriteBackend struct {
+	BookmarkSyncBackend
+}
+
+func (r *rewriteBackend) Capabilities() Capabilities {
+	return capabilitiesOf(r.BookmarkSyncBackend)
+}
+
+func (r *rewriteBackend) Unwrap() BookmarkSyncBackend {
+	return r.BookmarkSyncBackend
+}
+
+func (r *rewriteBackend) GetPlaces() ([]Place, error) {
+	places, err := r.BookmarkSyncBackend.GetPlaces()
+	if err != nil {
+		return nil, err
+	}
+	return rewriteTargets(r.Name(), places, true), nil
+}
+
+func (r *rewriteBackend) Merge(places []Place) error {
+	return r.BookmarkSyncBackend.Merge(rewriteTargets(r.Name(), places, false))
+}
+
+func (r *rewriteBackend) Replace(places []Place) error {
+	return r.BookmarkSyncBackend.Replace(rewriteTargets(r.Name(), places, false))
+}
+
+func (r *rewriteBackend) Render(places []Place) ([]byte, error) {
+	inner, ok := rendererOf(r.BookmarkSyncBackend)
+	if !ok {
+		return nil, fmt.Errorf("%s isn't written as a single file and can't be rendered", r.Name())
+	}
+	return inner.Render(rewriteTargets(r.Name(), places, false))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRewrite sets the rewrite rules of the configuration, checked
+func testRewrite(t *testing.T, rules ...rewriteRule) {
+	t.Helper()
+	for i := range rules {
+		if err := rules[i].check(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config.Rewrite = rules
+}
+
+func TestRewriteRoundTrip(t *testing.T) {
+	home := testHome(t)
+	testRewrite(t,
+		rewriteRule{Backends: []string{"gtk"}, From: "~/work", To: "/mnt/work"},
+		rewriteRule{Backends: []string{"GTK"}, From: "sftp://old.example.com/", To: "sftp://new.example.com/"},
+	)
+	work := fileURI(filepath.Join(home, "work"))
+	original := places(
+		"work", work,
+		"notes", work+"/notes",
+		"workshop", fileURI(filepath.Join(home, "workshop")),
+		"server", "sftp://old.example.com/srv",
+		"other", "file:///mnt/other",
+	)
+	want := places(
+		"work", "file:///mnt/work",
+		"notes", "file:///mnt/work/notes",
+		"workshop", fileURI(filepath.Join(home, "workshop")),
+		"server", "sftp://new.example.com/srv",
+		"other", "file:///mnt/other",
+	)
+
+	written := rewriteTargets("gtk", original, false)
+	if !reflect.DeepEqual(written, want) {
+		t.Errorf("written %v, want %v", written, want)
+	}
+	if read := rewriteTargets("gtk", written, true); !reflect.DeepEqual(read, original) {
+		t.Errorf("read back %v, want %v", read, original)
+	}
+	if other := rewriteTargets("kde", original, false); !reflect.DeepEqual(other, original) {
+		t.Errorf("kde written %v", other)
+	}
+
+	// Through the backend, the file holds the rewritten places and the
+	// others get the originals back
+	gtk := &GTKBackend{Path: filepath.Join(home, "bookmarks")}
+	backend := &rewriteBackend{gtk}
+	if err := backend.Replace(original); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := gtk.GetPlaces(); !reflect.DeepEqual(stored, want) {
+		t.Errorf("stored %v, want %v", stored, want)
+	}
+	if read, _ := backend.GetPlaces(); !reflect.DeepEqual(read, original) {
+		t.Errorf("read %v, want %v", read, original)
+	}
+}
+
+func TestRewriteDirections(t *testing.T) {
+	testHome(t)
+	testRewrite(t,
+		rewriteRule{Backends: []string{"gtk"}, From: `re:^/media/(\w+)/`, To: "/run/media/$1/"},
+		rewriteRule{Backends: []string{"gtk"}, From: "/srv", To: "/data", Direction: rewriteRead},
+	)
+	written := rewriteTargets("gtk", places("usb", "file:///media/stick/photos", "srv", "file:///srv"), false)
+	if want := places("usb", "file:///run/media/stick/photos", "srv", "file:///srv"); !reflect.DeepEqual(written, want) {
+		t.Errorf("written %v, want %v", written, want)
+	}
+	read := rewriteTargets("gtk", places("usb", "file:///run/media/stick/photos", "srv", "file:///srv/x"), true)
+	if want := places("usb", "file:///run/media/stick/photos", "srv", "file:///data/x"); !reflect.DeepEqual(read, want) {
+		t.Errorf("read %v, want %v", read, want)
+	}
+}
+
+func TestRewriteHosts(t *testing.T) {
+	testHome(t)
+	testRewrite(t, rewriteRule{Backends: []string{"gtk"}, Hosts: []string{"not-this-*"}, From: "/a", To: "/b"})
+	if strings.HasPrefix(hostname(), "not-this-") {
+		t.Skip("the host matches the rule")
+	}
+	input := places("a", "file:///a")
+	if got := rewriteTargets("gtk", input, false); !reflect.DeepEqual(got, input) {
+		t.Errorf("rule for another host rewrote %v", got)
+	}
+}
+
+func TestRewriteCheck(t *testing.T) {
+	tests := []struct {
+		rule rewriteRule
+		want string
+	}{
+		{rewriteRule{From: "/a", To: "/b"}, "no backends"},
+		{rewriteRule{Backends: []string{"gtk"}, To: "/b"}, "no from"},
+		{rewriteRule{Backends: []string{"gtk"}, From: "re:(", To: "/b"}, "missing closing )"},
+		{rewriteRule{Backends: []string{"gtk"}, From: "re:a", To: "/b", Direction: rewriteBoth}, "can't be undone on read"},
+		{rewriteRule{Backends: []string{"gtk"}, From: "/a", To: "/"}, "can't be undone on read"},
+		{rewriteRule{Backends: []string{"gtk"}, From: "/a", To: "/b", Direction: "sideways"}, "unknown direction"},
+		{rewriteRule{Backends: []string{"gtk"}, Hosts: []string{"["}, From: "/a", To: "/b"}, "invalid host"},
+	}
+	for _, test := range tests {
+		err := test.rule.check()
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("check of %+v: %v, want %q", test.rule, err, test.want)
+		}
+	}
+
+	rule := rewriteRule{Backends: []string{"gtk"}, From: "/a", To: "/", Direction: rewriteWrite}
+	if err := rule.check(); err != nil {
+		t.Errorf("write-only rule to /: %v", err)
+	}
+}
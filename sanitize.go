@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"unicode"
+)
+
+// unsafeSchemes are target schemes that carry code or content instead of
+// pointing at a location
+var unsafeSchemes = []string{"javascript", "data", "vbscript"}
+
+// targetProblem returns why a target read from a backend isn't safe to
+// copy to others, or "" if it is
+func targetProblem(target string) string {
+	if strings.IndexFunc(target, unicode.IsControl) >= 0 {
+		return tr("it contains control characters")
+	}
+	if scheme, _, ok := strings.Cut(target, ":"); ok {
+		scheme = strings.ToLower(strings.TrimSpace(scheme))
+		for _, unsafe := range unsafeSchemes {
+			if scheme == unsafe {
+				return tr("%s: targets aren't places", unsafe)
+			}
+		}
+	}
+	return ""
+}
+
+// sanitizeLabel replaces control characters in a label with spaces and
+// drops the invisible ones that reorder text
+func sanitizeLabel(label string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case unicode.Is(unicode.Bidi_Control, r):
+			return -1
+		case unicode.IsControl(r):
+			return ' '
+		}
+		return r
+	}, label)
+}
+
+// sanitizePlaces drops the places read from source whose targets are
+// unsafe and cleans up their labels, unless AllowUnsafe is set. Places
+// now come from many files and remotes, and a crafted one shouldn't end
+// up in every file dialog. Those with a scheme the policy file forbids are
+// dropped either way.
+func (bs *BookmarkSync) sanitizePlaces(source string, places []Place) []Place {
+	allowed := make([]Place, 0, len(places))
+	for _, place := range places {
+		if scheme := forbiddenScheme(place.Target); scheme != "" {
+			log.Print(tr("Warning: ignoring %q from %s: %s: places are forbidden by %s", place.Target, source, scheme, policyFile))
+			continue
+		}
+		allowed = append(allowed, place)
+	}
+	places = allowed
+	if bs.AllowUnsafe {
+		return places
+	}
+	safe := make([]Place, 0, len(places))
+	for _, place := range places {
+		if problem := targetProblem(place.Target); problem != "" {
+			log.Print(tr("Warning: ignoring %q from %s: %s (use --allow-unsafe to copy it anyway)", place.Target, source, problem))
+			continue
+		}
+		place.Label = sanitizeLabel(place.Label)
+		safe = append(safe, place)
+	}
+	return safe
+}
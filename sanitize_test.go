@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSanitizeLabel(t *testing.T) {
+	tests := []struct{ label, want string }{
+		{"Documents", "Documents"},
+		{"two\nlines\ttabbed", "two lines tabbed"},
+		{"invoice\u202efdp.exe", "invoicefdp.exe"},
+		{"\u2066isolated\u2069 \u200fmark", "isolated mark"},
+		{"Ünïcödé 文件", "Ünïcödé 文件"},
+	}
+	for _, test := range tests {
+		if got := sanitizeLabel(test.label); got != test.want {
+			t.Errorf("sanitizeLabel(%q) = %q, want %q", test.label, got, test.want)
+		}
+	}
+}
+
+func TestSanitizePlaces(t *testing.T) {
+	testHome(t)
+	config.ForbidSchemes = []string{"ftp"}
+	read := places(
+		"ok", "file:///a",
+		"script", "JavaScript:alert(1)",
+		"inline", "data:text/html,<b>hi</b>",
+		"broken", "file:///a\nb",
+		"rtl\u202etxt.exe", "file:///b",
+		"old", "ftp://host/pub",
+	)
+
+	bs := &BookmarkSync{}
+	if got, want := bs.sanitizePlaces("gtk", read), places("ok", "file:///a", "rtltxt.exe", "file:///b"); !reflect.DeepEqual(got, want) {
+		t.Errorf("sanitizePlaces() = %v, want %v", got, want)
+	}
+
+	// --allow-unsafe copies them as they are, but not what the policy
+	// forbids
+	bs.AllowUnsafe = true
+	if got, want := bs.sanitizePlaces("gtk", read), read[:5]; !reflect.DeepEqual(got, want) {
+		t.Errorf("sanitizePlaces() with AllowUnsafe = %v, want %v", got, want)
+	}
+}
+
+func TestSyncDropsUnsafePlaces(t *testing.T) {
+	home := testHome(t)
+	testWithoutGTK2(t)
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\njavascript:alert(1) click\n")
+	if err := runSync([]string{"--from", "gtk"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := kdePlaces(t), places("a", "file:///a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("kde holds %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ScriptsBackend implements BookmarkSyncBackend for the scripts folder of
+// Nautilus or Nemo. Every place becomes an executable script in a
+// Bookmarks submenu of the file manager's context menu, opening the place
+// in a new window. Like Wine shortcuts the scripts are write-only, and
+// only the ones listed in the manifest are ever removed.
+type ScriptsBackend struct {
+	// FileManager is the file manager's command, also the name of its data
+	// directory: nautilus or nemo
+	FileManager string
+}
+
+// scriptsFileManagers are the file managers that run scripts from
+// $XDG_DATA_HOME/NAME/scripts
+var scriptsFileManagers = []string{"nautilus", "nemo"}
+
+// scriptsSubmenu is the folder, and so the context menu entry, holding the
+// scripts
+const scriptsSubmenu = "Bookmarks"
+
+// ScriptsBackends returns a backend for every installed file manager that
+// supports scripts
+func ScriptsBackends() ([]BookmarkSyncBackend, error) {
+	dataHome, err := xdgDataHome()
+	if err != nil {
+		return nil, err
+	}
+	var backends []BookmarkSyncBackend
+	for _, name := range scriptsFileManagers {
+		_, lookErr := exec.LookPath(name)
+		_, statErr := os.Stat(filepath.Join(dataHome, name))
+		if lookErr == nil || statErr == nil {
+			backends = append(backends, &ScriptsBackend{FileManager: name})
+		}
+	}
+	return backends, nil
+}
+
+func (s *ScriptsBackend) Name() string {
+	return "scripts:" + s.FileManager
+}
+
+// dir returns the folder the scripts are written to
+func (s *ScriptsBackend) dir() (string, error) {
+	dataHome, err := xdgDataHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataHome, s.FileManager, "scripts", scriptsSubmenu), nil
+}
+
+func (s *ScriptsBackend) Files() ([]string, error) {
+	dir, err := s.dir()
+	if err != nil {
+		return nil, err
+	}
+	return shortcutFiles(dir), nil
+}
+
+func (s *ScriptsBackend) GetPlaces() ([]Place, error) {
+	dir, err := s.dir()
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := readShortcutManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	places := []Place{}
+	for _, entry := range manifest {
+		places = append(places, entry.Place)
+	}
+	return places, nil
+}
+
+func (s *ScriptsBackend) Merge(places []Place) error {
+	return mergeInto(s, places)
+}
+
+func (s *ScriptsBackend) Replace(places []Place) error {
+	dir, err := s.dir()
+	if err != nil {
+		return err
+	}
+
+	previous, err := readShortcutManifest(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range previous {
+		if err := os.Remove(filepath.Join(dir, entry.File)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var manifest bytes.Buffer
+	used := make(map[string]bool)
+	for _, place := range places {
+		label := place.Label
+		if label == "" {
+			label = filepath.Base(place.Target)
+		}
+		// The file name is the menu entry
+		base := strings.TrimLeft(strings.ReplaceAll(label, "/", "∕"), ".")
+		if base == "" {
+			base = "_"
+		}
+		name := base
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s (%d)", base, i)
+		}
+		used[name] = true
+
+		script := fmt.Sprintf("#!/bin/sh\n# Written by bookmarksync, which removes it with the bookmark\nexec %s %s\n",
+			s.openCommand(), shellQuote(place.Target))
+		if err := writeFileSynced(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			return err
+		}
+		fmt.Fprintf(&manifest, "%s\t%s\t%s\n", name, place.Target, place.Label)
+	}
+
+	return replaceFile(filepath.Join(dir, shortcutManifest), manifest.Bytes())
+}
+
+// openCommand returns the command opening a location in a new window
+func (s *ScriptsBackend) openCommand() string {
+	if s.FileManager == "nautilus" {
+		return "nautilus --new-window"
+	}
+	return s.FileManager
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// serviceName is the name shared by the systemd units install-service writes
+const serviceName = "bookmarksync"
+
+// systemdUserDir returns the directory systemd reads user units from
+func systemdUserDir() (string, error) {
+	dir, err := xdgConfigHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "systemd", "user"), nil
+}
+
+// dbusServicesDir returns the directory the session bus reads activatable
+// services from
+func dbusServicesDir() (string, error) {
+	dir, err := xdgDataHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dbus-1", "services"), nil
+}
+
+// runInstallService implements "bookmarksync install-service", which writes
+// systemd user units that run bookmarksync on login and enables them.
+// Arguments after the flags are passed on to every sync. By default the
+// service runs the daemon; with --path or --timer it is a oneshot sync
+// started by a path unit watching the backend files or by a timer. With
+// --dbus the daemon isn't started on login but by the first D-Bus call,
+// and exits again once idle; --path with --idle-exit likewise starts an
+// idling daemon on the first change.
+func runInstallService(args []string) error {
+	fs := flag.NewFlagSet("install-service", flag.ExitOnError)
+	path := fs.Bool("path", false, "Sync when a backend file changes, using a systemd path unit instead of the daemon")
+	timer := fs.String("timer", "", "Sync periodically at this interval (e.g. 15min), using a systemd timer instead of the daemon")
+	activate := fs.Bool("dbus", false, "Start the daemon on the first D-Bus call instead of on login")
+	idleExit := fs.Duration("idle-exit", 10*time.Minute, "Stop the daemon after this long without activity (with --dbus or --path)")
+	noEnable := fs.Bool("no-enable", false, "Only write the unit files, do not enable them")
+	fs.Parse(args)
+	idle := *activate
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "idle-exit" {
+			idle = true
+		}
+	})
+	if *activate && (*path || *timer != "") {
+		return fmt.Errorf("--dbus cannot be combined with --path or --timer")
+	}
+	if idle && !*activate && !*path {
+		return fmt.Errorf("--idle-exit needs --dbus or --path to start the daemon again")
+	}
+	// The daemon runs unless a oneshot sync is started by a timer or, when
+	// it wouldn't idle, by the path unit
+	daemon := *timer == "" && (!*path || idle)
+	onLogin := daemon && !*path && !*activate
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	dir, err := systemdUserDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	execStart := []string{systemdQuote(exe), "sync"}
+	if daemon {
+		execStart[1] = "daemon"
+	}
+	if idle {
+		execStart = append(execStart, "--idle-exit", idleExit.String())
+	}
+	for _, arg := range fs.Args() {
+		execStart = append(execStart, systemdQuote(arg))
+	}
+
+	var service strings.Builder
+	service.WriteString("[Unit]\n")
+	service.WriteString("Description=Sync file dialog bookmarks between GTK, KDE and Qt\n\n")
+	service.WriteString("[Service]\n")
+	if !daemon {
+		service.WriteString("Type=oneshot\n")
+	} else if *activate {
+		service.WriteString("Type=dbus\n")
+		fmt.Fprintf(&service, "BusName=%s\n", dbusName)
+	} else {
+		service.WriteString("Type=simple\n")
+		service.WriteString("Restart=on-failure\n")
+	}
+	fmt.Fprintf(&service, "ExecStart=%s\n", strings.Join(execStart, " "))
+	if onLogin {
+		service.WriteString("\n[Install]\nWantedBy=default.target\n")
+	}
+
+	units := map[string]string{serviceName + ".service": service.String()}
+	var enable []string
+	if onLogin {
+		enable = append(enable, serviceName+".service")
+	}
+
+	if *path {
+		var unit strings.Builder
+		unit.WriteString("[Unit]\n")
+		unit.WriteString("Description=Watch file dialog bookmarks for changes\n\n")
+		unit.WriteString("[Path]\n")
+		for _, backend := range NewBookmarkSync().Backends() {
+			files, err := backend.Files()
+			if err != nil {
+				continue
+			}
+			for _, file := range files {
+				fmt.Fprintf(&unit, "PathChanged=%s\n", file)
+			}
+		}
+		unit.WriteString("\n[Install]\nWantedBy=default.target\n")
+		units[serviceName+".path"] = unit.String()
+		enable = append(enable, serviceName+".path")
+	}
+
+	if *timer != "" {
+		var unit strings.Builder
+		unit.WriteString("[Unit]\n")
+		unit.WriteString("Description=Sync file dialog bookmarks periodically\n\n")
+		unit.WriteString("[Timer]\n")
+		unit.WriteString("OnStartupSec=1min\n")
+		fmt.Fprintf(&unit, "OnUnitActiveSec=%s\n", *timer)
+		unit.WriteString("\n[Install]\nWantedBy=timers.target\n")
+		units[serviceName+".timer"] = unit.String()
+		enable = append(enable, serviceName+".timer")
+	}
+
+	for name, content := range units {
+		file := filepath.Join(dir, name)
+		if err := replaceFile(file, []byte(content)); err != nil {
+			return err
+		}
+		fmt.Print(tr("Wrote %s\n", file))
+	}
+
+	if *activate {
+		// The bus asks systemd to start the unit, and only runs Exec
+		// itself without systemd
+		servicesDir, err := dbusServicesDir()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(servicesDir, 0755); err != nil {
+			return err
+		}
+		var activation strings.Builder
+		activation.WriteString("[D-BUS Service]\n")
+		fmt.Fprintf(&activation, "Name=%s\n", dbusName)
+		fmt.Fprintf(&activation, "Exec=%s\n", strings.Join(execStart, " "))
+		fmt.Fprintf(&activation, "SystemdService=%s.service\n", serviceName)
+		file := filepath.Join(servicesDir, dbusName+".service")
+		if err := replaceFile(file, []byte(activation.String())); err != nil {
+			return err
+		}
+		fmt.Print(tr("Wrote %s\n", file))
+		if *noEnable {
+			return nil
+		}
+		return systemctl("daemon-reload")
+	}
+
+	if *noEnable {
+		fmt.Print(tr("Enable with: systemctl --user enable --now %s\n", strings.Join(enable, " ")))
+		return nil
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	return systemctl(append([]string{"enable", "--now"}, enable...)...)
+}
+
+// systemctl runs systemctl --user with the given arguments
+func systemctl(args ...string) error {
+	cmd := exec.Command("systemctl", append([]string{"--user"}, args...)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("systemctl --user %s: %v", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// systemdQuote quotes an ExecStart argument if it contains characters
+// systemd would otherwise split or expand
+func systemdQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\$%;") {
+		return s
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "$", "$$")
+	s = strings.ReplaceAll(s, "%", "%%")
+	return `"` + s + `"`
+}
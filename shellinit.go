@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// runPath implements "bookmarksync path NAME", printing the local directory
+// of a bookmark, or with -l every local bookmark's label
+func runPath(args []string) error {
+	fs := flag.NewFlagSet("path", flag.ExitOnError)
+	labels := fs.Bool("l", false, "List the labels of local bookmarks")
+	places, err := sourcePlaces(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *labels {
+		for _, place := range places {
+			if strings.HasPrefix(place.Target, "file://") && place.Label != "" {
+				fmt.Println(place.Label)
+			}
+		}
+		return nil
+	}
+
+	if fs.NArg() != 1 {
+		return usageError("bookmarksync-go path [-f BACKEND] NAME")
+	}
+	place, err := findPlace(places, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(place.Target)
+	if err != nil || u.Scheme != "file" {
+		return fmt.Errorf("%s is not a local folder", place.Target)
+	}
+	fmt.Println(u.Path)
+	return nil
+}
+
+// runShellInit implements "bookmarksync shell-init bash|zsh|fish", which
+// prints a bm function that cd's into a bookmark by label. The function
+// and its completions query the backend on every call, so the shell always
+// sees the same list as the file dialogs.
+func runShellInit(args []string) error {
+	fs := flag.NewFlagSet("shell-init", flag.ExitOnError)
+	syncFrom := fs.String("f", "", "Backend to read bookmarks from (default: from in the configuration, else canonical, else gtk)")
+	fs.StringVar(syncFrom, "sync-from", "", "Backend to read bookmarks from (default: from in the configuration, else canonical, else gtk)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return usageError("bookmarksync-go shell-init [-f BACKEND] bash|zsh|fish")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	// Without -f, the function reads what the configuration says then
+	self := shellQuote(exe) + " path"
+	if *syncFrom != "" {
+		self += " -f " + shellQuote(*syncFrom)
+	}
+
+	var script string
+	switch fs.Arg(0) {
+	case "bash":
+		script = `bm() {
+    local dir
+    dir="$(` + self + ` -- "$*")" && cd "$dir"
+}
+_bm_complete() {
+    local IFS=$'\n'
+    COMPREPLY=($(compgen -W "$(` + self + ` -l)" -- "${COMP_WORDS[COMP_CWORD]}"))
+}
+complete -F _bm_complete bm
+`
+	case "zsh":
+		script = `bm() {
+    local dir
+    dir="$(` + self + ` -- "$*")" && cd "$dir"
+}
+_bm() {
+    local -a labels
+    labels=("${(@f)$(` + self + ` -l)}")
+    compadd -a labels
+}
+(( $+functions[compdef] )) && compdef _bm bm
+`
+	case "fish":
+		script = `function bm
+    set -l dir (` + self + ` -- "$argv")
+    and cd $dir
+end
+complete -c bm -f -a "(` + self + ` -l)"
+`
+	default:
+		return fmt.Errorf("unsupported shell: %s", fs.Arg(0))
+	}
+
+	fmt.Print(script)
+	return nil
+}
+
+// shellQuote single-quotes s for POSIX shells and fish
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Strategies for simulating what a backend can't store, set per backend in
+// the [simulate] table of the configuration
+const (
+	// simulateDrop loses what the backend can't store, the default
+	simulateDrop = "drop"
+	// simulateLinks gives a backend without labels a symlink named after
+	// the label in place of each folder, so the dialog shows the label
+	simulateLinks = "links"
+)
+
+// simulationStrategies are the valid [simulate] values
+var simulationStrategies = []string{simulateDrop, simulateLinks}
+
+// simulateFlag collects --simulate BACKEND=STRATEGY values into the
+// configured strategies
+type simulateFlag map[string]string
+
+func (f simulateFlag) String() string {
+	var strategies []string
+	for name, strategy := range f {
+		strategies = append(strategies, name+"="+strategy)
+	}
+	return strings.Join(strategies, ",")
+}
+
+func (f simulateFlag) Set(value string) error {
+	name, strategy, ok := strings.Cut(value, "=")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !ok || name == "" || !slices.Contains(simulationStrategies, strategy) {
+		return fmt.Errorf("expected BACKEND=STRATEGY with STRATEGY one of %s, got %q", strings.Join(simulationStrategies, ", "), value)
+	}
+	f[name] = strategy
+	return nil
+}
+
+// linkSlash stands in for the slashes of labels in link names
+const linkSlash = "∕"
+
+// linksBackend simulates labels for a backend that derives them from the
+// path: with the links strategy, local places are written as symlinks in
+// a farm of its own, named after their labels, and read back as the
+// folders they point to. It wraps every backend without labels, so links
+// are still read back, and the farm removed, once the strategy is dropped.
+type linksBackend struct {
+	BookmarkSyncBackend
+}
+
+// enabled reports whether the backend's strategy is links
+func (l *linksBackend) enabled() bool {
+	return config.Simulate[l.Name()] == simulateLinks
+}
+
+func (l *linksBackend) Capabilities() Capabilities {
+	caps := capabilitiesOf(l.BookmarkSyncBackend)
+	caps.Labels = l.enabled()
+	return caps
+}
+
+func (l *linksBackend) Unwrap() BookmarkSyncBackend {
+	return l.BookmarkSyncBackend
+}
+
+// dir returns the folder holding the backend's links
+func (l *linksBackend) dir() (string, error) {
+	dataHome, err := xdgDataHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataHome, "bookmarksync", "links", strings.ReplaceAll(l.Name(), ":", "-")), nil
+}
+
+func (l *linksBackend) GetPlaces() ([]Place, error) {
+	places, err := l.BookmarkSyncBackend.GetPlaces()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := l.dir()
+	if err != nil {
+		return nil, err
+	}
+	for i, place := range places {
+		path, err := localPath(place.Target)
+		if err != nil || filepath.Dir(path) != dir {
+			continue
+		}
+		folder, err := os.Readlink(path)
+		if err != nil {
+			continue
+		}
+		places[i] = Place{
+			Label:  strings.ReplaceAll(filepath.Base(path), linkSlash, "/"),
+			Target: fileURI(folder),
+		}
+	}
+	return places, nil
+}
+
+func (l *linksBackend) Merge(places []Place) error {
+	return mergeInto(l, places)
+}
+
+// linked returns places with the targets of labeled local places replaced
+// by their links in dir, and the folder each link points to by link name
+func (l *linksBackend) linked(dir string, places []Place) ([]Place, map[string]string) {
+	linked := make([]Place, len(places))
+	links := make(map[string]string)
+	if !l.enabled() {
+		copy(linked, places)
+		return linked, links
+	}
+	for i, place := range places {
+		linked[i] = place
+		path, err := localPath(place.Target)
+		if err != nil || place.Label == "" || place.Label == filepath.Base(path) {
+			continue
+		}
+		base := strings.ReplaceAll(place.Label, "/", linkSlash)
+		if base == "." || base == ".." {
+			continue
+		}
+		name := base
+		for n := 2; links[name] != ""; n++ {
+			name = fmt.Sprintf("%s (%d)", base, n)
+		}
+		links[name] = path
+		linked[i].Target = fileURI(filepath.Join(dir, name))
+	}
+	return linked, links
+}
+
+func (l *linksBackend) Render(places []Place) ([]byte, error) {
+	r, ok := rendererOf(l.BookmarkSyncBackend)
+	if !ok {
+		return nil, fmt.Errorf("%s isn't written as a single file and can't be rendered", l.Name())
+	}
+	dir, err := l.dir()
+	if err != nil {
+		return nil, err
+	}
+	linked, _ := l.linked(dir, places)
+	return r.Render(linked)
+}
+
+func (l *linksBackend) Replace(places []Place) error {
+	dir, err := l.dir()
+	if err != nil {
+		return err
+	}
+	linked, links := l.linked(dir, places)
+	if len(links) > 0 {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	for name, path := range links {
+		link := filepath.Join(dir, name)
+		if current, err := os.Readlink(link); err != nil || current != path {
+			if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if err := os.Symlink(path, link); err != nil {
+				return err
+			}
+		}
+	}
+	if err := l.BookmarkSyncBackend.Replace(linked); err != nil {
+		return err
+	}
+
+	return collectLinks(dir, links)
+}
+
+// collectLinks removes the links in dir that aren't in links, and dir once
+// it is empty. Anything else in the folder isn't ours and stays.
+func collectLinks(dir string, links map[string]string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	kept := 0
+	for _, entry := range entries {
+		if _, ok := links[entry.Name()]; ok || entry.Type()&os.ModeSymlink == 0 {
+			kept++
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	if kept == 0 {
+		if err := os.Remove(dir); err != nil {
+			return err
+		}
+		// The farms share a folder, which goes with the last of them
+		os.Remove(filepath.Dir(dir))
+	}
+	return nil
+}
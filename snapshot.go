@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotVersion is the version of the snapshot format written. Restore
+// refuses snapshots of newer versions rather than misreading them.
+const snapshotVersion = 1
+
+// Snapshot holds the places every backend had when it was saved
+type Snapshot struct {
+	Version  int                `json:"version"`
+	Created  time.Time          `json:"created"`
+	Backends map[string][]Place `json:"backends"`
+}
+
+// snapshotPath returns the file of the snapshot called name
+func snapshotPath(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid snapshot name %q", name)
+	}
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "snapshots", name+".json"), nil
+}
+
+// loadSnapshot reads the snapshot called name
+func loadSnapshot(name string) (*Snapshot, error) {
+	path, err := snapshotPath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, errors.New(tr("no snapshot named %s", name))
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("corrupt snapshot %s: %v", path, err)
+	}
+	if snapshot.Version > snapshotVersion {
+		return nil, fmt.Errorf("snapshot %s was saved by a newer bookmarksync (format %d)", name, snapshot.Version)
+	}
+	return &snapshot, nil
+}
+
+// runSnapshot implements "bookmarksync snapshot save|restore|list|delete".
+// A snapshot keeps the places of every backend under a name, so they can
+// be put back after reorganizing them went wrong.
+func runSnapshot(args []string) error {
+	usage := usageError("bookmarksync-go snapshot save [--force] NAME | restore NAME [BACKEND...] | list | delete NAME")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "save":
+		fs := flag.NewFlagSet("snapshot save", flag.ExitOnError)
+		force := fs.Bool("force", false, "Overwrite a snapshot with the same name")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return usage
+		}
+		return saveSnapshot(fs.Arg(0), *force)
+	case "restore":
+		if len(args) < 2 {
+			return usage
+		}
+		return restoreSnapshot(args[1], args[2:])
+	case "list":
+		return listSnapshots()
+	case "delete":
+		if len(args) != 2 {
+			return usage
+		}
+		path, err := snapshotPath(args[1])
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); os.IsNotExist(err) {
+			return errors.New(tr("no snapshot named %s", args[1]))
+		} else if err != nil {
+			return err
+		}
+		fmt.Print(tr("Deleted snapshot %s\n", args[1]))
+		return nil
+	}
+	return usage
+}
+
+// saveSnapshot saves the places of every backend as the snapshot name
+func saveSnapshot(name string, force bool) error {
+	path, err := snapshotPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil && !force {
+		return errors.New(tr("snapshot %s already exists (use --force to overwrite it)", name))
+	}
+
+	bs := NewBookmarkSync()
+	snapshot := Snapshot{Version: snapshotVersion, Created: time.Now(), Backends: map[string][]Place{}}
+	count := 0
+	for _, backend := range bs.Backends() {
+		places, err := backend.GetPlaces()
+		if err != nil {
+			return fmt.Errorf("failed to get places from %s: %v", backend.Name(), err)
+		}
+		if places == nil {
+			places = []Place{}
+		}
+		snapshot.Backends[backend.Name()] = places
+		count += len(places)
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := writeFileSynced(path, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Print(tr("Saved snapshot %s (%d backends, %d places)\n", name, len(snapshot.Backends), count))
+	return nil
+}
+
+// restoreSnapshot writes the places of the snapshot name back to the given
+// backends, or every backend it has. Like any write, the backends are
+// backed up first, so undo takes the restore back.
+func restoreSnapshot(name string, only []string) error {
+	snapshot, err := loadSnapshot(name)
+	if err != nil {
+		return err
+	}
+
+	bs := NewBookmarkSync()
+	var names []string
+	for backend := range snapshot.Backends {
+		names = append(names, backend)
+	}
+	sort.Strings(names)
+	if len(only) > 0 {
+		names = nil
+		for _, backend := range only {
+			backend = strings.ToLower(backend)
+			if _, ok := snapshot.Backends[backend]; !ok {
+				return errors.New(tr("snapshot %s has no places of %s", name, backend))
+			}
+			names = append(names, backend)
+		}
+	}
+
+	var backends []BookmarkSyncBackend
+	for _, backend := range names {
+		if b, ok := bs.backends[backend]; ok {
+			backends = append(backends, b)
+		} else {
+			log.Print(tr("Warning: skipping %s, which isn't a backend here", backend))
+		}
+	}
+	if err := bs.checkWritable(backends); err != nil {
+		return err
+	}
+
+	err = inTransaction(backends, func() error {
+		for _, backend := range backends {
+			places := snapshot.Backends[backend.Name()]
+			current, err := backend.GetPlaces()
+			if err == nil && samePlaces(current, places, capabilitiesOf(backend)) {
+				bs.noteBackend(backend.Name(), "unchanged", nil)
+				continue
+			}
+			if err := backend.Replace(places); err != nil {
+				return fmt.Errorf("failed to write %s: %v", backend.Name(), err)
+			}
+			bs.noteBackend(backend.Name(), "written", nil)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Print(tr("Restored snapshot %s from %s\n", name, snapshot.Created.Local().Format(time.DateTime)))
+	return bs.recordSync()
+}
+
+// listSnapshots prints the saved snapshots, oldest first
+func listSnapshots() error {
+	dir, err := stateDir()
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "snapshots"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var snapshots []string
+	created := make(map[string]*Snapshot)
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		snapshot, err := loadSnapshot(name)
+		if err != nil {
+			log.Print(tr("Warning: %v", err))
+			continue
+		}
+		snapshots = append(snapshots, name)
+		created[name] = snapshot
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return created[snapshots[i]].Created.Before(created[snapshots[j]].Created)
+	})
+	for _, name := range snapshots {
+		snapshot := created[name]
+		count := 0
+		for _, places := range snapshot.Backends {
+			count += len(places)
+		}
+		fmt.Printf("%s\t%s\t%d backends\t%d places\n", name, snapshot.Created.Local().Format(time.DateTime), len(snapshot.Backends), count)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	home := testHome(t)
+	testWithoutGTK2(t)
+	gtk := writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\n")
+	if err := runSync([]string{"--from", "gtk"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runSnapshot([]string{"save", "before"}); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///b b\n")
+	if err := runSync([]string{"--from", "gtk"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only kde
+	if err := runSnapshot([]string{"restore", "before", "KDE"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := kdePlaces(t), places("a", "file:///a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("kde holds %v, want the snapshot's %v", got, want)
+	}
+	if data, _ := os.ReadFile(gtk); string(data) != "file:///b b\n" {
+		t.Errorf("gtk restored too: %q", data)
+	}
+
+	// Every backend
+	if err := runSnapshot([]string{"restore", "before"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(gtk); string(data) != "file:///a a\n" {
+		t.Errorf("gtk holds %q, want the snapshot's", data)
+	}
+
+	if err := runSnapshot([]string{"delete", "before"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runSnapshot([]string{"restore", "before"}); err == nil || !strings.Contains(err.Error(), "no snapshot named before") {
+		t.Errorf("restoring a deleted snapshot: error %v", err)
+	}
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	home := testHome(t)
+	testWithoutGTK2(t)
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\n")
+	if err := runSnapshot([]string{"save", "one"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runSnapshot([]string{"save", "one"}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("saving over a snapshot: error %v", err)
+	}
+	if err := runSnapshot([]string{"save", "--force", "one"}); err != nil {
+		t.Errorf("saving over a snapshot with --force: %v", err)
+	}
+	if err := runSnapshot([]string{"restore", "one", "blender"}); err == nil || !strings.Contains(err.Error(), "has no places of blender") {
+		t.Errorf("restoring a backend the snapshot lacks: error %v", err)
+	}
+	for _, name := range []string{"../one", ".hidden", ""} {
+		if _, err := snapshotPath(name); err == nil {
+			t.Errorf("snapshot named %q", name)
+		}
+	}
+
+	path, err := snapshotPath("future")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, "/", path, `{"version": 2, "backends": {}}`)
+	if _, err := loadSnapshot("future"); err == nil || !strings.Contains(err.Error(), "newer bookmarksync") {
+		t.Errorf("snapshot of a newer version: error %v", err)
+	}
+	writeFile(t, "/", path, `{"version": 1, "backends": `)
+	if _, err := loadSnapshot("future"); err == nil || !strings.Contains(err.Error(), "corrupt snapshot") {
+		t.Errorf("cut short snapshot: error %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// sshHost is a concrete (non-wildcard) Host entry from ~/.ssh/config
+type sshHost struct {
+	Alias string
+	User  string
+}
+
+// sshHostBlock is a Host section with the settings we care about
+type sshHostBlock struct {
+	Patterns []string
+	User     string
+}
+
+// Matches reports whether the block's Host patterns apply to alias
+func (b sshHostBlock) Matches(alias string) bool {
+	matched := false
+	for _, pattern := range b.Patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		if ok, _ := path.Match(strings.TrimPrefix(pattern, "!"), alias); ok {
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// readSSHHosts parses the concrete Host aliases from an OpenSSH client
+// config. As in ssh itself, the first User setting from a matching Host
+// block (wildcards included) wins. Match blocks are skipped.
+func readSSHHosts(configPath string) ([]sshHost, error) {
+	file, err := os.Open(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var blocks []sshHostBlock
+	var current *sshHostBlock
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		keyword, value, _ := strings.Cut(line, " ")
+		if k, v, ok := strings.Cut(line, "="); ok && !strings.Contains(k, " ") {
+			keyword, value = k, v
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+
+		switch strings.ToLower(keyword) {
+		case "host":
+			blocks = append(blocks, sshHostBlock{Patterns: strings.Fields(value)})
+			current = &blocks[len(blocks)-1]
+		case "match":
+			current = nil
+		case "user":
+			if current != nil && current.User == "" {
+				current.User = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var hosts []sshHost
+	seen := make(map[string]bool)
+	for _, block := range blocks {
+		for _, alias := range block.Patterns {
+			if strings.ContainsAny(alias, "*?!") || seen[alias] {
+				continue
+			}
+			seen[alias] = true
+
+			host := sshHost{Alias: alias}
+			for _, b := range blocks {
+				if b.User != "" && b.Matches(alias) {
+					host.User = b.User
+					break
+				}
+			}
+			hosts = append(hosts, host)
+		}
+	}
+
+	return hosts, nil
+}
+
+// withSSHPlaces regenerates sftp:// places for the selected ~/.ssh/config
+// hosts ("*" selects every host). Previously generated places for those
+// hosts are replaced so changes to the config are picked up on each sync;
+// other places on them are the user's and stay.
+func withSSHPlaces(places []Place, selected []string) ([]Place, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	hosts, err := readSSHHosts(filepath.Join(homeDir, ".ssh", "config"))
+	if err != nil {
+		return nil, err
+	}
+
+	all := false
+	wanted := make(map[string]bool)
+	for _, alias := range selected {
+		if alias == "*" {
+			all = true
+		}
+		wanted[alias] = true
+	}
+
+	var generated []Place
+	for _, host := range hosts {
+		if !all && !wanted[host.Alias] {
+			continue
+		}
+		user := host.User
+		if user == "" {
+			user = os.Getenv("USER")
+		}
+		home := path.Join("/home", user)
+		if user == "root" {
+			home = "/root"
+		}
+		generated = append(generated, Place{
+			Label:  host.Alias,
+			Target: "sftp://" + host.Alias + home,
+		})
+		wanted[host.Alias] = true
+	}
+
+	var kept []Place
+	for _, place := range places {
+		if host, ok := sshHomePlace(place.Target); ok && wanted[host] {
+			continue
+		}
+		kept = append(kept, place)
+	}
+
+	return append(kept, generated...), nil
+}
+
+// sshHomePlace returns the host of an sftp:// place of a home folder, the
+// places withSSHPlaces generates
+func sshHomePlace(target string) (string, bool) {
+	rest, ok := strings.CutPrefix(target, "sftp://")
+	if !ok {
+		return "", false
+	}
+	host, dir, _ := strings.Cut(rest, "/")
+	dir = strings.TrimSuffix(dir, "/")
+	if dir == "root" {
+		return host, true
+	}
+	user, ok := strings.CutPrefix(dir, "home/")
+	return host, ok && user != "" && !strings.Contains(user, "/")
+}
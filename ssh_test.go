@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testSSHConfig = `Host web
+    User deploy
+
+Host db backup
+    HostName 10.0.0.2
+
+Host *.internal !secret
+    User ops
+
+Host *
+    User root
+`
+
+func TestReadSSHHosts(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config", testSSHConfig)
+	hosts, err := readSSHHosts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []sshHost{{"web", "deploy"}, {"db", "root"}, {"backup", "root"}}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("readSSHHosts() = %v, want %v", hosts, want)
+	}
+	if hosts, err := readSSHHosts(filepath.Join(t.TempDir(), "missing")); err != nil || hosts != nil {
+		t.Errorf("readSSHHosts() of a missing file = %v, %v", hosts, err)
+	}
+}
+
+// Regression test: every sftp:// place on a selected host was dropped, not
+// just the one generated for it
+func TestWithSSHPlacesKeepsOtherPlaces(t *testing.T) {
+	home := testHome(t)
+	writeFile(t, home, ".ssh/config", testSSHConfig)
+	current := places(
+		"web", "sftp://web/home/olduser",
+		"www", "sftp://web/var/www",
+		"db", "sftp://db/root/",
+		"a", "file:///a",
+	)
+
+	got, err := withSSHPlaces(current, []string{"web", "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := places(
+		"www", "sftp://web/var/www",
+		"a", "file:///a",
+		"web", "sftp://web/home/deploy",
+		"db", "sftp://db/root",
+	)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("withSSHPlaces() = %v, want %v", got, want)
+	}
+}
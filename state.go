@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State is what bookmarksync remembers between runs, stored in
+// ~/.local/state/bookmarksync/state.json
+type State struct {
+	// Baseline is the place list every backend agreed on after the last
+	// two-way sync; it is the common ancestor for the next 3-way merge
+	Baseline []Place `json:"baseline,omitempty"`
+	// Backends holds what each backend reported right after that sync, so
+	// its own edits can be told apart from what it simply can't store
+	Backends map[string][]Place `json:"backends,omitempty"`
+	// LastSync is when the baseline was recorded
+	LastSync time.Time `json:"last_sync,omitempty"`
+
+	// Synced is when the last sync of any kind finished writing
+	Synced time.Time `json:"synced,omitempty"`
+	// Failed is why the last sync, of any kind, failed, "" when it worked
+	Failed string `json:"failed,omitempty"`
+	// Fingerprints are the backends' contents as that sync left them
+	Fingerprints map[string]Fingerprint `json:"fingerprints,omitempty"`
+	// Seen are the places each backend held after that sync
+	Seen map[string][]Place `json:"seen,omitempty"`
+	// Tombstones are bookmarks the user deleted, kept from coming back
+	Tombstones []Tombstone `json:"tombstones,omitempty"`
+	// Provenance tells where each bookmark came from, by normalized target
+	Provenance map[string]Provenance `json:"provenance,omitempty"`
+	// PlaceIDs are the IDs of the places, by normalized target
+	PlaceIDs map[string]string `json:"place_ids,omitempty"`
+	// NativeIDs map place IDs to the identifiers of the backends' records,
+	// by backend, for the backends whose records have their own
+	NativeIDs map[string]map[string]string `json:"native_ids,omitempty"`
+	// Pins are bookmarks kept in a backend when it is replaced, by backend
+	Pins map[string][]Place `json:"pins,omitempty"`
+	// Vaulted are places held back while the vault they are in is closed
+	Vaulted []Place `json:"vaulted,omitempty"`
+	// Volumes are the mount points of encrypted and removable volumes seen
+	// mounted, whose places are held back like those of closed vaults
+	Volumes []string `json:"volumes,omitempty"`
+	// Undo names the backups holding each backend's files as they were
+	// before the last sync that wrote any, "" for backends without files
+	Undo map[string]string `json:"undo,omitempty"`
+
+	// Groups are bookmarks added together from a template, by group name
+	Groups map[string]Group `json:"groups,omitempty"`
+	// Temporary are bookmarks to remove from every backend once they expire
+	Temporary []TempPlace `json:"temporary,omitempty"`
+}
+
+// stateDir returns the directory holding bookmarksync's state files
+func stateDir() (string, error) {
+	dir, err := xdgStateHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bookmarksync"), nil
+}
+
+// LoadState reads the state file, returning an empty state if there is none
+func LoadState() (*State, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{}
+	data, err := os.ReadFile(filepath.Join(dir, "state.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Save writes the state file, replacing it atomically
+func (s *State) Save() error {
+	dir, err := stateDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "state-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, "state.json"))
+}
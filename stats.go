@@ -0,0 +1,155 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// daemonStats counts what the daemon did since it started, for status to
+// ask for over D-Bus. A nil daemonStats records nothing.
+type daemonStats struct {
+	mu      sync.Mutex
+	started time.Time
+	syncs   int
+	// synced is when the last sync finished
+	synced time.Time
+	// written is when each backend was last written
+	written     map[string]time.Time
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// newDaemonStats returns empty statistics of a daemon starting now
+func newDaemonStats() *daemonStats {
+	return &daemonStats{started: time.Now(), written: map[string]time.Time{}}
+}
+
+// Wrap returns run counted as a sync, remembering its error
+func (s *daemonStats) Wrap(run func() error) func() error {
+	if s == nil {
+		return run
+	}
+	return func() error {
+		err := run()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.syncs++
+		s.synced = time.Now()
+		if err != nil {
+			s.lastError, s.lastErrorAt = err.Error(), s.synced
+		}
+		return err
+	}
+}
+
+// noteBackend records what happened to a backend during a sync
+func (s *daemonStats) noteBackend(name, status string, err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case err != nil:
+		s.lastError, s.lastErrorAt = name+": "+err.Error(), time.Now()
+	case status == "written":
+		s.written[name] = time.Now()
+	}
+}
+
+// DaemonStatus is what the running daemon reports about itself
+type DaemonStatus struct {
+	Started     string            `json:"started"`
+	Uptime      string            `json:"uptime"`
+	Syncs       int               `json:"syncs"`
+	LastSync    string            `json:"last_sync,omitempty"`
+	Written     map[string]string `json:"written,omitempty"`
+	LastError   string            `json:"last_error,omitempty"`
+	LastErrorAt string            `json:"last_error_at,omitempty"`
+}
+
+// unixTime returns t as seconds since the epoch, 0 for the zero time,
+// which is how times travel over the bus
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// Stats returns the daemon's statistics: when it started, how many syncs
+// it ran and when the last finished, when each backend was last written,
+// and the last error with its time. Times are seconds since the epoch, 0
+// for never.
+func (s *dbusService) Stats() (int64, uint32, int64, map[string]int64, string, int64, *dbus.Error) {
+	defer s.idle.Begin()()
+	stats := s.bs.Stats
+	if stats == nil {
+		return 0, 0, 0, map[string]int64{}, "", 0, nil
+	}
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	written := make(map[string]int64, len(stats.written))
+	for name, at := range stats.written {
+		written[name] = unixTime(at)
+	}
+	return unixTime(stats.started), uint32(stats.syncs), unixTime(stats.synced), written, stats.lastError, unixTime(stats.lastErrorAt), nil
+}
+
+// queryDaemon asks a running daemon for its statistics over the session
+// bus. It returns nil when no daemon is running, without starting one
+// through D-Bus activation.
+func queryDaemon() *DaemonStatus {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	var (
+		started, lastSync, lastErrorAt int64
+		syncs                          uint32
+		written                        map[string]int64
+		lastError                      string
+	)
+	call := conn.Object(dbusName, dbusPath).Call(dbusInterface+".Stats", dbus.FlagNoAutoStart)
+	if err := call.Store(&started, &syncs, &lastSync, &written, &lastError, &lastErrorAt); err != nil {
+		return nil
+	}
+
+	format := func(unix int64) string {
+		if unix == 0 {
+			return ""
+		}
+		return time.Unix(unix, 0).Format(time.DateTime)
+	}
+	status := &DaemonStatus{
+		Started:     format(started),
+		Uptime:      time.Since(time.Unix(started, 0)).Round(time.Second).String(),
+		Syncs:       int(syncs),
+		LastSync:    format(lastSync),
+		LastError:   lastError,
+		LastErrorAt: format(lastErrorAt),
+	}
+	if len(written) > 0 {
+		status.Written = make(map[string]string, len(written))
+		for name, at := range written {
+			status.Written[name] = format(at)
+		}
+	}
+	return status
+}
+
+// writtenBackends returns the names of the backends in status.Written,
+// sorted
+func (d *DaemonStatus) writtenBackends() []string {
+	names := make([]string, 0, len(d.Written))
+	for name := range d.Written {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
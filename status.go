@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// statusRow is one backend's line of the status table. Status is one of
+// missing, unreadable, never synced, in sync, changed since last sync or
+// error, and is translated only in the table.
+type statusRow struct {
+	Backend  string   `json:"backend"`
+	Files    []string `json:"files"`
+	Places   *int     `json:"places,omitempty"`
+	Modified string   `json:"modified,omitempty"`
+	Status   string   `json:"status"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// runStatus implements "bookmarksync status", showing every backend's
+// files, how many places it holds and whether it changed since the last
+// sync, and when the daemon runs, what it did since it started
+func runStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	asJSON := fs.Bool("json", jsonOutput, "Print the status as JSON")
+	prompt := fs.Bool("prompt", false, "Print one character for shell prompts: = in sync, ~ changed since the last sync, ! last sync failed")
+	waybar := fs.Bool("waybar", false, "Print the status as a Waybar custom module, with return-type json")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		return usageError("bookmarksync-go status [--json|--prompt|--waybar]")
+	}
+
+	state, err := LoadState()
+	if err != nil {
+		return fmt.Errorf("failed to load state: %v", err)
+	}
+	switch {
+	case *prompt:
+		fmt.Println(promptStatus(state))
+		return nil
+	case *waybar:
+		// Waybar reads one line of JSON
+		return json.NewEncoder(os.Stdout).Encode(waybarStatus(state))
+	}
+
+	var rows []statusRow
+	for _, backend := range NewBookmarkSync().Backends() {
+		row := statusRow{Backend: backend.Name(), Files: []string{}}
+		files, err := backend.Files()
+		if err != nil {
+			row.Status, row.Error = "error", err.Error()
+			rows = append(rows, row)
+			continue
+		}
+
+		for _, file := range files {
+			if _, err := os.Stat(file); err == nil {
+				row.Files = append(row.Files, file)
+			}
+		}
+		if len(row.Files) == 0 {
+			row.Status = "missing"
+			if len(files) > 0 {
+				row.Files = files[:1]
+			}
+			rows = append(rows, row)
+			continue
+		}
+
+		places, err := backend.GetPlaces()
+		if err == nil {
+			count := len(places)
+			row.Places = &count
+		}
+
+		row.Status = "unreadable"
+		if fp, fpErr := fingerprint(backend); fpErr == nil {
+			row.Modified = fp.ModTime.Format(time.DateTime)
+			recorded, ok := state.Fingerprints[row.Backend]
+			switch {
+			case err != nil:
+				// the files exist but don't parse
+			case !ok:
+				row.Status = "never synced"
+			case recorded.Hash == fp.Hash:
+				row.Status = "in sync"
+			default:
+				row.Status = "changed since last sync"
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	daemon := queryDaemon()
+	if *asJSON {
+		result := struct {
+			Backends []statusRow   `json:"backends"`
+			LastSync string        `json:"last_sync,omitempty"`
+			Daemon   *DaemonStatus `json:"daemon,omitempty"`
+		}{Backends: rows, Daemon: daemon}
+		if !state.Synced.IsZero() {
+			result.LastSync = state.Synced.Format(time.RFC3339)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, tr("BACKEND\tFILE\tPLACES\tMODIFIED\tSTATUS"))
+	for _, row := range rows {
+		file, count, modified := "-", "-", "-"
+		if len(row.Files) > 0 {
+			file = strings.Join(row.Files, ", ")
+		}
+		if row.Places != nil {
+			count = fmt.Sprint(*row.Places)
+		}
+		if row.Modified != "" {
+			modified = row.Modified
+		}
+		status := tr(row.Status)
+		if row.Error != "" {
+			status = tr("error: %v", row.Error)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", row.Backend, file, count, modified, status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !state.Synced.IsZero() {
+		fmt.Println(tr("\nLast sync: %s", state.Synced.Format(time.DateTime)))
+	}
+	if daemon == nil {
+		return nil
+	}
+	fmt.Println(tr("\nDaemon: running since %s (%s), %d syncs", daemon.Started, daemon.Uptime, daemon.Syncs))
+	if daemon.LastSync != "" {
+		fmt.Println(tr("  Last sync: %s", daemon.LastSync))
+	}
+	for _, name := range daemon.writtenBackends() {
+		fmt.Println(tr("  %s last written: %s", name, daemon.Written[name]))
+	}
+	if daemon.LastError != "" {
+		fmt.Println(tr("  Last error (%s): %s", daemon.LastErrorAt, daemon.LastError))
+	}
+	return nil
+}
+
+// Characters of status --prompt
+const (
+	promptSynced   = "="
+	promptDiverged = "~"
+	promptFailed   = "!"
+)
+
+// quickStatus tells whether backend is as the last sync left it without
+// reading it, fast enough to run for every shell prompt: its files are only
+// hashed when their modification time moved. The status is one of those of
+// statusRow besides unreadable.
+func quickStatus(state *State, backend BookmarkSyncBackend) string {
+	recorded, ok := state.Fingerprints[backend.Name()]
+	if !ok {
+		if hasFiles(backend) {
+			return "never synced"
+		}
+		return "missing"
+	}
+	files, err := backend.Files()
+	if err != nil {
+		return "error"
+	}
+	var modified time.Time
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+	if modified.Equal(recorded.ModTime) {
+		return "in sync"
+	}
+	if fp, err := fingerprint(backend); err != nil || fp.Hash != recorded.Hash {
+		return "changed since last sync"
+	}
+	return "in sync"
+}
+
+// promptStatus sums the state up in one character: the last sync failed,
+// a backend changed since it, or all are as it left them
+func promptStatus(state *State) string {
+	if state.Failed != "" {
+		return promptFailed
+	}
+	for _, backend := range NewBookmarkSync().Backends() {
+		switch quickStatus(state, backend) {
+		case "error":
+			return promptFailed
+		case "never synced", "changed since last sync":
+			return promptDiverged
+		}
+	}
+	return promptSynced
+}
+
+// statusName names a character of status --prompt: synced, diverged or
+// failed
+func statusName(prompt string) string {
+	switch prompt {
+	case promptSynced:
+		return "synced"
+	case promptDiverged:
+		return "diverged"
+	}
+	return "failed"
+}
+
+// waybarModule is the output of status --waybar, what Waybar's custom
+// modules read with return-type json
+type waybarModule struct {
+	Text    string `json:"text"`
+	Alt     string `json:"alt"`
+	Tooltip string `json:"tooltip"`
+	Class   string `json:"class"`
+}
+
+// waybarStatus returns the status for a Waybar module: the character of
+// status --prompt as the text, synced, diverged or failed as the alt and
+// class to pick icons and styles by, and each backend's status, the last
+// sync and what a running daemon did as the tooltip
+func waybarStatus(state *State) waybarModule {
+	text := promptStatus(state)
+	module := waybarModule{Text: text, Alt: statusName(text)}
+	module.Class = module.Alt
+
+	var lines []string
+	for _, backend := range NewBookmarkSync().Backends() {
+		lines = append(lines, fmt.Sprintf("%s: %s", backend.Name(), tr(quickStatus(state, backend))))
+	}
+	if !state.Synced.IsZero() {
+		lines = append(lines, strings.TrimSpace(tr("\nLast sync: %s", state.Synced.Format(time.DateTime))))
+	}
+	if state.Failed != "" {
+		lines = append(lines, tr("Last sync failed: %s", state.Failed))
+	}
+	if daemon := queryDaemon(); daemon != nil {
+		lines = append(lines, strings.TrimSpace(tr("\nDaemon: running since %s (%s), %d syncs", daemon.Started, daemon.Uptime, daemon.Syncs)))
+	}
+	// Waybar takes the tooltip for Pango markup
+	module.Tooltip = html.EscapeString(strings.Join(lines, "\n"))
+	return module
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// TempPlace is a bookmark that is removed from every backend once it expires
+type TempPlace struct {
+	Place   Place     `json:"place"`
+	Expires time.Time `json:"expires"`
+}
+
+// ExpireTemporary removes expired temporary bookmarks from every backend
+func (bs *BookmarkSync) ExpireTemporary() error {
+	state, err := LoadState()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var expired []Place
+	var active []TempPlace
+	for _, temp := range state.Temporary {
+		if now.After(temp.Expires) {
+			expired = append(expired, temp.Place)
+		} else {
+			active = append(active, temp)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	if err := bs.UpdateAll(func(current []Place) []Place {
+		return removeTargets(current, expired)
+	}); err != nil {
+		return err
+	}
+	for _, place := range expired {
+		fmt.Print(tr("Removed expired bookmark %s (%s)\n", place.Label, place.Target))
+	}
+
+	// UpdateAll saved the sync time; reload so it isn't overwritten
+	state, err = LoadState()
+	if err != nil {
+		return err
+	}
+	state.Temporary = active
+	return state.Save()
+}
+
+// runTemp implements "bookmarksync temp add|list"
+func runTemp(args []string) error {
+	if len(args) == 0 || (args[0] != "add" && args[0] != "list") {
+		return usageError("bookmarksync-go temp add [--ttl DURATION] [--label LABEL] PATH | temp list")
+	}
+
+	state, err := LoadState()
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "list" {
+		for _, temp := range state.Temporary {
+			fmt.Print(tr("%s\t%s\texpires %s\n", temp.Place.Label, temp.Place.Target, temp.Expires.Format(time.DateTime)))
+		}
+		return nil
+	}
+
+	fs := flag.NewFlagSet("temp add", flag.ExitOnError)
+	ttl := fs.Duration("ttl", time.Hour, "How long the bookmark should live")
+	label := fs.String("label", "", "Bookmark label (default: the folder name)")
+	fs.Parse(args[1:])
+	if fs.NArg() != 1 {
+		return usageError("bookmarksync-go temp add [--ttl DURATION] [--label LABEL] PATH")
+	}
+
+	dir, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if !isDir(dir) {
+		return errors.New(tr("%s is not a directory", dir))
+	}
+	place := Place{Label: *label, Target: fileURI(dir)}
+	if place.Label == "" {
+		place.Label = filepath.Base(dir)
+	}
+
+	if err := NewBookmarkSync().UpdateAll(func(current []Place) []Place {
+		return appendMissingPlaces(current, []Place{place})
+	}); err != nil {
+		return err
+	}
+
+	state, err = LoadState()
+	if err != nil {
+		return err
+	}
+	expires := time.Now().Add(*ttl)
+	state.Temporary = append(state.Temporary, TempPlace{Place: place, Expires: expires})
+	fmt.Print(tr("Added %s until %s\n", place.Label, expires.Format(time.DateTime)))
+	return state.Save()
+}
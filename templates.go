@@ -0,0 +1,256 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Group is a set of bookmarks added together from a template, so they can
+// be removed together later
+type Group struct {
+	Template string  `json:"template"`
+	Root     string  `json:"root"`
+	Places   []Place `json:"places"`
+	// Disabled groups are kept here but removed from every backend
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// builtinTemplates are available without any template files. Each line is
+// "PATH LABEL" as in the edit format, with ${var} placeholders.
+var builtinTemplates = map[string]string{
+	"project": `${root} ${name}
+${root}/src ${name} src
+${root}/docs ${name} docs
+${root}/build ${name} build
+`,
+}
+
+// loadTemplate returns a template from ~/.config/bookmarksync/templates or
+// the built-in ones
+func loadTemplate(name string) (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "templates", name))
+	if err == nil {
+		return string(data), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	if template, ok := builtinTemplates[name]; ok {
+		return template, nil
+	}
+	return "", fmt.Errorf("unknown template: %s", name)
+}
+
+// expandTemplate substitutes variables and parses the template into places
+func expandTemplate(template string, vars map[string]string) ([]Place, error) {
+	expanded := os.Expand(template, func(key string) string {
+		if value, ok := vars[key]; ok {
+			return value
+		}
+		return "${" + key + "}"
+	})
+	if i := strings.Index(expanded, "${"); i >= 0 {
+		end := strings.IndexAny(expanded[i:], "}\n")
+		return nil, fmt.Errorf("template variable %s is not set", expanded[i:i+end+1])
+	}
+
+	places, problems := parseEditable(expanded)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid template: %s", strings.Join(problems, "; "))
+	}
+	return places, nil
+}
+
+// UpdateAll applies edit to the places of every backend and writes back
+// the backends whose places changed
+func (bs *BookmarkSync) UpdateAll(edit func([]Place) []Place) error {
+	if err := bs.checkWritable(bs.Backends()); err != nil {
+		return err
+	}
+	var destinations []BookmarkSyncBackend
+	for _, backend := range bs.Backends() {
+		if bs.writable(backend.Name()) {
+			destinations = append(destinations, backend)
+		}
+	}
+	failures := &backendErrors{}
+	err := inTransaction(bs.Backends(), func() error {
+		bs.writeBackends(destinations, failures, func(backend BookmarkSyncBackend) (string, error) {
+			places, err := backend.GetPlaces()
+			if err != nil {
+				return "", fmt.Errorf("failed to read: %v", err)
+			}
+			updated := edit(places)
+			if samePlaces(places, updated, Capabilities{Labels: true, Remote: true}) {
+				return "unchanged", nil
+			}
+			return "written", backend.Replace(updated)
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := bs.recordSync(); err != nil {
+		return err
+	}
+	return failures.err()
+}
+
+// removeTargets drops places whose target is one of targets
+func removeTargets(places []Place, targets []Place) []Place {
+	drop := make(map[string]bool, len(targets))
+	for _, place := range targets {
+		drop[normalizeTarget(place.Target)] = true
+	}
+	var kept []Place
+	for _, place := range places {
+		if !drop[normalizeTarget(place.Target)] {
+			kept = append(kept, place)
+		}
+	}
+	return kept
+}
+
+// runAddProject implements "bookmarksync add-project ROOT", adding the
+// bookmarks of a template for one project directory to every backend
+func runAddProject(args []string) error {
+	fs := flag.NewFlagSet("add-project", flag.ExitOnError)
+	templateName := fs.String("t", "project", "Template to instantiate")
+	groupName := fs.String("name", "", "Group name (default: the root's base name)")
+	all := fs.Bool("all", false, "Also add folders that don't exist yet")
+	var vars stringListFlag
+	fs.Var(&vars, "var", "Template variable KEY=VALUE (repeatable)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return usageError("bookmarksync-go add-project [-t TEMPLATE] [--name NAME] [--var KEY=VALUE] [--all] ROOT")
+	}
+
+	root, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	name := *groupName
+	if name == "" {
+		name = filepath.Base(root)
+	}
+
+	values := map[string]string{"root": root, "name": name}
+	for _, kv := range vars {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return fmt.Errorf("expected KEY=VALUE, got %q", kv)
+		}
+		values[key] = value
+	}
+
+	template, err := loadTemplate(*templateName)
+	if err != nil {
+		return err
+	}
+	places, err := expandTemplate(template, values)
+	if err != nil {
+		return err
+	}
+	if !*all {
+		var existing []Place
+		for _, place := range places {
+			if dir, err := localPath(place.Target); err != nil || isDir(dir) {
+				existing = append(existing, place)
+			}
+		}
+		places = existing
+	}
+	if len(places) == 0 {
+		return fmt.Errorf("none of the template's folders exist under %s", root)
+	}
+
+	state, err := LoadState()
+	if err != nil {
+		return err
+	}
+	if _, exists := state.Groups[name]; exists {
+		return fmt.Errorf("group %s already exists", name)
+	}
+
+	if err := NewBookmarkSync().UpdateAll(func(current []Place) []Place {
+		return appendMissingPlaces(current, places)
+	}); err != nil {
+		return err
+	}
+
+	// UpdateAll saved what it wrote; reload so it isn't overwritten
+	state, err = LoadState()
+	if err != nil {
+		return err
+	}
+	if state.Groups == nil {
+		state.Groups = make(map[string]Group)
+	}
+	state.Groups[name] = Group{Template: *templateName, Root: root, Places: places}
+	for _, place := range places {
+		fmt.Print(tr("Added %s (%s)\n", place.Label, place.Target))
+	}
+	return state.Save()
+}
+
+// runRemoveProject implements "bookmarksync remove-project NAME"
+func runRemoveProject(args []string) error {
+	if len(args) != 1 {
+		return usageError("bookmarksync-go remove-project NAME")
+	}
+
+	state, err := LoadState()
+	if err != nil {
+		return err
+	}
+	group, exists := state.Groups[args[0]]
+	if !exists {
+		return fmt.Errorf("no group named %s", args[0])
+	}
+
+	if group.Disabled {
+		delete(state.Groups, args[0])
+		fmt.Print(tr("Removed disabled group %s\n", args[0]))
+		return state.Save()
+	}
+
+	if err := NewBookmarkSync().UpdateAll(func(current []Place) []Place {
+		return removeTargets(current, group.Places)
+	}); err != nil {
+		return err
+	}
+
+	state, err = LoadState()
+	if err != nil {
+		return err
+	}
+	delete(state.Groups, args[0])
+	fmt.Print(tr("Removed %d bookmarks of %s\n", len(group.Places), args[0]))
+	return state.Save()
+}
+
+// localPath returns the filesystem path of a file:// target
+func localPath(target string) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("%s is not a local folder", target)
+	}
+	return u.Path, nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
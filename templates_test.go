@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Regression test: add-project saved the state it loaded before UpdateAll
+// over what that recorded
+func TestAddProjectKeepsSyncState(t *testing.T) {
+	home := testHome(t)
+	root := filepath.Join(home, "project")
+	if err := os.MkdirAll(filepath.Join(root, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runAddProject([]string{root}); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(state.Groups["project"].Places); got != 2 {
+		t.Errorf("group has %d places, want the 2 that exist", got)
+	}
+	assertInSync(t, state)
+}
+
+func TestRemoveProjectKeepsSyncState(t *testing.T) {
+	home := testHome(t)
+	dir := filepath.Join(home, "project", "docs")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", fileURI(dir)+" docs\n")
+	state := &State{Groups: map[string]Group{
+		"project": {Root: filepath.Dir(dir), Places: []Place{{Label: "docs", Target: fileURI(dir)}}},
+	}}
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runRemoveProject([]string{"project"}); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := state.Groups["project"]; exists {
+		t.Error("group not removed")
+	}
+	assertInSync(t, state)
+}
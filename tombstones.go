@@ -0,0 +1,122 @@
+package main
+
+import "time"
+
+// tombstoneTTL is how long a deleted bookmark is remembered
+const tombstoneTTL = 90 * 24 * time.Hour
+
+// Tombstone remembers a bookmark the user deleted from a backend, so that
+// merging from a backend that still has it doesn't bring it back
+type Tombstone struct {
+	Target  string    `json:"target"`
+	Label   string    `json:"label,omitempty"`
+	Backend string    `json:"backend"`
+	Deleted time.Time `json:"deleted"`
+}
+
+// updateTombstones compares every backend with what it held after the last
+// sync. Places it lost or moved since are buried; a buried place that reappears in
+// a backend edited after the deletion was added back on purpose and is
+// unburied. A backend with no files at all is skipped, since losing every
+// place that way is not an edit.
+func (bs *BookmarkSync) updateTombstones(state *State) {
+	for _, backend := range bs.Backends() {
+		name := backend.Name()
+		seen, ok := state.Seen[name]
+		if !ok {
+			continue
+		}
+		fp, err := fingerprint(backend)
+		if err != nil || fp.ModTime.IsZero() {
+			continue
+		}
+		places, err := backend.GetPlaces()
+		if err != nil {
+			continue
+		}
+
+		edits := diffPlaces(name, capabilitiesOf(backend), seen, places)
+		for _, place := range seen {
+			key := normalizeTarget(place.Target)
+			if _, moved := edits.moves[key]; moved || edits.deleted[key] {
+				state.bury(place, name, fp.ModTime)
+			}
+		}
+		for key := range edits.added {
+			state.unbury(key, fp.ModTime)
+		}
+		for _, moved := range edits.moves {
+			state.unbury(normalizeTarget(moved.Target), fp.ModTime)
+		}
+	}
+
+	var kept []Tombstone
+	for _, tombstone := range state.Tombstones {
+		if time.Since(tombstone.Deleted) < tombstoneTTL {
+			kept = append(kept, tombstone)
+		}
+	}
+	state.Tombstones = kept
+}
+
+// bury records that backend lost place at the given time
+func (s *State) bury(place Place, backend string, deleted time.Time) {
+	key := normalizeTarget(place.Target)
+	for i, tombstone := range s.Tombstones {
+		if tombstone.Target == key {
+			s.Tombstones[i].Deleted = deleted
+			s.Tombstones[i].Backend = backend
+			return
+		}
+	}
+	s.Tombstones = append(s.Tombstones, Tombstone{Target: key, Label: place.Label, Backend: backend, Deleted: deleted})
+}
+
+// unbury forgets the tombstone of a normalized target if the place was
+// added again after it was deleted
+func (s *State) unbury(key string, added time.Time) {
+	for i, tombstone := range s.Tombstones {
+		if tombstone.Target == key && added.After(tombstone.Deleted) {
+			s.Tombstones = append(s.Tombstones[:i], s.Tombstones[i+1:]...)
+			return
+		}
+	}
+}
+
+// withoutBuried drops places with a tombstone from a list, except those in
+// keep, which are known to be wanted
+func (s *State) withoutBuried(places []Place, keep []Place) []Place {
+	if len(s.Tombstones) == 0 {
+		return places
+	}
+	buried := make(map[string]bool, len(s.Tombstones))
+	for _, tombstone := range s.Tombstones {
+		buried[tombstone.Target] = true
+	}
+	for _, place := range keep {
+		delete(buried, normalizeTarget(place.Target))
+	}
+
+	var alive []Place
+	for _, place := range places {
+		if !buried[normalizeTarget(place.Target)] {
+			alive = append(alive, place)
+		}
+	}
+	return alive
+}
+
+// recordBackends remembers what every backend holds right after a sync
+func (bs *BookmarkSync) recordBackends(state *State) {
+	state.Fingerprints = bs.fingerprints()
+	state.Seen = make(map[string][]Place, len(bs.order))
+	for _, backend := range bs.Backends() {
+		if places, err := backend.GetPlaces(); err == nil {
+			state.Seen[backend.Name()] = places
+		}
+	}
+	bs.recordProvenance(state)
+	bs.recordIDs(state)
+	recordUndo(state)
+	bs.recordVaulted(state)
+}
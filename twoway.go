@@ -0,0 +1,447 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Conflict describes edits made in different backends that could not both
+// be applied
+type Conflict struct {
+	Target  string
+	Message string
+
+	// key is the normalized target of the place the merge kept, and
+	// versions are what the backends made of it, for resolving the
+	// conflict by hand
+	key      string
+	versions []conflictVersion
+}
+
+// conflictVersion is a backend's side of a conflict: the place as it has
+// it, or its removal
+type conflictVersion struct {
+	backend string
+	place   Place
+	removed bool
+}
+
+func (c Conflict) String() string {
+	return c.Target + ": " + c.Message
+}
+
+// placeEdits are the changes one backend made since the last sync
+type placeEdits struct {
+	backend  string
+	caps     Capabilities
+	added    map[string]Place
+	deleted  map[string]bool
+	relabels map[string]string
+	// moves are places whose target changed but whose label did not, by
+	// the normalized old target
+	moves map[string]Place
+	order []string
+	// modified are when the backend's places were last relabelled, by
+	// normalized target, for backends that keep track like the canonical
+	// places file
+	modified map[string]time.Time
+}
+
+// diffPlaces compares a backend's places against what it reported after
+// the previous sync
+func diffPlaces(backend string, caps Capabilities, before, after []Place) placeEdits {
+	edits := placeEdits{
+		backend:  backend,
+		caps:     caps,
+		added:    make(map[string]Place),
+		deleted:  make(map[string]bool),
+		relabels: make(map[string]string),
+		moves:    make(map[string]Place),
+	}
+
+	old := make(map[string]Place, len(before))
+	for _, place := range before {
+		old[normalizeTarget(place.Target)] = place
+	}
+	current := make(map[string]bool, len(after))
+	for _, place := range after {
+		key := normalizeTarget(place.Target)
+		current[key] = true
+		previous, existed := old[key]
+		switch {
+		case !existed:
+			edits.added[key] = place
+			edits.order = append(edits.order, key)
+		case caps.Labels && previous.Label != place.Label:
+			edits.relabels[key] = place.Label
+		}
+	}
+	for key := range old {
+		if !current[key] {
+			edits.deleted[key] = true
+		}
+	}
+
+	if caps.Labels {
+		detectMoves(&edits, old)
+	}
+	return edits
+}
+
+// detectMoves turns a deleted place and an added one with the same label
+// into a move, for backends that carry no IDs to follow a bookmark by.
+// Labels that are not unique on either side are ambiguous and left alone.
+func detectMoves(edits *placeEdits, old map[string]Place) {
+	deletedByLabel := make(map[string][]string)
+	for key := range edits.deleted {
+		if label := old[key].Label; label != "" {
+			deletedByLabel[label] = append(deletedByLabel[label], key)
+		}
+	}
+	addedByLabel := make(map[string][]string)
+	for _, key := range edits.order {
+		if label := edits.added[key].Label; label != "" {
+			addedByLabel[label] = append(addedByLabel[label], key)
+		}
+	}
+
+	moved := make(map[string]bool)
+	for label, deleted := range deletedByLabel {
+		added := addedByLabel[label]
+		if len(deleted) != 1 || len(added) != 1 {
+			continue
+		}
+		edits.moves[deleted[0]] = edits.added[added[0]]
+		delete(edits.deleted, deleted[0])
+		delete(edits.added, added[0])
+		moved[added[0]] = true
+	}
+
+	var order []string
+	for _, key := range edits.order {
+		if !moved[key] {
+			order = append(order, key)
+		}
+	}
+	edits.order = order
+}
+
+// threeWayMerge applies every backend's edits to the baseline. Deleting a
+// place that another backend relabeled keeps it, and conflicting labels are
+// resolved in favour of the newest when every backend involved knows when
+// it changed the place, and of the backend listed first otherwise.
+func threeWayMerge(baseline []Place, edits []placeEdits) ([]Place, []Conflict) {
+	var merged []Place
+	var conflicts []Conflict
+
+	inBaseline := make(map[string]bool, len(baseline))
+	for _, place := range baseline {
+		key := normalizeTarget(place.Target)
+		inBaseline[key] = true
+
+		var deletedBy, relabeledBy, movedBy []string
+		var labels []string
+		var times []time.Time
+		var moves []Place
+		for _, e := range edits {
+			if e.deleted[key] {
+				deletedBy = append(deletedBy, e.backend)
+			}
+			if moved, ok := e.moves[key]; ok {
+				movedBy = append(movedBy, e.backend)
+				moves = append(moves, moved)
+			}
+			label, relabeled := e.relabels[key]
+			if added, ok := e.added[key]; ok && e.caps.Labels {
+				label, relabeled = added.Label, added.Label != place.Label
+			}
+			if relabeled {
+				relabeledBy = append(relabeledBy, e.backend)
+				labels = append(labels, label)
+				times = append(times, e.modified[key])
+			}
+		}
+
+		if len(moves) > 0 {
+			var targets []string
+			for _, moved := range moves {
+				targets = append(targets, moved.Target)
+			}
+			if distinct := distinctStrings(targets); len(distinct) > 1 {
+				conflict := Conflict{
+					Target: place.Target,
+					Message: fmt.Sprintf("moved to %s in %s; keeping %s",
+						strings.Join(distinct, " and "), strings.Join(movedBy, ", "), targets[0]),
+					key: normalizeTarget(targets[0]),
+				}
+				for i, moved := range moves {
+					conflict.versions = append(conflict.versions, conflictVersion{backend: movedBy[i], place: moved})
+				}
+				conflicts = append(conflicts, conflict)
+			}
+			place.Target = targets[0]
+			inBaseline[normalizeTarget(place.Target)] = true
+			// Moving a place keeps it, whatever else deleted it
+			deletedBy = nil
+		}
+
+		if len(deletedBy) > 0 {
+			if len(relabeledBy) == 0 {
+				continue
+			}
+			conflict := Conflict{
+				Target: place.Target,
+				Message: fmt.Sprintf("removed in %s but renamed in %s; keeping it",
+					strings.Join(deletedBy, ", "), strings.Join(relabeledBy, ", ")),
+				key: normalizeTarget(place.Target),
+			}
+			for _, backend := range deletedBy {
+				conflict.versions = append(conflict.versions, conflictVersion{backend: backend, removed: true})
+			}
+			for i, backend := range relabeledBy {
+				conflict.versions = append(conflict.versions, conflictVersion{backend: backend, place: Place{Label: labels[i], Target: place.Target}})
+			}
+			conflicts = append(conflicts, conflict)
+		}
+
+		if len(labels) > 0 {
+			keep := newest(times)
+			if distinct := distinctStrings(labels); len(distinct) > 1 {
+				conflict := Conflict{
+					Target: place.Target,
+					Message: fmt.Sprintf("renamed to %s in %s; keeping %q",
+						quoteAll(labels), strings.Join(relabeledBy, ", "), labels[keep]),
+					key: normalizeTarget(place.Target),
+				}
+				for i, backend := range relabeledBy {
+					conflict.versions = append(conflict.versions, conflictVersion{backend: backend, place: Place{Label: labels[i], Target: place.Target}})
+				}
+				conflicts = append(conflicts, conflict)
+			}
+			place.Label = labels[keep]
+		}
+		merged = append(merged, place)
+	}
+
+	// Places added since the last sync, in backend priority order
+	added := make(map[string]int)
+	// labelledBy is the backend the label kept of each comes from
+	labelledBy := make(map[string]string)
+	labelled := make(map[string]bool)
+	addedAt := make(map[string]time.Time)
+	for _, e := range edits {
+		for _, key := range e.order {
+			if inBaseline[key] {
+				continue
+			}
+			place := e.added[key]
+			i, seen := added[key]
+			switch {
+			case !seen:
+				added[key] = len(merged)
+				labelledBy[key] = e.backend
+				labelled[key] = e.caps.Labels
+				addedAt[key] = e.modified[key]
+				merged = append(merged, place)
+			case !e.caps.Labels || place.Label == merged[i].Label:
+			case !labelled[key]:
+				// A real label beats one derived from the path
+				merged[i].Label = place.Label
+				labelledBy[key] = e.backend
+				labelled[key] = true
+			default:
+				keep := merged[i].Label
+				later := newest([]time.Time{addedAt[key], e.modified[key]}) == 1
+				if later {
+					keep = place.Label
+				}
+				conflicts = append(conflicts, Conflict{
+					Target:  place.Target,
+					Message: fmt.Sprintf("added as %q and %q; keeping %q", merged[i].Label, place.Label, keep),
+					key:     key,
+					versions: []conflictVersion{
+						{backend: labelledBy[key], place: merged[i]},
+						{backend: e.backend, place: place},
+					},
+				})
+				if later {
+					merged[i].Label = place.Label
+					labelledBy[key] = e.backend
+					addedAt[key] = e.modified[key]
+				}
+			}
+		}
+	}
+
+	return merged, conflicts
+}
+
+// newest returns the index of the latest of times, or 0 unless all of them
+// are known
+func newest(times []time.Time) int {
+	latest := 0
+	for i, t := range times {
+		if t.IsZero() {
+			return 0
+		}
+		if t.After(times[latest]) {
+			latest = i
+		}
+	}
+	return latest
+}
+
+func distinctStrings(values []string) []string {
+	var distinct []string
+	seen := make(map[string]bool)
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			distinct = append(distinct, value)
+		}
+	}
+	return distinct
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+	return strings.Join(quoted, " and ")
+}
+
+// representable filters places down to what a backend can store
+func representable(places []Place, caps Capabilities) []Place {
+	var kept []Place
+	for _, place := range places {
+		if caps.Remote || strings.HasPrefix(place.Target, "file://") {
+			kept = append(kept, place)
+		}
+	}
+	return kept
+}
+
+// samePlaces reports whether a backend already holds places, as far as its
+// format can tell
+func samePlaces(current, places []Place, caps Capabilities) bool {
+	places = representable(places, caps)
+	if len(current) != len(places) {
+		return false
+	}
+	for i := range places {
+		if normalizeTarget(current[i].Target) != normalizeTarget(places[i].Target) {
+			return false
+		}
+		if caps.Labels && current[i].Label != places[i].Label {
+			return false
+		}
+	}
+	return true
+}
+
+// SyncBidirectional propagates additions, removals and renames made in any
+// backend since the last run to all the others, using the baseline saved
+// in the state file as the common ancestor
+func (bs *BookmarkSync) SyncBidirectional() ([]Conflict, error) {
+	state, err := LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %v", err)
+	}
+
+	bs.updateTombstones(state)
+
+	backends := bs.Backends()
+	current := make(map[string][]Place, len(backends))
+	var edits []placeEdits
+	for _, backend := range backends {
+		places, err := backend.GetPlaces()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get places from %s: %v", backend.Name(), err)
+		}
+		current[backend.Name()] = places
+		if bs.readable(backend.Name()) {
+			// Resolving portal paths shows up as an edit, which rewrites
+			// them everywhere
+			resolved := dedupePlaces(withPortalPathsResolved(bs.sanitizePlaces(backend.Name(), places)))
+			edit := diffPlaces(backend.Name(), capabilitiesOf(backend), state.Backends[backend.Name()], resolved)
+			for _, key := range edit.order {
+				bs.noteOrigins(backend.Name(), []Place{edit.added[key]})
+			}
+			edits = append(edits, edit)
+		}
+	}
+
+	merged, conflicts := threeWayMerge(state.Baseline, edits)
+	if len(conflicts) > 0 && bs.EditConflicts {
+		if merged, err = editConflicts(merged, conflicts); err != nil {
+			return conflicts, err
+		}
+		conflicts = nil
+	}
+	// A backend that missed a deletion offers the place as new; only
+	// places that were in the baseline can survive their tombstone
+	merged = state.withoutBuried(merged, state.Baseline)
+	merged, err = bs.withGeneratedPlaces(merged)
+	if err != nil {
+		return nil, err
+	}
+	merged, err = bs.withVaults(merged)
+	if err != nil {
+		return nil, err
+	}
+	merged = bs.pruned(bs.Filter.Apply(merged))
+
+	if err := bs.checkWritable(backends); err != nil {
+		return conflicts, err
+	}
+	state.Baseline = merged
+	state.Backends = make(map[string][]Place, len(backends))
+	failures := &backendErrors{}
+	var destinations []BookmarkSyncBackend
+	for _, backend := range backends {
+		if bs.writable(backend.Name()) {
+			destinations = append(destinations, backend)
+		}
+	}
+	err = inTransaction(backends, func() error {
+		bs.writeBackends(destinations, failures, func(backend BookmarkSyncBackend) (string, error) {
+			name := backend.Name()
+			// Places the filter keeps from spreading, pinned places and
+			// locked ones stay where they are; forbidden ones go
+			places := orderPlaces(withLocked(withoutForbidden(withPins(pinnedPlaces(state, name), current[name], bs.Filter.Held(current[name], merged)))))
+			if samePlaces(current[name], places, capabilitiesOf(backend)) {
+				return "unchanged", nil
+			}
+			return "written", backend.Replace(places)
+		})
+		for _, backend := range backends {
+			name := backend.Name()
+			if !bs.writable(name) {
+				state.Backends[name] = current[name]
+				continue
+			}
+			// Record what the backend actually kept, which is the
+			// baseline its next edits are measured against
+			places, err := backend.GetPlaces()
+			if err != nil {
+				places = current[name]
+			}
+			state.Backends[name] = places
+		}
+		return nil
+	})
+	if err != nil {
+		return conflicts, err
+	}
+	state.LastSync = time.Now()
+	state.Synced = state.LastSync
+	bs.recordBackends(state)
+
+	if err := state.Save(); err != nil {
+		return conflicts, fmt.Errorf("failed to save state: %v", err)
+	}
+	bs.commitCanonical()
+	return conflicts, failures.err()
+}
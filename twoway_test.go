@@ -0,0 +1,279 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	withLabels    = Capabilities{Labels: true, Remote: true}
+	withoutLabels = Capabilities{Labels: false, Remote: true}
+)
+
+// backendEdit is what a backend held after the last sync and holds now
+type backendEdit struct {
+	name          string
+	caps          Capabilities
+	before, after []Place
+	modified      map[string]time.Time
+}
+
+func TestThreeWayMerge(t *testing.T) {
+	baseline := places("a", "file:///a", "b", "file:///b")
+	earlier := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+	tests := []struct {
+		name      string
+		edits     []backendEdit
+		want      []Place
+		conflicts int
+	}{
+		{
+			name: "nothing changed",
+			edits: []backendEdit{
+				{name: "gtk", caps: withLabels, before: baseline, after: baseline},
+				{name: "kde", caps: withLabels, before: baseline, after: baseline},
+			},
+			want: baseline,
+		},
+		{
+			name: "additions of every backend",
+			edits: []backendEdit{
+				{name: "gtk", caps: withLabels, before: baseline, after: append(baseline, places("c", "file:///c")...)},
+				{name: "kde", caps: withLabels, before: baseline, after: append(baseline, places("d", "file:///d")...)},
+			},
+			want: places("a", "file:///a", "b", "file:///b", "c", "file:///c", "d", "file:///d"),
+		},
+		{
+			name: "removal in one backend",
+			edits: []backendEdit{
+				{name: "gtk", caps: withLabels, before: baseline, after: places("a", "file:///a")},
+				{name: "kde", caps: withLabels, before: baseline, after: baseline},
+			},
+			want: places("a", "file:///a"),
+		},
+		{
+			name: "relabel in one backend",
+			edits: []backendEdit{
+				{name: "gtk", caps: withLabels, before: baseline, after: baseline},
+				{name: "kde", caps: withLabels, before: baseline, after: places("A", "file:///a", "b", "file:///b")},
+			},
+			want: places("A", "file:///a", "b", "file:///b"),
+		},
+		{
+			name: "removal and relabel keep the place",
+			edits: []backendEdit{
+				{name: "gtk", caps: withLabels, before: baseline, after: places("b", "file:///b")},
+				{name: "kde", caps: withLabels, before: baseline, after: places("A", "file:///a", "b", "file:///b")},
+			},
+			want:      places("A", "file:///a", "b", "file:///b"),
+			conflicts: 1,
+		},
+		{
+			name: "conflicting relabels keep the first backend's",
+			edits: []backendEdit{
+				{name: "gtk", caps: withLabels, before: baseline, after: places("X", "file:///a", "b", "file:///b")},
+				{name: "kde", caps: withLabels, before: baseline, after: places("Y", "file:///a", "b", "file:///b")},
+			},
+			want:      places("X", "file:///a", "b", "file:///b"),
+			conflicts: 1,
+		},
+		{
+			name: "conflicting relabels keep the newest when known",
+			edits: []backendEdit{
+				{name: "canonical", caps: withLabels, before: baseline, after: places("X", "file:///a", "b", "file:///b"),
+					modified: map[string]time.Time{"file:///a": earlier}},
+				{name: "git", caps: withLabels, before: baseline, after: places("Y", "file:///a", "b", "file:///b"),
+					modified: map[string]time.Time{"file:///a": later}},
+			},
+			want:      places("Y", "file:///a", "b", "file:///b"),
+			conflicts: 1,
+		},
+		{
+			name: "a move keeps the place where it was",
+			edits: []backendEdit{
+				{name: "gtk", caps: withLabels, before: baseline, after: places("a", "file:///new/a", "b", "file:///b")},
+				{name: "kde", caps: withLabels, before: baseline, after: baseline},
+			},
+			want: places("a", "file:///new/a", "b", "file:///b"),
+		},
+		{
+			name: "a move beats a removal",
+			edits: []backendEdit{
+				{name: "gtk", caps: withLabels, before: baseline, after: places("a", "file:///new/a", "b", "file:///b")},
+				{name: "kde", caps: withLabels, before: baseline, after: places("b", "file:///b")},
+			},
+			want: places("a", "file:///new/a", "b", "file:///b"),
+		},
+		{
+			name: "a label beats one derived from the path",
+			edits: []backendEdit{
+				{name: "qt", caps: withoutLabels, before: baseline, after: append(baseline, places("c", "file:///c")...)},
+				{name: "gtk", caps: withLabels, before: baseline, after: append(baseline, places("See", "file:///c/")...)},
+			},
+			want: places("a", "file:///a", "b", "file:///b", "See", "file:///c"),
+		},
+		{
+			name: "backends without labels don't relabel",
+			edits: []backendEdit{
+				{name: "qt", caps: withoutLabels, before: baseline, after: places("x", "file:///a", "b", "file:///b")},
+			},
+			want: baseline,
+		},
+		{
+			name: "added twice with different labels",
+			edits: []backendEdit{
+				{name: "gtk", caps: withLabels, before: baseline, after: append(baseline, places("c", "file:///c")...)},
+				{name: "kde", caps: withLabels, before: baseline, after: append(baseline, places("C", "file:///c")...)},
+			},
+			want:      places("a", "file:///a", "b", "file:///b", "c", "file:///c"),
+			conflicts: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var edits []placeEdits
+			for _, e := range test.edits {
+				edit := diffPlaces(e.name, e.caps, e.before, e.after)
+				for target, modified := range e.modified {
+					if edit.modified == nil {
+						edit.modified = make(map[string]time.Time)
+					}
+					edit.modified[normalizeTarget(target)] = modified
+				}
+				edits = append(edits, edit)
+			}
+			merged, conflicts := threeWayMerge(baseline, edits)
+			if !reflect.DeepEqual(merged, test.want) {
+				t.Errorf("merged %v, want %v", merged, test.want)
+			}
+			if len(conflicts) != test.conflicts {
+				t.Errorf("conflicts %v, want %d", conflicts, test.conflicts)
+			}
+		})
+	}
+}
+
+func TestDiffPlacesMoves(t *testing.T) {
+	before := places("docs", "file:///old/docs", "x", "file:///x1", "x", "file:///x2")
+	after := places("docs", "file:///new/docs", "x", "file:///x3", "x", "file:///x4")
+	edits := diffPlaces("gtk", withLabels, before, after)
+	if moved := edits.moves[normalizeTarget("file:///old/docs")]; moved.Target != "file:///new/docs" {
+		t.Errorf("docs moved to %v", moved)
+	}
+	// Labels that aren't unique are left as removals and additions
+	if len(edits.moves) != 1 || len(edits.deleted) != 2 || len(edits.order) != 2 {
+		t.Errorf("moves %v, deleted %v, added %v", edits.moves, edits.deleted, edits.order)
+	}
+}
+
+func TestSyncBidirectional(t *testing.T) {
+	home := testHome(t)
+	testWithoutGTK2(t)
+	gtk := writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\nfile:///b b\n")
+	if _, err := NewBookmarkSync().SyncBidirectional(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An addition in gtk and a removal in kde since
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\nfile:///b b\nfile:///c c\n")
+	kde := NewBookmarkSync().backends["kde"]
+	if err := kde.Replace(places("b", "file:///b")); err != nil {
+		t.Fatal(err)
+	}
+	conflicts, err := NewBookmarkSync().SyncBidirectional()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("conflicts %v", conflicts)
+	}
+
+	data, err := os.ReadFile(gtk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "file:///b b\nfile:///c c\n"; string(data) != want {
+		t.Errorf("gtk holds %q, want %q", data, want)
+	}
+	got, err := kde.GetPlaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var targets []string
+	for _, place := range got {
+		targets = append(targets, place.Target)
+	}
+	if want := "file:///b file:///c"; strings.Join(targets, " ") != want {
+		t.Errorf("kde holds %s, want %s", targets, want)
+	}
+	state, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := places("b", "file:///b", "c", "file:///c"); !reflect.DeepEqual(state.Baseline, want) {
+		t.Errorf("baseline %v, want %v", state.Baseline, want)
+	}
+}
+
+func TestSyncBidirectionalAutoCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	home := testHome(t)
+	testWithoutGTK2(t)
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "test")
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "test@example.com")
+	}
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	remote := filepath.Join(home, "places.git")
+	git(home, "init", "--quiet", "--bare", "--initial-branch=main", remote)
+	dotfiles := filepath.Join(home, ".config", "bookmarksync")
+	if err := os.MkdirAll(dotfiles, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	git(dotfiles, "init", "--quiet", "--initial-branch=main")
+	git(dotfiles, "commit", "--quiet", "--allow-empty", "-m", "dotfiles")
+	git(dotfiles, "remote", "add", "origin", remote)
+	git(dotfiles, "push", "--quiet", "--set-upstream", "origin", "main")
+
+	config.Canonical = true
+	config.AutoCommit = true
+	config.AutoPush = true
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\n")
+	if _, err := NewBookmarkSync().SyncBidirectional(); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\nfile:///b b\n")
+	if _, err := NewBookmarkSync().SyncBidirectional(); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := git(dotfiles, "status", "--porcelain", "--", "places.toml"); status != "" {
+		t.Errorf("places.toml not committed: %s", status)
+	}
+	if count := git(dotfiles, "rev-list", "--count", "HEAD"); count != "3" {
+		t.Errorf("%s commits, want the places' 2 on top of the dotfiles'", count)
+	}
+	if message := git(dotfiles, "log", "-1", "--format=%s"); !strings.Contains(message, "b") {
+		t.Errorf("last commit %q doesn't name the added place", message)
+	}
+	if local, pushed := git(dotfiles, "rev-parse", "HEAD"), git(remote, "rev-parse", "main"); local != pushed {
+		t.Errorf("remote at %s, want the pushed %s", pushed, local)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupOf finds the backupBackend AddBackend puts around a backend
+func backupOf(backend BookmarkSyncBackend) (*backupBackend, bool) {
+	for {
+		if b, ok := backend.(*backupBackend); ok {
+			return b, true
+		}
+		wrapper, ok := backend.(interface{ Unwrap() BookmarkSyncBackend })
+		if !ok {
+			return nil, false
+		}
+		backend = wrapper.Unwrap()
+	}
+}
+
+// restoreBackup puts the files of backup back in place of the backend's,
+// removing those the backup doesn't have. An empty backup removes them all.
+func restoreBackup(backend BookmarkSyncBackend, backup string) error {
+	files, err := backend.Files()
+	if err != nil {
+		return err
+	}
+	dir, err := backupsDir(backend.Name())
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		saved := filepath.Join(dir, backup)
+		if len(files) > 1 {
+			saved = filepath.Join(saved, filepath.Base(file))
+		}
+		data, err := os.ReadFile(saved)
+		if backup == "" || os.IsNotExist(err) {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		if err := replaceFile(file, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasFiles reports whether any of the backend's files exist
+func hasFiles(backend BookmarkSyncBackend) bool {
+	files, err := backend.Files()
+	if err != nil {
+		return false
+	}
+	for _, file := range files {
+		if _, err := os.Stat(file); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// runUndo implements "bookmarksync undo [BACKEND]", which puts the files of
+// every backend, or only BACKEND, back as they were before the last sync or
+// command that wrote them. The files are backed up first like any write,
+// so running undo again redoes the sync.
+func runUndo(args []string) error {
+	if len(args) > 1 {
+		return usageError("bookmarksync-go undo [BACKEND]")
+	}
+	if keptBackups() <= 0 {
+		return errors.New(tr("nothing to undo: no backups are kept (backups = 0)"))
+	}
+
+	bs := NewBookmarkSync()
+	state, err := LoadState()
+	if err != nil {
+		return err
+	}
+	if len(state.Undo) == 0 {
+		return errors.New(tr("nothing to undo: no sync has been recorded yet"))
+	}
+
+	var names []string
+	for name := range state.Undo {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(args) == 1 {
+		name := strings.ToLower(args[0])
+		if _, ok := state.Undo[name]; !ok {
+			return errors.New(tr("nothing to undo: the last sync didn't write %s", name))
+		}
+		names = []string{name}
+	}
+
+	var backends []BookmarkSyncBackend
+	for _, name := range names {
+		backend, ok := bs.backends[name]
+		if !ok {
+			return fmt.Errorf("unknown backend: %s", name)
+		}
+		backup := state.Undo[name]
+		if backup != "" {
+			dir, err := backupsDir(name)
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stat(filepath.Join(dir, backup)); err != nil {
+				return errors.New(tr("can't undo %s: its backup %s is gone", name, backup))
+			}
+		}
+		backends = append(backends, backend)
+	}
+	if err := bs.checkWritable(backends); err != nil {
+		return err
+	}
+
+	err = inTransaction(backends, func() error {
+		for _, backend := range backends {
+			if b, ok := backupOf(backend); ok {
+				if err := b.backup(); err != nil {
+					return fmt.Errorf("failed to back up %s: %v", backend.Name(), err)
+				}
+			}
+			backup := state.Undo[backend.Name()]
+			if backup == "" && !hasFiles(backend) {
+				continue
+			}
+			if err := restoreBackup(backend, backup); err != nil {
+				return fmt.Errorf("failed to restore %s: %v", backend.Name(), err)
+			}
+			if when, err := time.ParseInLocation(backupTimeFormat, backup, time.Local); err == nil {
+				fmt.Print(tr("Restored %s as it was at %s\n", backend.Name(), when.Format(time.DateTime)))
+			} else {
+				fmt.Print(tr("Removed the files of %s, which it didn't have before\n", backend.Name()))
+			}
+			bs.noteBackend(backend.Name(), "written", nil)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// The backends left alone can still be undone afterwards
+	for name, backup := range state.Undo {
+		if _, ok := runBackups[name]; !ok && !slices.Contains(names, name) {
+			runBackups[name] = backup
+		}
+	}
+	return bs.recordSync()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// kdePlaces returns the places of the kde backend
+func kdePlaces(t *testing.T) []Place {
+	t.Helper()
+	got, err := NewBookmarkSync().backends["kde"].GetPlaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestUndo(t *testing.T) {
+	home := testHome(t)
+	testWithoutGTK2(t)
+	runBackups = map[string]string{}
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\n")
+	if err := runSync([]string{"--from", "gtk"}); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\nfile:///b b\n")
+	if err := runSync([]string{"--from", "gtk"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := kdePlaces(t), places("a", "file:///a", "b", "file:///b"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("synced kde holds %v, want %v", got, want)
+	}
+
+	if err := runUndo([]string{"kde"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := kdePlaces(t), places("a", "file:///a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("undone kde holds %v, want %v", got, want)
+	}
+
+	// Undoing the undo redoes the sync
+	if err := runUndo([]string{"kde"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := kdePlaces(t), places("a", "file:///a", "b", "file:///b"); !reflect.DeepEqual(got, want) {
+		t.Errorf("redone kde holds %v, want %v", got, want)
+	}
+}
+
+func TestUndoErrors(t *testing.T) {
+	home := testHome(t)
+	testWithoutGTK2(t)
+	runBackups = map[string]string{}
+	if err := runUndo(nil); err == nil || !strings.Contains(err.Error(), "no sync has been recorded") {
+		t.Errorf("before any sync: error %v", err)
+	}
+
+	writeFile(t, home, ".config/gtk-3.0/bookmarks", "file:///a a\n")
+	if err := runSync([]string{"--from", "gtk"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runUndo([]string{"gtk"}); err == nil || !strings.Contains(err.Error(), "didn't write gtk") {
+		t.Errorf("undoing the source: error %v", err)
+	}
+
+	none := 0
+	config.Backups = &none
+	if err := runUndo(nil); err == nil || !strings.Contains(err.Error(), "no backups are kept") {
+		t.Errorf("without backups: error %v", err)
+	}
+}
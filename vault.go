@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// plasmaVaults returns the mount points of the Plasma Vaults set up in
+// plasmavaultrc, one group per vault, and the encrypted folders of vaults
+// in the configuration
+func plasmaVaults() []string {
+	vaults := append([]string(nil), config.Vaults...)
+	configHome, err := xdgConfigHome()
+	if err != nil {
+		return vaults
+	}
+	cfg, err := ini.Load(filepath.Join(configHome, "plasmavaultrc"))
+	if err != nil {
+		return vaults
+	}
+	for _, section := range cfg.Sections() {
+		if mountPoint := section.Key("mountPoint").String(); filepath.IsAbs(mountPoint) {
+			vaults = append(vaults, filepath.Clean(mountPoint))
+		}
+	}
+	return vaults
+}
+
+// mountPoints returns the folders something is mounted on
+func mountPoints() map[string]bool {
+	mounted := make(map[string]bool)
+	file, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return mounted
+	}
+	defer file.Close()
+	// Spaces and other separators in mount points are octal escapes
+	unescape := strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 1 {
+			mounted[unescape.Replace(fields[1])] = true
+		}
+	}
+	return mounted
+}
+
+// vaultOf returns the vault or volume target is in, or "" when it isn't in
+// any
+func vaultOf(target string, vaults []string) string {
+	path, err := localPath(target)
+	if err != nil || path == "" {
+		return ""
+	}
+	for _, vault := range vaults {
+		if path == vault || strings.HasPrefix(path, vault+"/") {
+			return vault
+		}
+	}
+	return mediaVolume(path)
+}
+
+// withVaults keeps the places inside closed vaults, and on encrypted or
+// removable volumes that aren't mounted, from being written, so other
+// backends get no dead entries pointing into them and don't reveal where
+// they are, and puts back those held back earlier whose vault is open
+// again. The held back places are kept in bs.vaulted until the sync is
+// recorded.
+func (bs *BookmarkSync) withVaults(places []Place) ([]Place, error) {
+	state, err := LoadState()
+	if err != nil {
+		return nil, err
+	}
+	rememberVolumes(state)
+	vaults := conditionalFolders(state)
+	mounted := mountPoints()
+
+	var kept []Place
+	for _, place := range places {
+		if vault := vaultOf(place.Target, vaults); vault != "" && !mounted[vault] {
+			bs.vaulted = append(bs.vaulted, place)
+			continue
+		}
+		kept = append(kept, place)
+	}
+	for _, place := range state.Vaulted {
+		if vault := vaultOf(place.Target, vaults); vault != "" && mounted[vault] {
+			kept = appendMissingPlaces(kept, []Place{place})
+		}
+	}
+	return kept, nil
+}
+
+// recordVaulted remembers the places held back in closed vaults, so that
+// they come back when their vault is opened. Places of vaults that are
+// open again were put back and are forgotten, and so are those of vaults
+// that no longer exist.
+func (bs *BookmarkSync) recordVaulted(state *State) {
+	rememberVolumes(state)
+	vaults := conditionalFolders(state)
+	mounted := mountPoints()
+	var held []Place
+	for _, place := range state.Vaulted {
+		if vault := vaultOf(place.Target, vaults); vault != "" && !mounted[vault] {
+			held = append(held, place)
+		}
+	}
+	held = dedupePlaces(append(held, bs.vaulted...))
+	bs.vaulted = nil
+	if len(held) == 0 {
+		held = nil
+	}
+	state.Vaulted = held
+}
+
+// withoutPrivatePlaces drops the places in vaults and on encrypted or
+// removable volumes when private_volumes is set, for what leaves the
+// machine: exports and remote stores
+func withoutPrivatePlaces(places []Place) ([]Place, error) {
+	if !config.PrivateVolumes {
+		return places, nil
+	}
+	state, err := LoadState()
+	if err != nil {
+		return nil, err
+	}
+	rememberVolumes(state)
+	vaults := conditionalFolders(state)
+	var kept []Place
+	for _, place := range places {
+		if vaultOf(place.Target, vaults) == "" {
+			kept = append(kept, place)
+		}
+	}
+	return kept, nil
+}
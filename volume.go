@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// udisksObjects is what the udisks ObjectManager returns: the interfaces of
+// every object with their properties
+type udisksObjects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+
+// udisksVolumes returns the mount points of the encrypted and removable
+// volumes udisks has mounted. Without udisks on the system bus there are
+// none.
+func udisksVolumes() []string {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var objects udisksObjects
+	err = conn.Object("org.freedesktop.UDisks2", "/org/freedesktop/UDisks2").
+		CallWithContext(ctx, "org.freedesktop.DBus.ObjectManager.GetManagedObjects", dbus.FlagNoAutoStart).
+		Store(&objects)
+	if err != nil {
+		return nil
+	}
+
+	var volumes []string
+	for _, object := range objects {
+		fs, ok := object["org.freedesktop.UDisks2.Filesystem"]
+		if !ok {
+			continue
+		}
+		block := object["org.freedesktop.UDisks2.Block"]
+		if !encryptedBlock(block) && !objects.removable(block) {
+			continue
+		}
+		var mountPoints [][]byte
+		if fs["MountPoints"].Store(&mountPoints) != nil {
+			continue
+		}
+		for _, mountPoint := range mountPoints {
+			// Mount points are NUL-terminated byte strings
+			if path := strings.TrimRight(string(mountPoint), "\x00"); filepath.IsAbs(path) && path != "/" {
+				volumes = append(volumes, filepath.Clean(path))
+			}
+		}
+	}
+	return volumes
+}
+
+// encryptedBlock reports whether block is the unlocked side of a LUKS
+// volume
+func encryptedBlock(block map[string]dbus.Variant) bool {
+	var backing dbus.ObjectPath
+	return block["CryptoBackingDevice"].Store(&backing) == nil && backing != "/" && backing != ""
+}
+
+// removable reports whether block is on a drive that can be unplugged, or
+// unlocked from one
+func (objects udisksObjects) removable(block map[string]dbus.Variant) bool {
+	var backing dbus.ObjectPath
+	if block["CryptoBackingDevice"].Store(&backing) == nil && objects[backing] != nil {
+		block = objects[backing]["org.freedesktop.UDisks2.Block"]
+	}
+	var drive dbus.ObjectPath
+	if block["Drive"].Store(&drive) != nil {
+		return false
+	}
+	props := objects[drive]["org.freedesktop.UDisks2.Drive"]
+	for _, name := range []string{"Removable", "MediaRemovable", "Ejectable"} {
+		var value bool
+		if props[name].Store(&value) == nil && value {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaVolume returns the volume path is on when it is below one of the
+// folders udisks mounts volumes in, or ""
+func mediaVolume(path string) string {
+	current, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	for _, root := range []string{"/run/media/" + current.Username, "/media/" + current.Username} {
+		if rest, ok := strings.CutPrefix(path, root+"/"); ok {
+			name, _, _ := strings.Cut(rest, "/")
+			return filepath.Join(root, name)
+		}
+	}
+	return ""
+}
+
+// rememberVolumes adds the encrypted and removable volumes mounted now to
+// those of state, so their places are still held back once they are gone
+func rememberVolumes(state *State) {
+	for _, volume := range udisksVolumes() {
+		if !slices.Contains(state.Volumes, volume) {
+			state.Volumes = append(state.Volumes, volume)
+		}
+	}
+}
+
+// conditionalFolders returns the folders whose places are only written
+// while they are mounted: the vaults and the volumes state remembers
+func conditionalFolders(state *State) []string {
+	folders := plasmaVaults()
+	for _, volume := range state.Volumes {
+		if !slices.Contains(folders, volume) {
+			folders = append(folders, volume)
+		}
+	}
+	return folders
+}
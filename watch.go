@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// defaultDebounce is how long the watcher waits for a burst of writes to
+// settle before syncing
+const defaultDebounce = 500 * time.Millisecond
+
+// housekeepingInterval is how often the watcher runs periodic tasks such as
+// expiring temporary bookmarks
+const housekeepingInterval = time.Minute
+
+// Watch calls sync whenever a file of one of the given backends changes,
+// or triggers names one, until ctx is done. File managers often write
+// their bookmarks several times in a row, so events are coalesced: sync
+// only runs once no event has arrived for the debounce interval, or,
+// with maxDelay set, at the latest maxDelay after the first of them.
+// housekeeping runs every housekeepingInterval between syncs. A change
+// holds off idle until the sync that follows it has finished.
+func Watch(ctx context.Context, backends []BookmarkSyncBackend, triggers <-chan string, debounce, maxDelay time.Duration, sync func() error, housekeeping func(), idle *idleTimer) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// Watch the directories rather than the files: most applications
+	// replace their bookmarks file by renaming a new one over it
+	files := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for _, backend := range backends {
+		paths, err := backend.Files()
+		if err != nil {
+			return fmt.Errorf("failed to resolve files of %s: %v", backend.Name(), err)
+		}
+		for _, path := range paths {
+			files[path] = true
+			dirs[filepath.Dir(path)] = true
+		}
+	}
+	watched := 0
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			// Applications that were never run have no config directory
+			if !os.IsNotExist(err) {
+				log.Print(tr("Warning: not watching %s: %v", dir, err))
+			}
+			continue
+		}
+		watched++
+	}
+	if watched == 0 {
+		return fmt.Errorf("no backend directories to watch")
+	}
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	var pending func()
+	// first is when the first event since the last sync arrived
+	var first time.Time
+	changed := func() {
+		if pending == nil {
+			pending = idle.Begin()
+		}
+		if first.IsZero() {
+			first = time.Now()
+		}
+		wait := debounce
+		if maxDelay > 0 {
+			wait = max(min(wait, maxDelay-time.Since(first)), 0)
+		}
+		timer.Reset(wait)
+	}
+	ticker := time.NewTicker(housekeepingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			housekeeping()
+		case event := <-watcher.Events:
+			if files[event.Name] && event.Op != fsnotify.Chmod {
+				changed()
+			}
+		case path := <-triggers:
+			if files[path] {
+				changed()
+			}
+		case err := <-watcher.Errors:
+			log.Print(tr("Warning: watch error: %v", err))
+		case <-timer.C:
+			first = time.Time{}
+			if err := sync(); err != nil {
+				log.Print(tr("Warning: sync failed: %v", err))
+			}
+			// The daemon mostly sleeps, so don't hold on to the memory
+			// the sync needed
+			debug.FreeOSMemory()
+			if pending != nil {
+				pending()
+				pending = nil
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestSignalPaths(t *testing.T) {
+	home := testHome(t)
+	tests := []struct {
+		name   string
+		signal *dbus.Signal
+		want   []string
+	}{
+		{
+			name:   "KDE places",
+			signal: &dbus.Signal{Path: kdePlacesPath, Name: "org.kde.KIO.KBookmarkManager.bookmarksChanged", Body: []any{""}},
+			want:   []string{filepath.Join(home, ".local/share/user-places.xbel")},
+		},
+		{
+			name:   "Konqueror bookmarks",
+			signal: &dbus.Signal{Path: "/KBookmarkManager/konqueror", Name: "org.kde.KIO.KBookmarkManager.bookmarksChanged", Body: []any{""}},
+		},
+		{
+			name: "files written by KIO",
+			signal: &dbus.Signal{Path: "/", Name: "org.kde.KDirNotify.FilesChanged", Body: []any{[]string{
+				"file:///home/jo/.config/gtk-3.0/bookmarks", "sftp://host/srv/file",
+			}}},
+			want: []string{"/home/jo/.config/gtk-3.0/bookmarks"},
+		},
+		{
+			name:   "other signals",
+			signal: &dbus.Signal{Path: "/", Name: "org.kde.KDirNotify.FilesAdded", Body: []any{"file:///home/jo"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := signalPaths(test.signal); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("paths %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// A file manager's signal syncs like a change of the file it names, and
+// those of other files are ignored
+func TestWatchTriggers(t *testing.T) {
+	dir := t.TempDir()
+	backend := &GTKBackend{Path: filepath.Join(dir, "bookmarks")}
+	triggers := make(chan string)
+	synced := make(chan bool, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, []BookmarkSyncBackend{backend}, triggers, 10*time.Millisecond, 0, func() error {
+			synced <- true
+			return nil
+		}, func() {}, nil)
+	}()
+
+	triggers <- filepath.Join(dir, "other")
+	select {
+	case <-synced:
+		t.Fatal("synced for another file")
+	case <-time.After(100 * time.Millisecond):
+	}
+	triggers <- backend.Path
+	select {
+	case <-synced:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no sync for the bookmarks file")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
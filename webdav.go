@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// WebDAVBackend implements BookmarkSyncBackend for a canonical places file
+// on a WebDAV server like Nextcloud, for sharing places between machines
+// without git or ssh keys. Writes only replace the file if it is still as
+// last read, by its ETag; when another machine wrote it in between, the
+// changes of both are merged like the edits of a two-way sync. A copy of
+// what was last read or written is kept in the state directory, read while
+// the server can't be reached.
+type WebDAVBackend struct {
+	// URL is the places file's http(s) URL
+	URL string
+	// User is who to log in as, if the server asks
+	User string
+	// PasswordCommand prints the password, so it needn't be in the
+	// configuration; it is run by sh
+	PasswordCommand string
+
+	password *string
+}
+
+// webdavClient makes the backend's requests; a server that doesn't answer
+// shouldn't hang the daemon
+var webdavClient = &http.Client{Timeout: 30 * time.Second}
+
+// errPreconditionFailed is a write refused because the file changed since
+// it was last read
+var errPreconditionFailed = errors.New("the file changed on the server")
+
+func (w *WebDAVBackend) Name() string {
+	return "webdav"
+}
+
+// dir returns where the copy of the file and its ETag are kept
+func (w *WebDAVBackend) dir() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "webdav"), nil
+}
+
+// mirror is the copy of the file kept in the state directory
+func (w *WebDAVBackend) mirror() (*CanonicalBackend, error) {
+	dir, err := w.dir()
+	if err != nil {
+		return nil, err
+	}
+	return &CanonicalBackend{Path: filepath.Join(dir, "places.toml")}, nil
+}
+
+func (w *WebDAVBackend) Files() ([]string, error) {
+	mirror, err := w.mirror()
+	if err != nil {
+		return nil, err
+	}
+	return mirror.Files()
+}
+
+// etag returns the ETag the file had when it was last read or written, ""
+// when it didn't exist, and ok false when that isn't known
+func (w *WebDAVBackend) etag() (etag string, ok bool) {
+	dir, err := w.dir()
+	if err != nil {
+		return "", false
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "etag"))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+// remember keeps data as the copy of the file and etag as its ETag
+func (w *WebDAVBackend) remember(data []byte, etag string) error {
+	mirror, err := w.mirror()
+	if err != nil {
+		return err
+	}
+	if err := mirror.keep(data); err != nil {
+		return err
+	}
+	dir, err := w.dir()
+	if err != nil {
+		return err
+	}
+	return replaceFile(filepath.Join(dir, "etag"), []byte(etag+"\n"))
+}
+
+// request sends a request for the file, or with method MKCOL its folder
+func (w *WebDAVBackend) request(method, target string, body []byte, header http.Header) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if w.User != "" {
+		if w.password == nil {
+			// A command reading it from the keyring, like secret-tool,
+			// would fail or prompt
+			if err := checkKeyring(w.PasswordCommand != ""); err != nil {
+				return nil, nil, err
+			}
+			password, err := w.readPassword()
+			if err != nil {
+				return nil, nil, err
+			}
+			w.password = &password
+		}
+		req.SetBasicAuth(w.User, *w.password)
+	}
+	resp, err := webdavClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, data, nil
+}
+
+// readPassword runs PasswordCommand
+func (w *WebDAVBackend) readPassword() (string, error) {
+	if w.PasswordCommand == "" {
+		return "", nil
+	}
+	out, err := exec.Command("sh", "-c", w.PasswordCommand).Output()
+	if err != nil {
+		return "", fmt.Errorf("webdav_password_command: %v", err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// fetch reads the file from the server, returning it as it is there, its
+// places and its ETag. A file that doesn't exist is empty, with no places
+// and an empty ETag.
+func (w *WebDAVBackend) fetch() ([]byte, []Place, string, error) {
+	resp, data, err := w.request(http.MethodGet, w.URL, nil, nil)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, []Place{}, "", nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, nil, "", fmt.Errorf("GET %s: %s", w.URL, resp.Status)
+	}
+	plain, err := openPlaces(w.URL, data)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	places, err := hostPlaces(plain)
+	if err != nil {
+		return nil, nil, "", &corruptError{File: w.URL, Err: err}
+	}
+	return data, places, resp.Header.Get("ETag"), nil
+}
+
+// put writes data to the server if the file still has etag, or with known
+// false whatever it has. It returns the ETag the file has afterwards.
+func (w *WebDAVBackend) put(data []byte, etag string, known bool) (string, error) {
+	header := http.Header{"Content-Type": {"application/toml"}}
+	switch {
+	case known && etag == "":
+		header.Set("If-None-Match", "*")
+	case known:
+		header.Set("If-Match", etag)
+	}
+	resp, _, err := w.request(http.MethodPut, w.URL, data, header)
+	if err == nil && resp.StatusCode == http.StatusConflict {
+		// The folder isn't there yet
+		folder := w.URL[:strings.LastIndex(w.URL, "/")+1]
+		if _, _, err := w.request("MKCOL", folder, nil, nil); err != nil {
+			return "", err
+		}
+		resp, _, err = w.request(http.MethodPut, w.URL, data, header)
+	}
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		return "", errPreconditionFailed
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return "", fmt.Errorf("PUT %s: %s", w.URL, resp.Status)
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		return etag, nil
+	}
+	// Not every server sends the new ETag along
+	resp, _, err = w.request(http.MethodHead, w.URL, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+func (w *WebDAVBackend) GetPlaces() ([]Place, error) {
+	data, places, etag, err := w.fetch()
+	if isCorrupt(err) {
+		return nil, err
+	}
+	if err != nil {
+		log.Print(tr("Warning: failed to read the places from %s, using those read last: %v", w.URL, err))
+		mirror, err := w.mirror()
+		if err != nil {
+			return nil, err
+		}
+		return mirror.GetPlaces()
+	}
+	if err := w.remember(data, etag); err != nil {
+		return nil, err
+	}
+	return places, nil
+}
+
+func (w *WebDAVBackend) Merge(places []Place) error {
+	return mergeInto(w, places)
+}
+
+func (w *WebDAVBackend) Replace(places []Place) error {
+	mirror, err := w.mirror()
+	if err != nil {
+		return err
+	}
+	places, err = exportedPlaces(places)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		data, err := mirror.Render(places)
+		if err != nil {
+			return err
+		}
+		if data, err = sealPlaces(data, ""); err != nil {
+			return err
+		}
+		etag, known := w.etag()
+		etag, err = w.put(data, etag, known)
+		if err == nil {
+			return w.remember(data, etag)
+		}
+		if !errors.Is(err, errPreconditionFailed) || attempt == 3 {
+			return fmt.Errorf("failed to write the places to %s: %w", w.URL, err)
+		}
+
+		// Another machine wrote the file since it was read here: merge
+		// its changes into these, which win where both changed a place
+		base, err := mirror.GetPlaces()
+		if err != nil {
+			return err
+		}
+		raw, theirs, etag, err := w.fetch()
+		if err != nil {
+			return err
+		}
+		caps := Capabilities{Labels: true, Remote: true}
+		merged, conflicts := threeWayMerge(base, []placeEdits{
+			diffPlaces("local", caps, base, places),
+			diffPlaces(w.URL, caps, base, theirs),
+		})
+		for _, conflict := range conflicts {
+			log.Print(tr("Conflict: %s", conflict))
+		}
+		if err := w.remember(raw, etag); err != nil {
+			return err
+		}
+		places = merged
+	}
+}
+
+// checkWebDAVURL checks the URL of a WebDAVBackend
+func checkWebDAVURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.HasSuffix(u.Path, "/") || u.Path == "" {
+		return fmt.Errorf("invalid webdav_url %q: expected the http(s) URL of a file", s)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// webdavServer is a WebDAV server holding one file, which honours
+// If-Match and If-None-Match like Nextcloud
+type webdavServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	data     []byte
+	requests int
+	// refuse has every write fail with 412, like a file another machine
+	// keeps writing
+	refuse bool
+}
+
+// newWebDAVServer starts a webdavServer for the test, without the file
+func newWebDAVServer(t *testing.T) *webdavServer {
+	t.Helper()
+	s := &webdavServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// etag is the ETag of the file, "" when there is none
+func (s *webdavServer) etag() string {
+	if s.data == nil {
+		return ""
+	}
+	return fmt.Sprintf(`"%x"`, sha256.Sum256(s.data))
+}
+
+// set replaces the file, as another machine would
+func (s *webdavServer) set(data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = []byte(data)
+}
+
+func (s *webdavServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if s.data == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", s.etag())
+		w.Write(s.data)
+	case http.MethodPut:
+		match, noneMatch := r.Header.Get("If-Match"), r.Header.Get("If-None-Match")
+		if s.refuse || (match != "" && match != s.etag()) || (noneMatch == "*" && s.data != nil) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		s.data, _ = io.ReadAll(r.Body)
+		w.Header().Set("ETag", s.etag())
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// placesOn returns the places of the file on server
+func placesOn(t *testing.T, server *webdavServer) []Place {
+	t.Helper()
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	places, err := hostPlaces(server.data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return places
+}
+
+func TestWebDAVMergesConcurrentWrite(t *testing.T) {
+	testHome(t)
+	server := newWebDAVServer(t)
+	backend := &WebDAVBackend{URL: server.URL + "/places.toml"}
+	if err := backend.Replace(places("a", "file:///a", "b", "file:///b")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := backend.GetPlaces(); err != nil || !reflect.DeepEqual(got, places("a", "file:///a", "b", "file:///b")) {
+		t.Fatalf("read %v, %v", got, err)
+	}
+
+	// Another machine adds c, so the write here, which removes b, is
+	// refused with 412 and merged with it
+	other, err := (&CanonicalBackend{}).Render(places("a", "file:///a", "b", "file:///b", "c", "file:///c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.set(string(other))
+	if err := backend.Replace(places("a", "file:///a")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := placesOn(t, server), places("a", "file:///a", "c", "file:///c"); !reflect.DeepEqual(got, want) {
+		t.Errorf("server holds %v, want %v", got, want)
+	}
+	if etag, _ := backend.etag(); etag != server.etag() {
+		t.Errorf("remembered ETag %s, want the server's %s", etag, server.etag())
+	}
+}
+
+func TestWebDAVGivesUpOnConflicts(t *testing.T) {
+	testHome(t)
+	server := newWebDAVServer(t)
+	backend := &WebDAVBackend{URL: server.URL + "/places.toml"}
+	if err := backend.Replace(places("a", "file:///a")); err != nil {
+		t.Fatal(err)
+	}
+	server.mu.Lock()
+	server.refuse = true
+	server.mu.Unlock()
+	err := backend.Replace(places("b", "file:///b"))
+	if !errors.Is(err, errPreconditionFailed) {
+		t.Errorf("error %v, want the file changing on the server", err)
+	}
+	if got, want := placesOn(t, server), places("a", "file:///a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("server holds %v, want %v", got, want)
+	}
+}
+
+func TestWebDAVOffline(t *testing.T) {
+	testHome(t)
+	server := newWebDAVServer(t)
+	backend := &WebDAVBackend{URL: server.URL + "/places.toml"}
+	if err := backend.Replace(places("a", "file:///a")); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+	got, err := backend.GetPlaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := places("a", "file:///a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("read %v offline, want the copy's %v", got, want)
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// WineBackend implements BookmarkSyncBackend for a Wine (or Proton)
+// prefix. Local places are written as Windows shortcuts to their Z:\
+// mapped paths in the prefix user's Links (Explorer favorites) and SendTo
+// folders. Only shortcuts created by BookmarkSync are ever removed; they are
+// tracked in a manifest next to them.
+type WineBackend struct {
+	// Prefix is the WINEPREFIX directory (the one containing drive_c)
+	Prefix string
+}
+
+// shortcutManifest is the file listing the shortcuts BookmarkSync wrote
+// into a folder, shared by the backends that generate files
+const shortcutManifest = ".bookmarksync"
+
+// wineShortcutDirs are the folders, relative to the prefix user's profile,
+// that receive shortcuts
+var wineShortcutDirs = []string{
+	"Links",
+	filepath.Join("AppData", "Roaming", "Microsoft", "Windows", "SendTo"),
+}
+
+func (w *WineBackend) Name() string {
+	return "wine:" + filepath.Base(w.Prefix)
+}
+
+func (w *WineBackend) Capabilities() Capabilities {
+	return Capabilities{Labels: true, Remote: false}
+}
+
+// userDir returns the Windows profile directory inside the prefix. Wine uses
+// the Unix user name, Proton always uses steamuser.
+func (w *WineBackend) userDir() (string, error) {
+	usersDir := filepath.Join(w.Prefix, "drive_c", "users")
+	if user := os.Getenv("USER"); user != "" {
+		if _, err := os.Stat(filepath.Join(usersDir, user)); err == nil {
+			return filepath.Join(usersDir, user), nil
+		}
+	}
+
+	entries, err := os.ReadDir(usersDir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != "Public" {
+			return filepath.Join(usersDir, entry.Name()), nil
+		}
+	}
+	return "", fmt.Errorf("no user profile in %s", usersDir)
+}
+
+func (w *WineBackend) Files() ([]string, error) {
+	userDir, err := w.userDir()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, dir := range wineShortcutDirs {
+		files = append(files, shortcutFiles(filepath.Join(userDir, dir))...)
+	}
+	return files, nil
+}
+
+func (w *WineBackend) GetPlaces() ([]Place, error) {
+	userDir, err := w.userDir()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Place{}, nil
+		}
+		return nil, err
+	}
+
+	// Shortcuts are write-only; report what was last written
+	manifest, err := readShortcutManifest(filepath.Join(userDir, wineShortcutDirs[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	places := []Place{}
+	for _, entry := range manifest {
+		places = append(places, entry.Place)
+	}
+	return places, nil
+}
+
+func (w *WineBackend) Merge(places []Place) error {
+	return mergeInto(w, places)
+}
+
+func (w *WineBackend) Replace(places []Place) error {
+	userDir, err := w.userDir()
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range wineShortcutDirs {
+		if err := writeWineShortcuts(filepath.Join(userDir, dir), places); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shortcutManifestEntry records a shortcut file written by BookmarkSync
+type shortcutManifestEntry struct {
+	File  string
+	Place Place
+}
+
+// readShortcutManifest reads the "file<TAB>target<TAB>label" manifest of a
+// shortcut folder
+func readShortcutManifest(dir string) ([]shortcutManifestEntry, error) {
+	path := filepath.Join(dir, shortcutManifest)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := checkText(path, data); err != nil {
+		return nil, err
+	}
+
+	var entries []shortcutManifestEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		// Only bookmarksync writes the manifest, so a line it can't
+		// read is one a write broke off
+		parts := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(parts) != 3 {
+			return nil, &corruptError{File: path, Err: fmt.Errorf("line %d: expected file, target and label", n)}
+		}
+		entries = append(entries, shortcutManifestEntry{
+			File:  parts[0],
+			Place: Place{Target: parts[1], Label: parts[2]},
+		})
+	}
+	return entries, scanner.Err()
+}
+
+// shortcutFiles returns the manifest of a shortcut folder and the
+// shortcuts it lists. A manifest that can't be read lists none.
+func shortcutFiles(dir string) []string {
+	files := []string{filepath.Join(dir, shortcutManifest)}
+	entries, _ := readShortcutManifest(dir)
+	for _, entry := range entries {
+		files = append(files, filepath.Join(dir, entry.File))
+	}
+	return files
+}
+
+func writeWineShortcuts(dir string, places []Place) error {
+	previous, err := readShortcutManifest(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range previous {
+		if err := os.Remove(filepath.Join(dir, entry.File)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var manifest bytes.Buffer
+	used := make(map[string]bool)
+	for _, place := range places {
+		if !strings.HasPrefix(place.Target, "file://") {
+			continue
+		}
+		u, err := url.Parse(place.Target)
+		if err != nil {
+			continue
+		}
+
+		label := place.Label
+		if label == "" {
+			label = filepath.Base(u.Path)
+		}
+		name := windowsFileName(label)
+		for i := 2; used[strings.ToLower(name)]; i++ {
+			name = fmt.Sprintf("%s (%d)", windowsFileName(label), i)
+		}
+		used[strings.ToLower(name)] = true
+		name += ".lnk"
+
+		// Wine maps the Unix root to drive Z:
+		winPath := "Z:" + strings.ReplaceAll(u.Path, "/", `\`)
+		if err := replaceFile(filepath.Join(dir, name), windowsShortcut(winPath)); err != nil {
+			return err
+		}
+		fmt.Fprintf(&manifest, "%s\t%s\t%s\n", name, place.Target, place.Label)
+	}
+
+	return replaceFile(filepath.Join(dir, shortcutManifest), manifest.Bytes())
+}
+
+// windowsFileName replaces characters Windows does not allow in file names
+func windowsFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	return strings.TrimRight(name, ". ")
+}
+
+// windowsShortcut builds a minimal Shell Link (.lnk) file pointing at a
+// local directory, following [MS-SHLLINK]: a header followed by a LinkInfo
+// structure carrying the path in both ANSI and Unicode form.
+func windowsShortcut(target string) []byte {
+	const (
+		hasLinkInfo        = 0x02
+		isUnicode          = 0x80
+		fileAttrDirectory  = 0x10
+		swShowNormal       = 1
+		linkInfoHeaderSize = 0x24
+		volumeIDSize       = 0x11
+		driveFixed         = 3
+	)
+
+	ansi := []byte(strings.Map(func(r rune) rune {
+		if r > 0x7f {
+			return '?'
+		}
+		return r
+	}, target))
+	ansi = append(ansi, 0)
+	wide := utf16.Encode([]rune(target + "\x00"))
+
+	localBasePathOffset := uint32(linkInfoHeaderSize + volumeIDSize)
+	commonPathSuffixOffset := localBasePathOffset + uint32(len(ansi))
+	localBasePathOffsetUnicode := commonPathSuffixOffset + 1
+	commonPathSuffixOffsetUnicode := localBasePathOffsetUnicode + uint32(len(wide)*2)
+	linkInfoSize := commonPathSuffixOffsetUnicode + 2
+
+	var buf bytes.Buffer
+	le := func(v any) { binary.Write(&buf, binary.LittleEndian, v) }
+
+	// ShellLinkHeader
+	le(uint32(0x4C))
+	buf.Write([]byte{0x01, 0x14, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46})
+	le(uint32(hasLinkInfo | isUnicode))
+	le(uint32(fileAttrDirectory))
+	le([3]uint64{}) // creation, access and write times
+	le(uint32(0))   // file size
+	le(int32(0))    // icon index
+	le(uint32(swShowNormal))
+	le(uint16(0))  // hotkey
+	le([10]byte{}) // reserved
+
+	// LinkInfo
+	le(linkInfoSize)
+	le(uint32(linkInfoHeaderSize))
+	le(uint32(1)) // VolumeIDAndLocalBasePath
+	le(uint32(linkInfoHeaderSize))
+	le(localBasePathOffset)
+	le(uint32(0)) // no network relative link
+	le(commonPathSuffixOffset)
+	le(localBasePathOffsetUnicode)
+	le(commonPathSuffixOffsetUnicode)
+
+	// VolumeID with an empty label
+	le(uint32(volumeIDSize))
+	le(uint32(driveFixed))
+	le(uint32(0)) // serial number
+	le(uint32(0x10))
+	buf.WriteByte(0)
+
+	buf.Write(ansi)
+	buf.WriteByte(0) // empty common path suffix
+	le(wide)
+	le(uint16(0))
+
+	// Terminal ExtraData block
+	le(uint32(0))
+
+	return buf.Bytes()
+}
+
+// winePrefixFlag collects --wine-prefix values, one WineBackend per prefix
+type winePrefixFlag []*WineBackend
+
+func (f *winePrefixFlag) String() string {
+	var prefixes []string
+	for _, backend := range *f {
+		prefixes = append(prefixes, backend.Prefix)
+	}
+	return strings.Join(prefixes, ",")
+}
+
+func (f *winePrefixFlag) Set(value string) error {
+	prefix, err := expandHome(strings.TrimSpace(value))
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(prefix, "drive_c")); err != nil {
+		return fmt.Errorf("%s is not a Wine prefix: %v", prefix, err)
+	}
+	*f = append(*f, &WineBackend{Prefix: prefix})
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"unicode/utf16"
+)
+
+// readShortcut returns the ANSI and Unicode local base paths of a Shell
+// Link file as [MS-SHLLINK] lays it out, failing the test where it
+// doesn't
+func readShortcut(t *testing.T, data []byte) (ansi, wide string) {
+	t.Helper()
+	u32 := func(offset uint32) uint32 {
+		if int(offset)+4 > len(data) {
+			t.Fatalf("read past the end at %#x", offset)
+		}
+		return binary.LittleEndian.Uint32(data[offset:])
+	}
+	linkCLSID := []byte{0x01, 0x14, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
+	if u32(0) != 0x4C || !bytes.Equal(data[4:20], linkCLSID) {
+		t.Fatal("not a shell link header")
+	}
+	if flags := u32(20); flags != 0x82 {
+		t.Fatalf("link flags %#x, want HasLinkInfo and IsUnicode", flags)
+	}
+
+	const linkInfo = 0x4C
+	size, headerSize := u32(linkInfo), u32(linkInfo+4)
+	if headerSize != 0x24 {
+		t.Fatalf("link info header size %#x", headerSize)
+	}
+	if u32(linkInfo+8) != 1 || u32(linkInfo+20) != 0 {
+		t.Fatal("link info isn't a local path")
+	}
+	if volumeID := u32(linkInfo + 12); u32(linkInfo+volumeID) != 0x11 {
+		t.Fatalf("volume ID size %#x", u32(linkInfo+volumeID))
+	}
+	local, suffix := u32(linkInfo+16), u32(linkInfo+24)
+	localUnicode, suffixUnicode := u32(linkInfo+28), u32(linkInfo+32)
+
+	info := data[linkInfo : linkInfo+size]
+	end := bytes.IndexByte(info[local:], 0)
+	if end < 0 || local+uint32(end)+1 != suffix || info[suffix] != 0 {
+		t.Fatal("ANSI path isn't followed by the empty suffix")
+	}
+	ansi = string(info[local : local+uint32(end)])
+
+	var units []uint16
+	for offset := localUnicode; ; offset += 2 {
+		unit := binary.LittleEndian.Uint16(info[offset:])
+		if unit == 0 {
+			if offset+2 != suffixUnicode {
+				t.Fatal("Unicode path isn't followed by the empty suffix")
+			}
+			break
+		}
+		units = append(units, unit)
+	}
+	if binary.LittleEndian.Uint16(info[suffixUnicode:]) != 0 || suffixUnicode+2 != size {
+		t.Fatal("link info doesn't end with the Unicode suffix")
+	}
+	if !bytes.Equal(data[linkInfo+size:], make([]byte, 4)) {
+		t.Fatal("no terminal block after the link info")
+	}
+	return ansi, string(utf16.Decode(units))
+}
+
+func TestWindowsShortcut(t *testing.T) {
+	for _, target := range []string{`Z:\`, `Z:\home\jo\Documents`, `Z:\home\jo\Café Ünïcode`} {
+		ansi, wide := readShortcut(t, windowsShortcut(target))
+		if wide != target {
+			t.Errorf("Unicode path %q, want %q", wide, target)
+		}
+		want := []rune(target)
+		for i, r := range want {
+			if r > 0x7f {
+				want[i] = '?'
+			}
+		}
+		if ansi != string(want) {
+			t.Errorf("ANSI path %q, want %q", ansi, string(want))
+		}
+	}
+}
+
+func TestWineRoundTrip(t *testing.T) {
+	prefix := t.TempDir()
+	t.Setenv("USER", "jo")
+	links := filepath.Join(prefix, "drive_c/users/jo/Links")
+	writeFile(t, links, "Mine.lnk", "not ours")
+	backend := &WineBackend{Prefix: prefix}
+
+	written := places(
+		"Docs", "file:///home/jo/Docs",
+		"Docs", "file:///home/jo/Other%20Docs",
+		"A:B", "file:///srv/a",
+		"server", "sftp://host/srv",
+	)
+	if err := backend.Replace(written); err != nil {
+		t.Fatal(err)
+	}
+	got, err := backend.GetPlaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := written[:3]; !reflect.DeepEqual(got, want) {
+		t.Errorf("read %v, want %v", got, want)
+	}
+	data, err := os.ReadFile(filepath.Join(links, "Docs (2).lnk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, wide := readShortcut(t, data); wide != `Z:\home\jo\Other Docs` {
+		t.Errorf("shortcut to %q", wide)
+	}
+
+	if err := backend.Replace(places("Docs", "file:///home/jo/Docs")); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range wineShortcutDirs {
+		entries, err := os.ReadDir(filepath.Join(prefix, "drive_c/users/jo", dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		want := []string{".bookmarksync", "Docs.lnk"}
+		if dir == "Links" {
+			want = append(want, "Mine.lnk")
+		}
+		if !slices.Equal(names, want) {
+			t.Errorf("%s holds %v, want %v", dir, names, want)
+		}
+	}
+
+	// The journal and the watcher need the shortcuts as well as the
+	// manifest, and not the user's own
+	files, err := backend.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for _, dir := range wineShortcutDirs {
+		dir = filepath.Join(prefix, "drive_c/users/jo", dir)
+		want = append(want, filepath.Join(dir, ".bookmarksync"), filepath.Join(dir, "Docs.lnk"))
+	}
+	if !slices.Equal(files, want) {
+		t.Errorf("Files() = %v, want %v", files, want)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// xdgDir returns the base directory named by the environment variable
+// env, or the given path under the home directory when it is unset or not
+// absolute, as the XDG base directory specification asks
+func xdgDir(env string, fallback ...string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{homeDir}, fallback...)...), nil
+}
+
+// xdgConfigHome returns $XDG_CONFIG_HOME, by default ~/.config
+func xdgConfigHome() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// xdgDataHome returns $XDG_DATA_HOME, by default ~/.local/share
+func xdgDataHome() (string, error) {
+	return xdgDir("XDG_DATA_HOME", ".local", "share")
+}
+
+// xdgStateHome returns $XDG_STATE_HOME, by default ~/.local/state
+func xdgStateHome() (string, error) {
+	return xdgDir("XDG_STATE_HOME", ".local", "state")
+}